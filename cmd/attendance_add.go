@@ -20,6 +20,7 @@ package cmd
 import (
 	"errors"
 	"fmt"
+	"slices"
 	"time"
 
 	"github.com/applejag/rootless-personio/pkg/config"
@@ -37,6 +38,7 @@ var attendanceAddCmd = &cobra.Command{
 	Long:    `Adds an attendance period.`,
 	Example: `add 2023-01-25 "Project X" 4h`,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
 		if len(args) != 3 {
 			return fmt.Errorf("expected 3 arguments, got %d", len(args))
 		}
@@ -52,28 +54,29 @@ var attendanceAddCmd = &cobra.Command{
 		if duration <= 0 {
 			return errors.New("duration must be positive")
 		}
-		client, err := newLoggedInClient()
+		client, err := newLoggedInClient(ctx)
 		if err != nil {
 			return err
 		}
 
 		var projectID *int
 		if projectName != "none" {
-			projectId, err := client.GetProjectID(projectName)
+			projectId, err := client.GetProjectIDContext(ctx, projectName)
 			if err != nil {
 				return fmt.Errorf("failed to get project ID: %w", err)
 			}
 			projectID = &projectId
 		}
 
-		calendar, err := client.GetMyAttendanceCalendar(date, date)
+		calendar, err := client.GetMyAttendanceCalendarContext(ctx, date, date)
 		if err != nil {
 			return fmt.Errorf("failed to get attendance calendar: %w", err)
 		}
 		currentDay := calendar[0]
+		oldPeriods := slices.Clone(currentDay.Periods)
 		var startTime time.Time
 		if attendanceAddFlags.startTime != "" {
-			startTime, err = parseTime(date, attendanceAddFlags.startTime)
+			startTime, err = parseTime(date, attendanceAddFlags.startTime, client.Location())
 			if err != nil {
 				return fmt.Errorf("invalid start time format: %w", err)
 			}
@@ -82,7 +85,7 @@ var attendanceAddCmd = &cobra.Command{
 				return errors.New("no start time provided and no default start time configured")
 			}
 
-			startTime, err = parseTime(date, cfg.StandardStartTime)
+			startTime, err = parseTime(date, cfg.StandardStartTime, client.Location())
 			if err != nil {
 				return fmt.Errorf("invalid start time format: %w", err)
 			}
@@ -95,23 +98,30 @@ var attendanceAddCmd = &cobra.Command{
 		}
 		endTime := startTime.Add(duration)
 		currentDay.Periods = append(currentDay.Periods, personio.Period{
-			Start:     personio.PersonioTime{startTime},
-			End:       personio.PersonioTime{endTime},
+			Start:     personio.PersonioTime{Time: startTime},
+			End:       personio.PersonioTime{Time: endTime},
 			ProjectID: projectID,
 			Type:      personio.PeriodTypeWork,
 		})
 
-		err = client.SetAttendance(date, currentDay.Periods)
+		applied, err := applyDayChanges(ctx, client, journalEntry(cmd), []dayChange{{
+			date:    date,
+			old:     oldPeriods,
+			periods: currentDay.Periods,
+		}})
 		if err != nil {
 			return fmt.Errorf("failed to set attendance: %w", err)
 		}
+		if !applied {
+			return nil
+		}
 
 		if cfg.Output == config.OutFormatPretty {
 			projectTimes := map[string]time.Duration{}
 			for _, period := range currentDay.Periods {
 				var projectName = "<none>"
 				if period.ProjectID != nil {
-					projectName, err = client.GetProjectName(*period.ProjectID)
+					projectName, err = client.GetProjectNameContext(ctx, *period.ProjectID)
 					if err != nil {
 						return fmt.Errorf("failed to get project name: %w", err)
 					}
@@ -123,18 +133,20 @@ var attendanceAddCmd = &cobra.Command{
 				fmt.Printf("%s: %s\n", project, duration.Truncate(time.Second))
 			}
 		} else {
-			return printOutputJSONOrYAML(currentDay)
+			return printOutput(currentDay)
 		}
 		return nil
 	},
 }
 
-func parseTime(date time.Time, timeString string) (time.Time, error) {
+// parseTime parses a HH:MM time of day on the given date,
+// as a wall-clock time in the given location.
+func parseTime(date time.Time, timeString string, loc *time.Location) (time.Time, error) {
 	parsedTime, err := time.Parse("15:04", timeString)
 	if err != nil {
 		return time.Time{}, fmt.Errorf("invalid time format: %w", err)
 	}
-	return time.Date(date.Year(), date.Month(), date.Day(), parsedTime.Hour(), parsedTime.Minute(), 0, 0, date.Location()), nil
+	return time.Date(date.Year(), date.Month(), date.Day(), parsedTime.Hour(), parsedTime.Minute(), 0, 0, loc), nil
 }
 
 func parseDuration(s string) (time.Duration, error) {
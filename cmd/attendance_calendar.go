@@ -37,11 +37,25 @@ var attendanceCalendarFlags = struct {
 var attendanceCalendarCmd = &cobra.Command{
 	Use:   "calendar",
 	Short: "Show the calendar of your attendance",
+	Long: `Show the calendar of your attendance, where each day shows the
+tracked work time compared to the target, e.g "18 7:30/8:00".
+Breaks that overlap with the work periods are not counted as work time.
+
+The days are colored by whether they are under, on or over their target,
+pending or rejected approval, or an absence. Each week shows the sum of
+the tracked and target time so far, and the overtime balance as reported
+by Personio at the end of the week.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		client, err := newLoggedInClient(ctx)
+		if err != nil {
+			return err
+		}
+		now := time.Now().In(client.Location())
 		startDate := attendanceCalendarFlags.startDate.Time()
 		endDate := attendanceCalendarFlags.endDate.Time()
 
-		monthStart, monthEnd := util.TimeFullMonth(time.Now())
+		monthStart, monthEnd := util.TimeFullMonth(now)
 		if !cmd.Flag("start").Changed {
 			startDate = monthStart
 		}
@@ -53,19 +67,15 @@ var attendanceCalendarCmd = &cobra.Command{
 			Time("start", startDate).
 			Time("end", endDate).
 			Msg("Date range.")
-		client, err := newLoggedInClient()
-		if err != nil {
-			return err
-		}
-		cal, err := client.GetMyAttendanceCalendar(startDate, endDate)
+		cal, err := client.GetMyAttendanceCalendarContext(ctx, startDate, endDate)
 		if err != nil {
 			return err
 		}
 
 		if cfg.Output == config.OutFormatPretty {
-			return prettyPrintCalendar(cal, startDate, endDate)
+			return prettyPrintCalendar(cal, startDate, endDate, now)
 		}
-		return printOutputJSONOrYAML(cal)
+		return printOutput(cal)
 	},
 }
 
@@ -76,11 +86,11 @@ func init() {
 	attendanceCalendarCmd.Flags().VarP(&attendanceCalendarFlags.endDate, "end", "e", "End date to show (default first day this month)")
 }
 
-func prettyPrintCalendar(cal []personio.Timecard, startDate, endDate time.Time) error {
+func prettyPrintCalendar(cal []personio.Timecard, startDate, endDate, now time.Time) error {
 	year, month, _ := startDate.Date()
 	date := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
 	for date.Before(endDate) {
-		console.PrintCalendarMonth(date, cal)
+		console.PrintCalendarMonth(date, cal, now)
 		date = date.AddDate(0, 1, 0)
 	}
 	return nil
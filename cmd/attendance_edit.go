@@ -0,0 +1,182 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/config"
+	"github.com/applejag/rootless-personio/pkg/console"
+	"github.com/applejag/rootless-personio/pkg/personio"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+var attendanceEditFlags = struct {
+	start   string
+	end     string
+	project string
+	comment string
+	typ     string
+}{}
+
+var attendanceEditCmd = &cobra.Command{
+	Use:   "edit <YYYY-MM-DD> <period-id|position>",
+	Args:  cobra.ExactArgs(2),
+	Short: "Edit a single attendance period",
+	Long: `Edits a single attendance period, while keeping the other periods
+of the day unchanged.
+
+The period is referenced either by its ID, or by its position in the day,
+starting at 1, as shown by the "attendance show" command.
+Only the fields given via the flags are changed. An end time that is not
+after the start time is on the next day, such as for periods past
+midnight.`,
+	Example: `edit 2023-01-25 2 --end 17:30
+edit 2023-01-25 1 --project "Project X" --comment "Planning"
+edit 2023-01-25 3 --project none
+edit 2023-01-25 4 --end 01:30`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		date, err := time.Parse(time.DateOnly, args[0])
+		if err != nil {
+			return fmt.Errorf("invalid date format: %w", err)
+		}
+		flags := cmd.Flags()
+		if !flags.Changed("start") && !flags.Changed("end") && !flags.Changed("project") &&
+			!flags.Changed("comment") && !flags.Changed("type") {
+			return errors.New("nothing to edit, please provide at least one of --start, --end, --project, --comment or --type")
+		}
+
+		client, err := newLoggedInClient(ctx)
+		if err != nil {
+			return err
+		}
+		card, err := client.GetMyAttendanceDayContext(ctx, date)
+		if err != nil {
+			return fmt.Errorf("failed to get attendance: %w", err)
+		}
+		index, err := card.FindPeriod(args[1])
+		if err != nil {
+			return err
+		}
+		oldPeriods := slices.Clone(card.Periods)
+		period := &card.Periods[index]
+
+		if flags.Changed("start") {
+			period.Start.Time, err = parseTime(date, attendanceEditFlags.start, client.Location())
+			if err != nil {
+				return fmt.Errorf("invalid start time format: %w", err)
+			}
+		}
+		if flags.Changed("end") {
+			period.End.Time, err = parseEndTime(date, period.Start.Time, attendanceEditFlags.end, client.Location())
+			if err != nil {
+				return fmt.Errorf("invalid end time format: %w", err)
+			}
+		}
+		if !period.End.After(period.Start.Time) {
+			return fmt.Errorf("end time %s must be after start time %s",
+				period.End.Format("15:04"), period.Start.Format("15:04"))
+		}
+		if flags.Changed("project") {
+			period.ProjectID = nil
+			if attendanceEditFlags.project != "none" {
+				projectID, err := client.GetProjectIDContext(ctx, attendanceEditFlags.project)
+				if err != nil {
+					return fmt.Errorf("failed to get project ID: %w", err)
+				}
+				period.ProjectID = &projectID
+			}
+		}
+		if flags.Changed("comment") {
+			period.Comment = nil
+			if attendanceEditFlags.comment != "" {
+				period.Comment = &attendanceEditFlags.comment
+			}
+		}
+		if flags.Changed("type") {
+			switch personio.PeriodType(attendanceEditFlags.typ) {
+			case personio.PeriodTypeWork, personio.PeriodTypeBreak:
+				period.Type = personio.PeriodType(attendanceEditFlags.typ)
+			default:
+				return fmt.Errorf("invalid period type %q, must be one of: work, break", attendanceEditFlags.typ)
+			}
+		}
+
+		applied, err := applyDayChanges(ctx, client, journalEntry(cmd), []dayChange{{
+			date:    date,
+			old:     oldPeriods,
+			periods: card.Periods,
+		}})
+		if err != nil {
+			return fmt.Errorf("failed to set attendance: %w", err)
+		}
+		if !applied {
+			return nil
+		}
+		log.Info().
+			Str("day", card.Date).
+			Stringer("period", card.Periods[index].ID).
+			Msg("Successfully edited attendance period.")
+
+		return printAttendanceDay(ctx, client, card)
+	},
+}
+
+// parseEndTime parses a HH:MM time of day as the end of a period, using
+// [parseTime]. Ends that are not after the start are on the next day,
+// such as for periods past midnight.
+func parseEndTime(date, start time.Time, timeString string, loc *time.Location) (time.Time, error) {
+	end, err := parseTime(date, timeString, loc)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if !end.After(start) {
+		end = end.AddDate(0, 0, 1)
+	}
+	return end, nil
+}
+
+// printAttendanceDay prints the day the same way as the "attendance show"
+// command does.
+func printAttendanceDay(ctx context.Context, client *personio.Client, card personio.Timecard) error {
+	days, err := client.SummarizeDaysContext(ctx, []personio.Timecard{card})
+	if err != nil {
+		return err
+	}
+	if cfg.Output == config.OutFormatPretty {
+		console.PrintDaySummaries(days)
+		return nil
+	}
+	return printOutput(days[0])
+}
+
+func init() {
+	attendanceCmd.AddCommand(attendanceEditCmd)
+
+	attendanceEditCmd.Flags().StringVarP(&attendanceEditFlags.start, "start", "s", "", `New start time (as HH:MM)`)
+	attendanceEditCmd.Flags().StringVarP(&attendanceEditFlags.end, "end", "e", "", `New end time (as HH:MM)`)
+	attendanceEditCmd.Flags().StringVarP(&attendanceEditFlags.project, "project", "p", "", `New project name, or "none" to remove the project`)
+	attendanceEditCmd.Flags().StringVarP(&attendanceEditFlags.comment, "comment", "c", "", `New comment, or "" to remove the comment`)
+	attendanceEditCmd.Flags().StringVarP(&attendanceEditFlags.typ, "type", "t", "", `New period type: work or break`)
+}
@@ -0,0 +1,58 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseEndTime(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	date := time.Date(2023, 1, 18, 0, 0, 0, 0, time.UTC)
+	start := time.Date(2023, 1, 18, 22, 0, 0, 0, loc)
+
+	var tests = []struct {
+		name string
+		end  string
+		want time.Time
+	}{
+		{name: "same day", end: "23:30", want: time.Date(2023, 1, 18, 23, 30, 0, 0, loc)},
+		{name: "past midnight", end: "01:00", want: time.Date(2023, 1, 19, 1, 0, 0, 0, loc)},
+		{name: "midnight", end: "00:00", want: time.Date(2023, 1, 19, 0, 0, 0, 0, loc)},
+		{name: "same as start", end: "22:00", want: time.Date(2023, 1, 19, 22, 0, 0, 0, loc)},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseEndTime(date, start, tc.end, loc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !got.Equal(tc.want) {
+				t.Errorf("want %s, got %s", tc.want, got)
+			}
+		})
+	}
+
+	if _, err := parseEndTime(date, start, "25:00", loc); err == nil {
+		t.Error("want error for invalid time, got nil")
+	}
+}
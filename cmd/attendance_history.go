@@ -0,0 +1,111 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/applejag/rootless-personio/pkg/config"
+	"github.com/applejag/rootless-personio/pkg/console"
+	"github.com/applejag/rootless-personio/pkg/journal"
+	"github.com/applejag/rootless-personio/pkg/personio"
+	"github.com/spf13/cobra"
+)
+
+var attendanceHistoryFlags = struct {
+	limit int
+}{}
+
+var attendanceHistoryCmd = &cobra.Command{
+	Use:   "history [entry-id]",
+	Args:  cobra.MaximumNArgs(1),
+	Short: "Show the journal of attendance changes",
+	Long: `Shows the journal of attendance changes made by this program, newest
+first, which can be undone via the "attendance undo" command.
+
+When given an entry ID, the changes of that entry are shown as a diff
+instead. The journal is stored locally, and changes made elsewhere, such as
+in the Personio web page, are not included.`,
+	Example: `history
+history --limit 5
+history 12`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		entries, err := readJournal()
+		if err != nil {
+			return fmt.Errorf("failed to read journal: %w", err)
+		}
+
+		if len(args) > 0 {
+			id, err := strconv.Atoi(args[0])
+			if err != nil {
+				return fmt.Errorf("invalid entry ID: %w", err)
+			}
+			i := slices.IndexFunc(entries, func(e journal.Entry) bool {
+				return e.ID == id
+			})
+			if i == -1 {
+				return fmt.Errorf("journal entry not found: #%d", id)
+			}
+			return printJournalEntry(entries[i])
+		}
+
+		if attendanceHistoryFlags.limit > 0 && len(entries) > attendanceHistoryFlags.limit {
+			entries = entries[len(entries)-attendanceHistoryFlags.limit:]
+		}
+		if cfg.Output == config.OutFormatPretty {
+			loc, err := cfg.Location()
+			if err != nil {
+				return err
+			}
+			for i := range entries {
+				entries[i].Time = entries[i].Time.In(loc)
+			}
+			console.PrintJournal(entries)
+			return nil
+		}
+		// Newest first, same as the pretty output
+		slices.Reverse(entries)
+		return printOutput(entries)
+	},
+}
+
+func printJournalEntry(entry journal.Entry) error {
+	if cfg.Output != config.OutFormatPretty {
+		return printOutput(entry)
+	}
+	loc, err := cfg.Location()
+	if err != nil {
+		return err
+	}
+	plans := make([]personio.DayPlan, 0, len(entry.Days))
+	for _, day := range entry.Days {
+		plans = append(plans, day.Plan(loc))
+	}
+	// Projects are shown by ID, so the journal can be browsed without
+	// logging in to Personio.
+	console.PrintPlan(plans, nil)
+	return nil
+}
+
+func init() {
+	attendanceCmd.AddCommand(attendanceHistoryCmd)
+
+	attendanceHistoryCmd.Flags().IntVarP(&attendanceHistoryFlags.limit, "limit", "n", 20, `Maximum number of entries to show, or 0 to show all`)
+}
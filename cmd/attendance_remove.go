@@ -18,13 +18,18 @@
 package cmd
 
 import (
+	"context"
+	"fmt"
 	"time"
 
+	"github.com/applejag/rootless-personio/pkg/journal"
+	"github.com/applejag/rootless-personio/pkg/personio"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 )
 
 var attendanceRemoveFlags = struct {
+	periods []string
 }{}
 
 var attendanceRemoveCmd = &cobra.Command{
@@ -35,29 +40,106 @@ var attendanceRemoveCmd = &cobra.Command{
 	Long: `Clears (deletes) attendance periods for a specific day.
 
 Provide the date in format YYYY-MM-DD, e.g 2023-01-25 for Jan 25, 2023.
+
+Use the --period flag to only remove some of the day's periods, while
+keeping the other periods unchanged. The period is referenced either by
+its ID, or by its position in the day, starting at 1, as shown by the
+"attendance show" command.
 `,
+	Example: `remove 2023-01-25
+remove 2023-01-25 --period 2
+remove 2023-01-25 --period 0b5a3fd2-8c43-4f0e-9c4d-3a8e34d0a1f1`,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
 		date, err := time.Parse(time.DateOnly, args[0])
+		if err != nil {
+			return fmt.Errorf("invalid date format: %w", err)
+		}
 
-		client, err := newLoggedInClient()
+		client, err := newLoggedInClient(ctx)
 		if err != nil {
 			return err
 		}
 
-		err = client.DeleteAttendance(date)
+		if len(attendanceRemoveFlags.periods) > 0 {
+			return removeAttendancePeriods(ctx, client, journalEntry(cmd), date, attendanceRemoveFlags.periods)
+		}
+
+		card, err := client.GetMyAttendanceDayContext(ctx, date)
 		if err != nil {
+			return fmt.Errorf("failed to get attendance: %w", err)
+		}
+		applied, err := applyDayChanges(ctx, client, journalEntry(cmd), []dayChange{{
+			date:   date,
+			old:    card.Periods,
+			delete: true,
+		}})
+		if err != nil || !applied {
 			return err
 		}
 		log.Info().
 			Str("day", date.Format(time.DateOnly)).
 			Msg("Successfully deleted attendance periods for day.")
 
-		return printOutputJSONOrYAML(map[string]any{
+		return printOutput(map[string]any{
 			"date": date.Format(time.DateOnly),
 		})
 	},
 }
 
+func removeAttendancePeriods(ctx context.Context, client *personio.Client, entry journal.Entry, date time.Time, refs []string) error {
+	card, err := client.GetMyAttendanceDayContext(ctx, date)
+	if err != nil {
+		return fmt.Errorf("failed to get attendance: %w", err)
+	}
+	periods, err := withoutPeriods(card, refs)
+	if err != nil {
+		return err
+	}
+
+	applied, err := applyDayChanges(ctx, client, entry, []dayChange{{
+		date:    date,
+		old:     card.Periods,
+		periods: periods,
+	}})
+	if err != nil {
+		return fmt.Errorf("failed to set attendance: %w", err)
+	}
+	if !applied {
+		return nil
+	}
+	log.Info().
+		Str("day", card.Date).
+		Int("removed", len(card.Periods)-len(periods)).
+		Msg("Successfully removed attendance periods.")
+
+	card.Periods = periods
+	return printAttendanceDay(ctx, client, card)
+}
+
+// withoutPeriods returns the day's periods without the referenced ones,
+// using [personio.Timecard.FindPeriod].
+func withoutPeriods(card personio.Timecard, refs []string) ([]personio.Period, error) {
+	// Find all periods first, so the positions refer to the original list
+	remove := make([]bool, len(card.Periods))
+	for _, ref := range refs {
+		index, err := card.FindPeriod(ref)
+		if err != nil {
+			return nil, err
+		}
+		remove[index] = true
+	}
+	periods := []personio.Period{}
+	for i, period := range card.Periods {
+		if !remove[i] {
+			periods = append(periods, period)
+		}
+	}
+	return periods, nil
+}
+
 func init() {
 	attendanceCmd.AddCommand(attendanceRemoveCmd)
+
+	attendanceRemoveCmd.Flags().StringArrayVarP(&attendanceRemoveFlags.periods, "period", "p", nil, `ID or position of a period to remove, instead of the whole day (can be repeated)`)
 }
@@ -0,0 +1,98 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/personio"
+	"github.com/google/uuid"
+)
+
+func TestWithoutPeriods(t *testing.T) {
+	date := time.Date(2023, 1, 18, 0, 0, 0, 0, time.UTC)
+	period := func(from, to time.Duration) personio.Period {
+		return personio.Period{
+			ID:    uuid.New(),
+			Type:  personio.PeriodTypeWork,
+			Start: personio.PersonioTime{Time: date.Add(from)},
+			End:   personio.PersonioTime{Time: date.Add(to)},
+		}
+	}
+	card := personio.Timecard{
+		Date: "2023-01-18",
+		Periods: []personio.Period{
+			period(8*time.Hour, 12*time.Hour),
+			period(13*time.Hour, 17*time.Hour),
+			// Past midnight, shown as 22:00–01:00+1
+			period(22*time.Hour, 25*time.Hour),
+		},
+	}
+
+	var tests = []struct {
+		name string
+		refs []string
+		want []personio.Period
+	}{
+		{
+			name: "position past midnight",
+			refs: []string{"3"},
+			want: card.Periods[:2],
+		},
+		{
+			name: "positions refer to the original list",
+			refs: []string{"1", "3"},
+			want: card.Periods[1:2],
+		},
+		{
+			name: "ID and position",
+			refs: []string{card.Periods[1].ID.String(), "1"},
+			want: card.Periods[2:],
+		},
+		{
+			name: "all",
+			refs: []string{"1", "2", "3"},
+			want: []personio.Period{},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := withoutPeriods(card, tc.refs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("want %d periods, got %d", len(tc.want), len(got))
+			}
+			for i := range got {
+				if got[i].ID != tc.want[i].ID {
+					t.Errorf("period %d: want ID %s, got %s", i, tc.want[i].ID, got[i].ID)
+				}
+			}
+		})
+	}
+
+	if _, err := withoutPeriods(card, []string{"4"}); !errors.Is(err, personio.ErrPeriodNotFound) {
+		t.Errorf("want ErrPeriodNotFound, got %v", err)
+	}
+	if len(card.Periods) != 3 {
+		t.Errorf("want original periods unchanged, got %d", len(card.Periods))
+	}
+}
@@ -48,30 +48,39 @@ The input is provided by JSON objects in a file as specified with the --file fla
 The input should be a stream of Personio attendance periods. Example:
 
     {
-      "start": "2023-01-18T08:00:00Z",
-      "end": "2023-01-18T12:00:00Z",
+      "start": "2023-01-18T08:00:00",
+      "end": "2023-01-18T12:00:00",
       "comment": "Work before lunch",
       "type": "work"
     }
     {
-      "start": "2023-01-18T12:00:00Z",
-      "end": "2023-01-18T13:00:00Z",
+      "start": "2023-01-18T12:00:00",
+      "end": "2023-01-18T13:00:00",
       "comment": "Lunch break",
       "type": "break"
     }
     {
-      "start": "2023-01-18T13:00:00Z",
-      "end": "2023-01-18T17:00:00Z",
+      "start": "2023-01-18T13:00:00",
+      "end": "2023-01-18T17:00:00",
       "comment": "Work after lunch",
       "type": "work"
     }
 
+Times without an offset are wall-clock times in the configured timezone.
+Times with an offset, such as "2023-01-18T07:00:00Z", are converted to
+the configured timezone, which also decides which day they belong to.
+
+Periods that start at the same time as an existing period of the day
+update that period, and the remaining existing periods are removed.
+So setting the same periods again changes nothing.
+
 It is incorrect to provide a JSON array with the elements.
 If you have a JSON array, you can convert it to a stream via jq like so:
 
     jq '.[]' my-file.json
 `,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
 		var file io.ReadCloser = os.Stdin
 		if attendanceSetFlags.file != "-" {
 			var err error
@@ -82,7 +91,7 @@ If you have a JSON array, you can convert it to a stream via jq like so:
 		}
 		defer file.Close()
 
-		client, err := newLoggedInClient()
+		client, err := newLoggedInClient(ctx)
 		if err != nil {
 			return err
 		}
@@ -98,12 +107,20 @@ If you have a JSON array, you can convert it to a stream via jq like so:
 			if err != nil {
 				return fmt.Errorf("read periods: %w", err)
 			}
-			dur := p.End.Sub(p.Start)
+			start, err := parseImportTime(p.Start, client.Location())
+			if err != nil {
+				return fmt.Errorf("read periods: start: %w", err)
+			}
+			end, err := parseImportTime(p.End, client.Location())
+			if err != nil {
+				return fmt.Errorf("read periods: end: %w", err)
+			}
+			dur := end.Sub(start)
 
 			log.Debug().
 				Str("type", string(p.Type)).
-				Time("start", p.Start).
-				Time("end", p.End).
+				Time("start", start).
+				Time("end", end).
 				Str("dur", dur.Truncate(time.Second).String()).
 				Str("comment", p.Comment).
 				Msg("Read attendance period.")
@@ -111,8 +128,8 @@ If you have a JSON array, you can convert it to a stream via jq like so:
 			if dur < cfg.MinimumPeriodDuration {
 				log.Warn().
 					Str("type", string(p.Type)).
-					Time("start", p.Start).
-					Time("end", p.End).
+					Time("start", start).
+					Time("end", end).
 					Str("dur", dur.Truncate(time.Second).String()).
 					Str("comment", p.Comment).
 					Str("minimumDuration", cfg.MinimumPeriodDuration.String()).
@@ -121,12 +138,12 @@ If you have a JSON array, you can convert it to a stream via jq like so:
 			}
 
 			personioPeriod := personio.Period{
-				Start: personio.PersonioTime{p.Start},
-				End:   personio.PersonioTime{p.End},
+				Start: personio.PersonioTime{Time: start},
+				End:   personio.PersonioTime{Time: end},
 				Type:  personio.PeriodType(p.Type),
 			}
 			if p.Project != "" {
-				projectId, err := client.GetProjectID(p.Project)
+				projectId, err := client.GetProjectIDContext(ctx, p.Project)
 				if err != nil {
 					return fmt.Errorf("failed to get project ID: %w", err)
 				}
@@ -156,11 +173,34 @@ If you have a JSON array, you can convert it to a stream via jq like so:
 		}
 		var printableGroups []PerDay
 
-		for _, group := range periodsPerDay {
-			err = client.SetAttendance(group.Values[0].Start.Time, group.Values)
-			if err != nil {
-				return err
+		firstDate := periodsPerDay[0].Values[0].Start.Time
+		lastDate := periodsPerDay[len(periodsPerDay)-1].Values[0].Start.Time
+		cal, err := client.GetMyAttendanceCalendarContext(ctx, firstDate, lastDate)
+		if err != nil {
+			return fmt.Errorf("failed to get attendance calendar: %w", err)
+		}
+		var changes []dayChange
+		for i, group := range periodsPerDay {
+			change := dayChange{
+				date: group.Values[0].Start.Time,
+			}
+			for _, card := range cal {
+				if card.Date == group.Key {
+					change.old = card.Periods
+				}
 			}
+			// Update the existing periods, so importing the same
+			// periods again does not replace them all
+			periodsPerDay[i].Values = personio.ReusePeriodIDs(change.old, group.Values)
+			change.periods = periodsPerDay[i].Values
+			changes = append(changes, change)
+		}
+
+		applied, err := applyDayChanges(ctx, client, journalEntry(cmd), changes)
+		if err != nil || !applied {
+			return err
+		}
+		for _, group := range periodsPerDay {
 			log.Info().
 				Str("day", group.Key).
 				Int("periods", len(group.Values)).
@@ -171,18 +211,28 @@ If you have a JSON array, you can convert it to a stream via jq like so:
 			})
 		}
 
-		return printOutputJSONOrYAML(map[string]any{
+		return printOutput(map[string]any{
 			"groups": printableGroups,
 		})
 	},
 }
 
 type importPeriod struct {
-	Start   time.Time `json:"start"`
-	End     time.Time `json:"end"`
-	Project string    `json:"project"`
-	Comment string    `json:"comment"`
-	Type    string    `json:"type"`
+	Start   string `json:"start"`
+	End     string `json:"end"`
+	Project string `json:"project"`
+	Comment string `json:"comment"`
+	Type    string `json:"type"`
+}
+
+// parseImportTime parses a RFC 3339 timestamp and converts it to the given
+// location, or parses it as a wall-clock time in that location if it has
+// no offset.
+func parseImportTime(s string, loc *time.Location) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t.In(loc), nil
+	}
+	return time.ParseInLocation("2006-01-02T15:04:05", s, loc)
 }
 
 func init() {
@@ -0,0 +1,153 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/config"
+	"github.com/applejag/rootless-personio/pkg/console"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+var attendanceShowCmd = &cobra.Command{
+	Use:   "show [date|week|range]",
+	Args:  cobra.MaximumNArgs(1),
+	Short: "Show the attendance periods of a day, a week or a range",
+	Long: `Show the attendance periods of each day, with their start and end
+time, type, project and comment, followed by the tracked work time per
+project, the breaks, and the difference from the target work time.
+
+The days to show can be any of:
+
+    today, yesterday         a single day (default today)
+    2023-01-25               a single day
+    week, last-week          the current or previous week, Monday to Sunday
+    2023-W04                 an ISO 8601 week, Monday to Sunday
+    2023-01                  a whole month
+    2023-01-23..2023-01-27   a range of days, inclusive
+`,
+	Example: `show
+show yesterday
+show week
+show 2023-01-23..2023-01-27 --output csv`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		client, err := newLoggedInClient(ctx)
+		if err != nil {
+			return err
+		}
+
+		spec := "today"
+		if len(args) > 0 {
+			spec = args[0]
+		}
+		startDate, endDate, err := parseDateRange(spec, time.Now().In(client.Location()))
+		if err != nil {
+			return err
+		}
+		log.Debug().
+			Time("start", startDate).
+			Time("end", endDate).
+			Msg("Date range.")
+
+		cal, err := client.GetMyAttendanceCalendarContext(ctx, startDate, endDate)
+		if err != nil {
+			return err
+		}
+		days, err := client.SummarizeDaysContext(ctx, cal)
+		if err != nil {
+			return err
+		}
+
+		if cfg.Output == config.OutFormatPretty {
+			console.PrintDaySummaries(days)
+			return nil
+		}
+		return printOutput(days)
+	},
+}
+
+// parseDateRange parses the dates to show, as described in the
+// "attendance show" command's help text, and returns the first and last
+// day of the range, as midnight UTC.
+func parseDateRange(spec string, now time.Time) (time.Time, time.Time, error) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	switch spec {
+	case "today":
+		return today, today, nil
+	case "yesterday":
+		yesterday := today.AddDate(0, 0, -1)
+		return yesterday, yesterday, nil
+	case "week":
+		monday := startOfWeek(today)
+		return monday, monday.AddDate(0, 0, 6), nil
+	case "last-week":
+		monday := startOfWeek(today).AddDate(0, 0, -7)
+		return monday, monday.AddDate(0, 0, 6), nil
+	}
+
+	if start, end, ok := strings.Cut(spec, ".."); ok {
+		startDate, err := time.Parse(time.DateOnly, start)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid range start: %w", err)
+		}
+		endDate, err := time.Parse(time.DateOnly, end)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid range end: %w", err)
+		}
+		if endDate.Before(startDate) {
+			return time.Time{}, time.Time{}, errors.New("invalid range: end date is before start date")
+		}
+		return startDate, endDate, nil
+	}
+
+	var year, week int
+	if n, err := fmt.Sscanf(spec, "%4d-W%2d", &year, &week); err == nil && n == 2 && len(spec) == len("2006-W01") {
+		// January 4th is always in the first ISO week of the year
+		monday := startOfWeek(time.Date(year, 1, 4, 0, 0, 0, 0, time.UTC)).AddDate(0, 0, (week-1)*7)
+		if _, w := monday.ISOWeek(); w != week {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid week: %s", spec)
+		}
+		return monday, monday.AddDate(0, 0, 6), nil
+	}
+
+	if month, err := time.Parse("2006-01", spec); err == nil {
+		return month, month.AddDate(0, 1, -1), nil
+	}
+
+	date, err := time.Parse(time.DateOnly, spec)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid date, week or range: %q", spec)
+	}
+	return date, date, nil
+}
+
+// startOfWeek returns the Monday of the date's week.
+func startOfWeek(date time.Time) time.Time {
+	// Monday is the first day of the week, while time.Sunday is 0
+	return date.AddDate(0, 0, -((int(date.Weekday()) + 6) % 7))
+}
+
+func init() {
+	attendanceCmd.AddCommand(attendanceShowCmd)
+}
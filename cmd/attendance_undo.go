@@ -0,0 +1,194 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/config"
+	"github.com/applejag/rootless-personio/pkg/console"
+	"github.com/applejag/rootless-personio/pkg/journal"
+	"github.com/applejag/rootless-personio/pkg/personio"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+var attendanceUndoFlags = struct {
+	force bool
+}{}
+
+var attendanceUndoCmd = &cobra.Command{
+	Use:   "undo [n]",
+	Args:  cobra.MaximumNArgs(1),
+	Short: "Undo the last attendance changes",
+	Long: `Restores the attendance of the days changed by the last n changes
+(default 1) in the journal, as shown by the "attendance history" command.
+
+Changes that have already been undone are skipped, and the undo itself is
+added to the journal, so it can be inspected afterwards. When all days are
+already as before the changes, nothing is sent to Personio, but the changes
+are still marked as undone.
+
+The undo is refused if any of the days have been changed since, such as in
+the Personio web page, as those changes would otherwise be lost.
+Use the --force flag to restore the days anyway.`,
+	Example: `undo
+undo 3
+undo --dry-run`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		n := 1
+		if len(args) > 0 {
+			var err error
+			n, err = strconv.Atoi(args[0])
+			if err != nil || n < 1 {
+				return fmt.Errorf("invalid number of changes to undo: %q", args[0])
+			}
+		}
+		entries, err := readJournal()
+		if err != nil {
+			return fmt.Errorf("failed to read journal: %w", err)
+		}
+		undoEntries, err := journal.Undoable(entries, n)
+		if err != nil {
+			return err
+		}
+
+		client, err := newLoggedInClient(ctx)
+		if err != nil {
+			return err
+		}
+		entry := journalEntry(cmd)
+		for _, e := range undoEntries {
+			if e.BaseURL != client.BaseURL || e.EmployeeID != client.EmployeeID {
+				return fmt.Errorf("journal entry #%d was made for employee %d at %s, but logged in as employee %d at %s",
+					e.ID, e.EmployeeID, e.BaseURL, client.EmployeeID, client.BaseURL)
+			}
+			entry.UndoOf = append(entry.UndoOf, e.ID)
+		}
+
+		days := journal.UndoDays(undoEntries)
+		firstDate, err := time.Parse(time.DateOnly, days[0].Date)
+		if err != nil {
+			return fmt.Errorf("invalid date in journal: %w", err)
+		}
+		lastDate, err := time.Parse(time.DateOnly, days[len(days)-1].Date)
+		if err != nil {
+			return fmt.Errorf("invalid date in journal: %w", err)
+		}
+		cal, err := client.GetMyAttendanceCalendarContext(ctx, firstDate, lastDate)
+		if err != nil {
+			return fmt.Errorf("failed to get attendance calendar: %w", err)
+		}
+
+		loc := client.Location()
+		var changes []dayChange
+		var cards []personio.Timecard
+		for _, day := range days {
+			card, ok := findTimecard(cal, day.Date)
+			if !ok {
+				return fmt.Errorf("day not found in attendance calendar: %s", day.Date)
+			}
+			if undoConflict(card, day, loc) {
+				if !attendanceUndoFlags.force {
+					return fmt.Errorf("attendance of %s has changed since it was recorded in the journal, use --force to restore it anyway", day.Date)
+				}
+				log.Warn().Str("day", day.Date).
+					Msg("Restoring day that has changed since it was recorded in the journal.")
+			}
+			date, err := time.Parse(time.DateOnly, day.Date)
+			if err != nil {
+				return fmt.Errorf("invalid date in journal: %w", err)
+			}
+			change := dayChange{
+				date:    date,
+				old:     card.Periods,
+				periods: journal.PersonioPeriods(day.Before, loc),
+			}
+			if len(day.Before) == 0 {
+				if len(card.Periods) == 0 {
+					// Already empty, so there is nothing to restore
+					continue
+				}
+				// The day did not have any periods, so remove it altogether
+				change.delete = true
+			}
+			changes = append(changes, change)
+			card.Periods = change.periods
+			cards = append(cards, card)
+		}
+		if len(changes) == 0 {
+			log.Info().
+				Ints("entries", entry.UndoOf).
+				Msg("All days are already as before the changes, so there is nothing to restore.")
+			if !rootFlags.dryRun {
+				// Still mark the entries as undone, so they are not undone again
+				entry.BaseURL = client.BaseURL
+				entry.EmployeeID = client.EmployeeID
+				writeJournal(entry)
+			}
+			return nil
+		}
+
+		applied, err := applyDayChanges(ctx, client, entry, changes)
+		if err != nil || !applied {
+			return err
+		}
+		log.Info().
+			Ints("entries", entry.UndoOf).
+			Int("days", len(changes)).
+			Msg("Successfully undid attendance changes.")
+
+		summaries, err := client.SummarizeDaysContext(ctx, cards)
+		if err != nil {
+			return err
+		}
+		if cfg.Output == config.OutFormatPretty {
+			console.PrintDaySummaries(summaries)
+			return nil
+		}
+		return printOutput(summaries)
+	},
+}
+
+func findTimecard(cal []personio.Timecard, date string) (personio.Timecard, bool) {
+	for _, card := range cal {
+		if card.Date == date {
+			return card, true
+		}
+	}
+	return personio.Timecard{}, false
+}
+
+// undoConflict returns true if the day's current attendance differs from
+// the state that the journal says it was left in.
+func undoConflict(card personio.Timecard, day journal.Day, loc *time.Location) bool {
+	if len(day.After) > 0 && day.DayID != nil && card.DayID != nil && *day.DayID != *card.DayID {
+		return true
+	}
+	after := journal.PersonioPeriods(day.After, loc)
+	return personio.DiffPeriods(day.Date, card.Periods, after).HasChanges()
+}
+
+func init() {
+	attendanceCmd.AddCommand(attendanceUndoCmd)
+
+	attendanceUndoCmd.Flags().BoolVarP(&attendanceUndoFlags.force, "force", "f", false, `Restore the days even if they have changed since`)
+}
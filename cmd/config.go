@@ -32,12 +32,24 @@ var configCmd = &cobra.Command{
 	Use:   "config",
 	Short: "Prints the parsed config",
 	RunE: func(cmd *cobra.Command, args []string) error {
+		if !configFlags.showPassword {
+			cfg = cfg.RedactSecrets()
+		}
+		if outputQuery != nil {
+			// Convert via YAML, to query the same field names as in the file
+			b, err := yaml.Marshal(cfg)
+			if err != nil {
+				return err
+			}
+			var model any
+			if err := yaml.Unmarshal(b, &model); err != nil {
+				return err
+			}
+			return printOutput(model)
+		}
 		enc := yaml.NewEncoder(os.Stdout)
 		enc.SetIndent(2)
 		defer enc.Close()
-		if !configFlags.showPassword {
-			cfg.Auth.Password = "/redacted/"
-		}
 		return enc.Encode(cfg)
 	},
 }
@@ -45,5 +57,5 @@ var configCmd = &cobra.Command{
 func init() {
 	rootCmd.AddCommand(configCmd)
 
-	configCmd.Flags().BoolVar(&configFlags.showPassword, "show-password", false, "Show the password in the output")
+	configCmd.Flags().BoolVar(&configFlags.showPassword, "show-password", false, "Show the passwords, secrets and tokens in the output")
 }
@@ -0,0 +1,93 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/applejag/rootless-personio/pkg/config"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var configGetFlags = struct {
+	showPassword bool
+}{}
+
+var configGetCmd = &cobra.Command{
+	Use:   "get <key>",
+	Short: "Prints the effective value of a config",
+	Long: `Prints the value of a config, after the config files, env vars,
+flags and the selected profile have been applied.
+
+The key is the dot-separated path to the config, as in the config file,
+e.g "baseUrl", "log.level" or "profiles.work.auth.email".
+Use "config path" to see where the value came from.
+
+Passwords, secrets and tokens are redacted, unless the --show-password
+flag is given.`,
+	Example: `  rootless-personio config get log.level
+  rootless-personio config get auth`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		key := args[0]
+		if _, ok := config.SchemaForKey(key); !ok {
+			return fmt.Errorf("%w: %q", config.ErrUnknownKey, key)
+		}
+		c := cfg
+		if !configGetFlags.showPassword {
+			c = c.RedactSecrets()
+		}
+		data, err := yaml.Marshal(c)
+		if err != nil {
+			return err
+		}
+		node, ok, err := config.LookupValue(data, key)
+		if err != nil {
+			return err
+		}
+
+		if cfg.Output != config.OutFormatPretty {
+			var value any
+			if ok {
+				if err := node.Decode(&value); err != nil {
+					return err
+				}
+			}
+			return printOutput(value)
+		}
+		if !ok {
+			return nil
+		}
+		if node.Kind == yaml.ScalarNode {
+			fmt.Println(node.Value)
+			return nil
+		}
+		enc := yaml.NewEncoder(os.Stdout)
+		enc.SetIndent(2)
+		defer enc.Close()
+		return enc.Encode(node)
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configGetCmd)
+
+	configGetCmd.Flags().BoolVar(&configGetFlags.showPassword, "show-password", false, "Show the passwords, secrets and tokens in the output")
+}
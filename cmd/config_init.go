@@ -0,0 +1,388 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/applejag/rootless-personio/pkg/config"
+	"github.com/applejag/rootless-personio/pkg/personio"
+	"github.com/applejag/rootless-personio/pkg/util"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var configInitFlags = struct {
+	file  string
+	force bool
+}{}
+
+type configInitAnswers struct {
+	BaseURL           string
+	Source            string
+	Email             string
+	Password          string
+	PasswordCommand   string
+	EnvFile           string
+	EncryptedFile     string
+	StandardStartTime string
+	Output            string
+}
+
+var configInitCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Writes a new config file by asking some questions",
+	Long: `Asks for the most common configs, such as your Personio URL and
+how to read your credentials, optionally tests logging in with them,
+and writes them to a commented config file.
+
+The file is written to your user's config directory by default,
+e.g ~/.config/personio.yaml on Linux, and is only readable by you.`,
+	Args: cobra.NoArgs,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		// Intentionally ignores config errors from root.go,
+		// so this command can be used to rewrite an invalid config
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		file := configInitFlags.file
+		if file == "" {
+			var err error
+			file, err = config.UserConfigFile()
+			if err != nil {
+				return err
+			}
+		}
+		if _, err := os.Stat(file); err == nil && !configInitFlags.force {
+			overwrite := false
+			if err := survey.AskOne(&survey.Confirm{
+				Message: fmt.Sprintf("Overwrite existing %s?", util.PrettyPath(file)),
+			}, &overwrite); err != nil {
+				return err
+			}
+			if !overwrite {
+				return errors.New("aborted, config file already exists")
+			}
+		}
+
+		answers, err := askConfigInit()
+		if err != nil {
+			return err
+		}
+
+		testLogin := true
+		if err := survey.AskOne(&survey.Confirm{
+			Message: "Test logging in now?",
+			Default: true,
+		}, &testLogin); err != nil {
+			return err
+		}
+		if testLogin {
+			if err := testConfigInitLogin(ctx, answers); err != nil {
+				log.Warn().Err(err).Msg("Failed to log in.")
+				writeAnyway := false
+				if err := survey.AskOne(&survey.Confirm{
+					Message: "Write the config file anyway?",
+				}, &writeAnyway); err != nil {
+					return err
+				}
+				if !writeAnyway {
+					return err
+				}
+			}
+		}
+
+		data, err := renderConfigInit(answers)
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+			return err
+		}
+		if err := os.WriteFile(file, data, 0600); err != nil {
+			return err
+		}
+		// WriteFile only applies the permissions on newly created files
+		if err := os.Chmod(file, 0600); err != nil {
+			return err
+		}
+		fmt.Printf("Written config to %s\n", util.PrettyPath(file))
+		return nil
+	},
+}
+
+func askConfigInit() (configInitAnswers, error) {
+	answers := configInitAnswers{
+		BaseURL:           cfg.BaseURL,
+		Source:            string(cfg.Auth.Source),
+		Email:             cfg.Auth.Email,
+		StandardStartTime: cfg.StandardStartTime,
+		Output:            string(cfg.Output),
+	}
+	if answers.StandardStartTime == "" {
+		answers.StandardStartTime = "08:00"
+	}
+
+	var sources []string
+	for _, s := range config.CredentialSources {
+		sources = append(sources, string(s))
+	}
+	outputs := []string{
+		string(config.OutFormatPretty),
+		string(config.OutFormatJSON),
+		string(config.OutFormatYAML),
+		string(config.OutFormatCSV),
+		string(config.OutFormatTSV),
+	}
+	// Survey fails if the default is not one of the options,
+	// such as when the current config is invalid
+	if !slices.Contains(sources, answers.Source) {
+		answers.Source = string(config.CredentialSourceDefault)
+	}
+	if !slices.Contains(outputs, answers.Output) {
+		answers.Output = string(config.OutFormatPretty)
+	}
+	questions := []*survey.Question{
+		{
+			Name: "BaseURL",
+			Prompt: &survey.Input{
+				Message: "Personio URL:",
+				Default: answers.BaseURL,
+				Help:    "The URL you use to access Personio in your browser, e.g https://example.personio.de",
+			},
+			Validate: func(ans any) error {
+				s, _ := ans.(string)
+				if s == "" {
+					return errors.New("value is required")
+				}
+				_, err := personio.NormalizeBaseURL(s)
+				return err
+			},
+		},
+		{
+			Name: "Source",
+			Prompt: &survey.Select{
+				Message: "Read credentials from:",
+				Options: sources,
+				Default: answers.Source,
+				Description: func(value string, index int) string {
+					switch config.CredentialSource(value) {
+					case config.CredentialSourceConfig:
+						return "this config file"
+					case config.CredentialSourceKeepass:
+						return "a running KeePassXC"
+					case config.CredentialSourceCommand:
+						return "a shell command that prints the password"
+					case config.CredentialSourceEnvFile:
+						return "a file with env vars"
+					case config.CredentialSourceEncryptedFile:
+						return `a file written by "credentials encrypt"`
+					}
+					return ""
+				},
+			},
+		},
+	}
+	if err := survey.Ask(questions, &answers); err != nil {
+		return answers, err
+	}
+
+	switch config.CredentialSource(answers.Source) {
+	case config.CredentialSourceConfig:
+		questions = []*survey.Question{
+			{
+				Name:     "Email",
+				Prompt:   &survey.Input{Message: "Email:", Default: answers.Email},
+				Validate: survey.Required,
+			},
+			{
+				Name:     "Password",
+				Prompt:   &survey.Password{Message: "Password:"},
+				Validate: survey.Required,
+			},
+		}
+	case config.CredentialSourceCommand:
+		questions = []*survey.Question{
+			{
+				Name:     "Email",
+				Prompt:   &survey.Input{Message: "Email:", Default: answers.Email},
+				Validate: survey.Required,
+			},
+			{
+				Name:     "PasswordCommand",
+				Prompt:   &survey.Input{Message: "Password command:", Default: cfg.Auth.PasswordCommand, Help: "e.g pass show personio"},
+				Validate: survey.Required,
+			},
+		}
+	case config.CredentialSourceEnvFile:
+		questions = []*survey.Question{{
+			Name:     "EnvFile",
+			Prompt:   &survey.Input{Message: "Env file:", Default: cfg.Auth.EnvFile, Help: "Path to a file, or fd:N to read from file descriptor N"},
+			Validate: survey.Required,
+		}}
+	case config.CredentialSourceEncryptedFile:
+		questions = []*survey.Question{{
+			Name:     "EncryptedFile",
+			Prompt:   &survey.Input{Message: "Encrypted file:", Default: cfg.Auth.EncryptedFile, Help: `Written by "rootless-personio credentials encrypt"`},
+			Validate: survey.Required,
+		}}
+	default:
+		questions = nil
+	}
+	questions = append(questions,
+		&survey.Question{
+			Name: "StandardStartTime",
+			Prompt: &survey.Input{
+				Message: "Standard start time:",
+				Default: answers.StandardStartTime,
+				Help:    "When your work day starts (HH:MM), used when adding attendance without a start time",
+			},
+			Validate: func(ans any) error {
+				s, _ := ans.(string)
+				if _, err := time.Parse("15:04", s); err != nil {
+					return errors.New("must be in HH:MM format")
+				}
+				return nil
+			},
+		},
+		&survey.Question{
+			Name: "Output",
+			Prompt: &survey.Select{
+				Message: "Output format:",
+				Options: outputs,
+				Default: answers.Output,
+			},
+		},
+	)
+	if err := survey.Ask(questions, &answers); err != nil {
+		return answers, err
+	}
+	answers.BaseURL, _ = personio.NormalizeBaseURL(answers.BaseURL)
+	return answers, nil
+}
+
+func (a configInitAnswers) auth() config.Auth {
+	auth := cfg.Auth
+	auth.Source = config.CredentialSource(a.Source)
+	auth.Email = a.Email
+	auth.Password = a.Password
+	auth.PasswordCommand = a.PasswordCommand
+	auth.EnvFile = a.EnvFile
+	auth.EncryptedFile = a.EncryptedFile
+	return auth
+}
+
+func testConfigInitLogin(ctx context.Context, answers configInitAnswers) error {
+	cfg.BaseURL = answers.BaseURL
+	cfg.Auth = answers.auth()
+	client, err := newClient()
+	if err != nil {
+		return err
+	}
+	if err := client.LoginContext(ctx, cfg.Auth); err != nil {
+		if err := handleLoginError(ctx, client, err, cfg.Auth); err != nil {
+			return err
+		}
+	}
+	log.Info().Int("employeeId", client.EmployeeID).
+		Msg("Successfully logged in.")
+	saveSession(client)
+	return nil
+}
+
+var configInitTemplate = template.Must(template.New("personio.yaml").
+	Funcs(template.FuncMap{"yaml": yamlScalar}).
+	Parse(`# yaml-language-server: $schema=https://github.com/applejag/rootless-personio/raw/main/personio.schema.json
+#
+# Written by "rootless-personio config init". See the defaults for all
+# available configs: https://github.com/applejag/rootless-personio/blob/main/personio.yaml
+
+# Base URL for accessing Personio.
+baseUrl: {{ yaml .BaseURL }}
+
+auth:
+  # Where to read the credentials from.
+  source: {{ .Source }} # config | keepass | command | envFile | encryptedFile
+{{- if .Email }}
+  email: {{ yaml .Email }}
+{{- end }}
+{{- if .Password }}
+  password: {{ yaml .Password }}
+{{- end }}
+{{- if .PasswordCommand }}
+  # Shell command that prints the password.
+  passwordCommand: {{ yaml .PasswordCommand }}
+{{- end }}
+{{- if .EnvFile }}
+  # File with PERSONIO_AUTH_EMAIL=... and PERSONIO_AUTH_PASSWORD=... lines.
+  envFile: {{ yaml .EnvFile }}
+{{- end }}
+{{- if .EncryptedFile }}
+  # File written by "rootless-personio credentials encrypt".
+  encryptedFile: {{ yaml .EncryptedFile }}
+{{- end }}
+  # Generate 2FA codes from the TOTP secret, instead of asking for them.
+  # totpSecret: JBSWY3DPEHPK3PXP
+
+# Time of day when your work day starts, used when adding attendance
+# without a start time.
+standardStartTime: {{ yaml .StandardStartTime }}
+
+# Timezone that your attendance is tracked in.
+# Defaults to the system's local timezone.
+# timezone: Europe/Berlin
+
+# Format of the command results written to STDOUT.
+output: {{ .Output }} # pretty | json | yaml | csv | tsv
+`))
+
+func renderConfigInit(answers configInitAnswers) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := configInitTemplate.Execute(&buf, answers); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// yamlScalar formats a string as a YAML value, quoting it when needed.
+func yamlScalar(s string) (string, error) {
+	b, err := yaml.Marshal(s)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(string(b), "\n"), nil
+}
+
+func init() {
+	configCmd.AddCommand(configInitCmd)
+
+	configInitCmd.Flags().StringVarP(&configInitFlags.file, "file", "f", "", `File to write to (default is personio.yaml in your user's config directory)`)
+	configInitCmd.Flags().BoolVar(&configInitFlags.force, "force", false, "Overwrite the file without asking, if it already exists")
+}
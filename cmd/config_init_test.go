@@ -0,0 +1,140 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"testing"
+
+	"github.com/applejag/rootless-personio/pkg/config"
+	"gopkg.in/yaml.v3"
+)
+
+func TestRenderConfigInit(t *testing.T) {
+	var tests = []struct {
+		name    string
+		answers configInitAnswers
+	}{
+		{
+			name: "plain",
+			answers: configInitAnswers{
+				BaseURL:           "https://example.personio.de",
+				Source:            string(config.CredentialSourceConfig),
+				Email:             "jane.doe@example.com",
+				Password:          "hunter2",
+				StandardStartTime: "08:00",
+				Output:            string(config.OutFormatPretty),
+			},
+		},
+		{
+			name: "special characters",
+			answers: configInitAnswers{
+				BaseURL:           "https://example.personio.de",
+				Source:            string(config.CredentialSourceConfig),
+				Email:             "jane+personio@example.com",
+				Password:          `*hunter2: # "not a comment" 'quoted'`,
+				StandardStartTime: "08:00",
+				Output:            string(config.OutFormatJSON),
+			},
+		},
+		{
+			name: "leading indicators",
+			answers: configInitAnswers{
+				BaseURL:           "https://example.personio.de",
+				Source:            string(config.CredentialSourceConfig),
+				Email:             "- jane@example.com",
+				Password:          "&anchor !tag |block >folded %directive @reserved ` ",
+				StandardStartTime: "08:00",
+				Output:            string(config.OutFormatYAML),
+			},
+		},
+		{
+			name: "looks like other types",
+			answers: configInitAnswers{
+				BaseURL:           "https://example.personio.de",
+				Source:            string(config.CredentialSourceConfig),
+				Email:             "null",
+				Password:          "0123",
+				StandardStartTime: "09:30",
+				Output:            string(config.OutFormatPretty),
+			},
+		},
+		{
+			name: "command",
+			answers: configInitAnswers{
+				BaseURL:           "https://example.personio.de",
+				Source:            string(config.CredentialSourceCommand),
+				Email:             "jane@example.com",
+				PasswordCommand:   `pass show "personio: work" | head -n1 # first line`,
+				StandardStartTime: "08:00",
+				Output:            string(config.OutFormatCSV),
+			},
+		},
+		{
+			name: "files",
+			answers: configInitAnswers{
+				BaseURL:           "https://example.personio.de",
+				Source:            string(config.CredentialSourceEnvFile),
+				EnvFile:           `C:\Users\Jane Doe\personio: work.env`,
+				EncryptedFile:     "~/#personio.enc",
+				StandardStartTime: "08:00",
+				Output:            string(config.OutFormatTSV),
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			data, err := renderConfigInit(tc.answers)
+			if err != nil {
+				t.Fatalf("render: %s", err)
+			}
+			errs, err := config.Validate(data)
+			if err != nil {
+				t.Fatalf("validate: %s\n%s", err, data)
+			}
+			for _, e := range errs {
+				t.Errorf("validation error: %s", e)
+			}
+
+			var got config.Config
+			if err := yaml.Unmarshal(data, &got); err != nil {
+				t.Fatalf("unmarshal: %s\n%s", err, data)
+			}
+			a := tc.answers
+			var fields = []struct {
+				name string
+				want string
+				got  string
+			}{
+				{"baseUrl", a.BaseURL, got.BaseURL},
+				{"auth.source", a.Source, string(got.Auth.Source)},
+				{"auth.email", a.Email, got.Auth.Email},
+				{"auth.password", a.Password, got.Auth.Password},
+				{"auth.passwordCommand", a.PasswordCommand, got.Auth.PasswordCommand},
+				{"auth.envFile", a.EnvFile, got.Auth.EnvFile},
+				{"auth.encryptedFile", a.EncryptedFile, got.Auth.EncryptedFile},
+				{"standardStartTime", a.StandardStartTime, got.StandardStartTime},
+				{"output", a.Output, string(got.Output)},
+			}
+			for _, f := range fields {
+				if f.got != f.want {
+					t.Errorf("%s: want %q, got %q", f.name, f.want, f.got)
+				}
+			}
+		})
+	}
+}
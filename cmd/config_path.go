@@ -0,0 +1,110 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/applejag/rootless-personio/pkg/config"
+	"github.com/applejag/rootless-personio/pkg/console"
+	"github.com/applejag/rootless-personio/pkg/util"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+var configPathCmd = &cobra.Command{
+	Use:   "path",
+	Short: "Shows where the config is read from",
+	Long: `Shows the config files that are looked for, in the order they are read,
+and where the effective value of each config field came from:
+the defaults, a config file, an env var, a flag, or the selected profile.
+
+Later config files override the values from earlier ones. Env vars
+override the files, and flags override the env vars.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		flags := map[string]*pflag.Flag{}
+		for key, flagName := range configKeyFlags {
+			flags[key] = rootCmd.PersistentFlags().Lookup(flagName)
+		}
+		sources, err := config.ValueSources(config.SourceOptions{
+			Defaults:  cfgDefaults,
+			Files:     cfgFiles,
+			EnvPrefix: "PERSONIO",
+			LookupEnv: os.LookupEnv,
+			Flags:     flags,
+			Profile:   cfg.Profile,
+			Profiles:  cfg.Profiles,
+		})
+		if err != nil {
+			return err
+		}
+		if rootFlags.verbose > 0 || rootFlags.quiet {
+			for i := range sources {
+				if sources[i].Key == "log.level" {
+					sources[i].Source, sources[i].Detail = config.SourceFlag, "--verbose/--quiet"
+				}
+			}
+		}
+
+		if cfg.Output != config.OutFormatPretty {
+			return printOutput(map[string]any{
+				"files":  cfgFiles,
+				"values": sources,
+			})
+		}
+
+		fmt.Println("Config files:")
+		files := console.Table{}
+		files.SetSpacing("  ")
+		files.SetPrefix("  ")
+		for _, file := range cfgFiles {
+			status := "not found"
+			if file.Loaded {
+				status = "loaded"
+			}
+			files.WriteCell(util.PrettyPath(file.Path))
+			files.WriteCell(status)
+			files.CommitRow()
+		}
+		files.Fprintln(os.Stdout)
+
+		fmt.Println()
+		fmt.Println("Values:")
+		values := console.Table{}
+		values.SetSpacing("  ")
+		values.SetPrefix("  ")
+		for _, source := range sources {
+			values.WriteCell(source.Key)
+			values.WriteCell(source.Source)
+			if source.Source == config.SourceFile {
+				values.WriteCell(util.PrettyPath(source.Detail))
+			} else {
+				values.WriteCell(source.Detail)
+			}
+			values.CommitRow()
+		}
+		values.Fprintln(os.Stdout)
+		return nil
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configPathCmd)
+}
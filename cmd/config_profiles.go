@@ -0,0 +1,91 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/applejag/rootless-personio/pkg/config"
+	"github.com/applejag/rootless-personio/pkg/console"
+	"github.com/spf13/cobra"
+)
+
+type profileSummary struct {
+	Name    string `json:"name"`
+	Active  bool   `json:"active"`
+	BaseURL string `json:"baseUrl"`
+	Email   string `json:"email"`
+}
+
+var configProfilesCmd = &cobra.Command{
+	Use:   "profiles",
+	Short: "Lists the profiles in the config",
+	Long: `Lists the profiles in the config, and which one is in use.
+
+Select a profile via the --profile flag, the PERSONIO_PROFILE env var,
+or the profile config.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var profiles []profileSummary
+		for _, name := range cfg.ProfileNames() {
+			withProfile, err := cfgWithoutProfile.ApplyProfile(name)
+			if err != nil {
+				return err
+			}
+			profiles = append(profiles, profileSummary{
+				Name:    name,
+				Active:  name == cfg.Profile,
+				BaseURL: withProfile.BaseURL,
+				Email:   withProfile.Auth.Email,
+			})
+		}
+
+		if cfg.Output != config.OutFormatPretty {
+			return printOutput(profiles)
+		}
+		if len(profiles) == 0 {
+			fmt.Println("No profiles configured.")
+			return nil
+		}
+		t := console.Table{}
+		t.SetSpacing("  ")
+		t.WriteCell("")
+		t.WriteCell("PROFILE")
+		t.WriteCell("BASE URL")
+		t.WriteCell("EMAIL")
+		t.CommitRow()
+		for _, p := range profiles {
+			active := ""
+			if p.Active {
+				active = "*"
+			}
+			t.WriteCell(active)
+			t.WriteCell(p.Name)
+			t.WriteCell(p.BaseURL)
+			t.WriteCell(p.Email)
+			t.CommitRow()
+		}
+		t.Fprintln(os.Stdout)
+		return nil
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configProfilesCmd)
+}
@@ -0,0 +1,98 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/applejag/rootless-personio/pkg/config"
+	"github.com/applejag/rootless-personio/pkg/util"
+	"github.com/spf13/cobra"
+)
+
+var configSetFlags = struct {
+	file string
+}{}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set <key> <value>",
+	Short: "Changes a config in the config file",
+	Long: `Changes a config in the config file, while keeping the comments
+and formatting of the rest of the file. The file is created if it
+does not exist.
+
+The key is the dot-separated path to the config, as in the config file,
+e.g "baseUrl", "log.level" or "profiles.work.auth.email".
+Lists, such as "allowedHosts", are set using comma-separated values.
+
+The value is checked against the config's JSON schema before it is written.
+
+Changes the file given by --file or --config, or else the config file
+in your user's config directory, e.g ~/.config/personio.yaml on Linux.`,
+	Example: `  rootless-personio config set baseUrl https://example.personio.de
+  rootless-personio config set log.level info
+  rootless-personio config set allowedHosts proxy.example.com,localhost:8080`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		key, value := args[0], args[1]
+		file := configSetFlags.file
+		if file == "" {
+			file = rootFlags.config
+		}
+		if file == "" {
+			var err error
+			file, err = config.UserConfigFile()
+			if err != nil {
+				return err
+			}
+		}
+
+		data, err := os.ReadFile(file)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		newData, err := config.SetValue(data, key, value)
+		if err != nil {
+			return fmt.Errorf("%s: %w", util.PrettyPath(file), err)
+		}
+		if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+			return err
+		}
+		// Only applies the permissions on newly created files,
+		// as the config may contain credentials
+		if err := os.WriteFile(file, newData, 0600); err != nil {
+			return err
+		}
+		fmt.Printf("Set %s in %s\n", key, util.PrettyPath(file))
+		return nil
+	},
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		// Intentionally ignores config errors from root.go,
+		// so this command can be used to fix them
+		return nil
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configSetCmd)
+
+	configSetCmd.Flags().StringVarP(&configSetFlags.file, "file", "f", "", "Config file to change (default is the config file in your user's config directory)")
+}
@@ -0,0 +1,112 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/applejag/rootless-personio/pkg/config"
+	"github.com/applejag/rootless-personio/pkg/util"
+	"github.com/spf13/cobra"
+)
+
+type configValidateResult struct {
+	File   string                   `json:"file"`
+	Valid  bool                     `json:"valid"`
+	Errors []config.ValidationError `json:"errors,omitempty"`
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate [file]",
+	Short: "Checks config files for invalid configs",
+	Long: `Checks the config files against the config's JSON schema
+(see "config schema"), and reports unknown keys, values of the wrong type,
+and invalid values such as an unknown output format, log level or
+timezone, together with their line numbers.
+
+Checks the given file, or else all config files that are read
+(see "config path").`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var files []string
+		if len(args) > 0 {
+			files = append(files, args[0])
+		} else {
+			for _, file := range config.DefaultCandidateFiles(rootFlags.config) {
+				if _, err := os.Stat(file.Path); err == nil || file.Required {
+					files = append(files, file.Path)
+				}
+			}
+			if len(files) == 0 {
+				return errors.New(`no config files found, see "rootless-personio config path"`)
+			}
+		}
+
+		var results []configValidateResult
+		problems := 0
+		for _, file := range files {
+			errs, err := config.ValidateFile(file)
+			if err != nil {
+				return err
+			}
+			results = append(results, configValidateResult{
+				File:   file,
+				Valid:  len(errs) == 0,
+				Errors: errs,
+			})
+			problems += len(errs)
+		}
+
+		output := cfg.Output
+		if output == config.OutFormatTemplate && outputTemplate == nil {
+			// Reported as invalid by the schema when set in a config file
+			output = config.OutFormatPretty
+		}
+		if output != config.OutFormatPretty {
+			if err := printOutput(results); err != nil {
+				return err
+			}
+		} else {
+			for _, result := range results {
+				if result.Valid {
+					fmt.Printf("%s: valid\n", util.PrettyPath(result.File))
+					continue
+				}
+				for _, e := range result.Errors {
+					e.File = util.PrettyPath(e.File)
+					fmt.Println(e)
+				}
+			}
+		}
+		if problems > 0 {
+			return fmt.Errorf("found %d invalid configs", problems)
+		}
+		return nil
+	},
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		// Intentionally ignores config errors from root.go,
+		// as this command is used to find them
+		return nil
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configValidateCmd)
+}
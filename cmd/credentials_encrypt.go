@@ -0,0 +1,131 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/applejag/rootless-personio/pkg/personio"
+	"github.com/applejag/rootless-personio/pkg/util"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+var credentialsEncryptFlags = struct {
+	file string
+}{}
+
+var credentialsEncryptCmd = &cobra.Command{
+	Use:   "encrypt",
+	Short: "Writes a passphrase-encrypted credentials file",
+	Long: `Asks for your credentials and writes them to a file encrypted
+with a passphrase of your choice.
+
+Use the file by setting the following in your config:
+
+    auth:
+      source: encryptedFile
+      encryptedFile: /path/to/file
+
+The passphrase is then read from the PERSONIO_CREDENTIALS_PASSPHRASE
+environment variable, or asked for when logging in.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		file := credentialsEncryptFlags.file
+		if file == "" {
+			file = cfg.Auth.EncryptedFile
+		}
+		if file == "" {
+			return errors.New("missing file, must set --file flag or auth.encryptedFile config")
+		}
+
+		var resp struct {
+			Email      string
+			Password   string
+			TOTPSecret string
+			Passphrase string
+			Confirm    string
+		}
+		questions := []*survey.Question{
+			{
+				Name:     "Email",
+				Prompt:   &survey.Input{Message: "Email:", Default: cfg.Auth.Email},
+				Validate: survey.Required,
+			},
+			{
+				Name:     "Password",
+				Prompt:   &survey.Password{Message: "Password:"},
+				Validate: survey.Required,
+			},
+			{
+				Name:   "TOTPSecret",
+				Prompt: &survey.Password{Message: "TOTP secret (optional):"},
+				Validate: func(ans any) error {
+					if s, _ := ans.(string); s != "" {
+						_, err := personio.GenerateTOTP(s, time.Now())
+						return err
+					}
+					return nil
+				},
+			},
+			{
+				Name:     "Passphrase",
+				Prompt:   &survey.Password{Message: "Passphrase for the file:"},
+				Validate: survey.Required,
+			},
+			{
+				Name:   "Confirm",
+				Prompt: &survey.Password{Message: "Repeat passphrase:"},
+			},
+		}
+		if err := survey.Ask(questions, &resp); err != nil {
+			return err
+		}
+		if resp.Passphrase != resp.Confirm {
+			return errors.New("passphrases do not match")
+		}
+
+		data, err := personio.EncryptCredentials(personio.Credentials{
+			Email:      resp.Email,
+			Password:   resp.Password,
+			TOTPSecret: resp.TOTPSecret,
+		}, resp.Passphrase)
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+			return err
+		}
+		if err := os.WriteFile(file, data, 0600); err != nil {
+			return err
+		}
+		log.Info().Str("file", util.PrettyPath(file)).
+			Msg("Written encrypted credentials to file.")
+		return nil
+	},
+}
+
+func init() {
+	credentialsCmd.AddCommand(credentialsEncryptCmd)
+
+	credentialsEncryptCmd.Flags().StringVarP(&credentialsEncryptFlags.file, "file", "f", "", `File to write to (default is the auth.encryptedFile config)`)
+}
@@ -0,0 +1,95 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/applejag/rootless-personio/pkg/journal"
+	"github.com/applejag/rootless-personio/pkg/util"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+func journalFilePath() (string, error) {
+	if cfg.Journal.File != "" {
+		return profileFilePath(cfg.Journal.File), nil
+	}
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	name := "journal.jsonl"
+	if cfg.Profile != "" {
+		// Keep the journals of different profiles apart
+		name = fmt.Sprintf("journal-%s.jsonl", safeFileName(cfg.Profile))
+	}
+	return filepath.Join(cacheDir, "rootless-personio", name), nil
+}
+
+// journalDisabled returns true if the changes should not be written to the
+// journal, which includes when replaying, as nothing is changed then.
+func journalDisabled() bool {
+	return cfg.Journal.Disabled || rootFlags.replay != ""
+}
+
+// journalEntry returns a new journal entry for the changes made by
+// the command, e.g "attendance set".
+func journalEntry(cmd *cobra.Command) journal.Entry {
+	return journal.Entry{
+		Command: strings.TrimPrefix(cmd.CommandPath(), rootCmd.Name()+" "),
+	}
+}
+
+func readJournal() ([]journal.Entry, error) {
+	path, err := journalFilePath()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := journal.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", util.PrettyPath(path), err)
+	}
+	return entries, nil
+}
+
+// writeJournal appends the entry to the journal. Failures are only logged,
+// as the changes have already been sent to Personio at this point.
+// Entries without any days are skipped, except for undos, which still
+// mark the undone entries.
+func writeJournal(entry journal.Entry) {
+	if journalDisabled() || (len(entry.Days) == 0 && !entry.IsUndo()) {
+		return
+	}
+	path, err := journalFilePath()
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to resolve journal file path.")
+		return
+	}
+	entry, err = journal.AppendFile(path, entry)
+	if err != nil {
+		log.Warn().Err(err).Str("file", util.PrettyPath(path)).
+			Msg("Failed to write journal, so the changes cannot be undone.")
+		return
+	}
+	log.Debug().Int("id", entry.ID).Str("file", util.PrettyPath(path)).
+		Msg("Wrote journal entry.")
+}
@@ -0,0 +1,56 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/applejag/rootless-personio/pkg/journal"
+)
+
+func TestWriteJournalWithoutDays(t *testing.T) {
+	oldJournal, oldProfile := cfg.Journal, cfg.Profile
+	t.Cleanup(func() {
+		cfg.Journal, cfg.Profile = oldJournal, oldProfile
+	})
+	cfg.Journal.File = filepath.Join(t.TempDir(), "journal.jsonl")
+	cfg.Journal.Disabled = false
+	cfg.Profile = ""
+
+	writeJournal(journal.Entry{Command: "attendance set", Days: []journal.Day{{Date: "2023-01-18"}}})
+	// Nothing was changed, so nothing to record
+	writeJournal(journal.Entry{Command: "attendance set"})
+	// Nothing was restored, but the entry is still undone
+	writeJournal(journal.Entry{Command: "attendance undo", UndoOf: []int{1}})
+
+	entries, err := readJournal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("want 2 entries, got %+v", entries)
+	}
+	if !entries[1].IsUndo() || len(entries[1].Days) != 0 {
+		t.Errorf("want undo entry without days, got %+v", entries[1])
+	}
+	if _, err := journal.Undoable(entries, 1); !errors.Is(err, journal.ErrNothingToUndo) {
+		t.Errorf("want %v, got %v", journal.ErrNothingToUndo, err)
+	}
+}
@@ -0,0 +1,68 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"errors"
+
+	"github.com/applejag/rootless-personio/pkg/config"
+	"github.com/applejag/rootless-personio/pkg/console"
+	"github.com/spf13/cobra"
+)
+
+var loginDoctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Diagnose each step of the login",
+	Long: `Log in step by step, and report the result of each step:
+reaching the base URL, the redirect to the login pages, the email,
+password and 2FA pages, and finding your employee ID and the XSRF cookies.
+
+The stored session is not used nor updated. The output contains no
+credentials, so it can be attached to bug reports, e.g:
+
+    rootless-personio login doctor -o yaml`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		if cfg.BaseURL == "" {
+			return errors.New("missing base URL, must set baseUrl config or PERSONIO_BASEURL env var")
+		}
+		client, err := newClient()
+		if err != nil {
+			return err
+		}
+
+		diagnosis := client.DiagnoseContext(ctx, cfg.Auth)
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if cfg.Output == config.OutFormatPretty {
+			console.PrintDiagnosis(diagnosis)
+		} else if err := printOutput(diagnosis); err != nil {
+			return err
+		}
+		if !diagnosis.OK {
+			return errors.New("login failed")
+		}
+		return nil
+	},
+}
+
+func init() {
+	loginCmd.AddCommand(loginDoctorCmd)
+}
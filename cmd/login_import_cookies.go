@@ -0,0 +1,99 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/applejag/rootless-personio/pkg/personio"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+var loginImportCookiesFlags = struct {
+	file string
+}{}
+
+var loginImportCookiesCmd = &cobra.Command{
+	Use:   "import-cookies",
+	Short: "Reuse the session from your web browser",
+	Long: `Reuse the session from your web browser, instead of logging in.
+
+Log into Personio in your web browser, export the cookies using e.g
+a browser extension, and import them:
+
+    rootless-personio login import-cookies --file cookies.txt
+
+Both the Netscape cookies.txt format and JSON cookie exports are supported.
+Only cookies for Personio are imported. The session is then stored,
+so later commands use it until it expires.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		if cfg.BaseURL == "" {
+			return errors.New("missing base URL, must set baseUrl config or PERSONIO_BASEURL env var")
+		}
+		if sessionDisabled() {
+			log.Warn().Msg("Storing the session is disabled, so the imported cookies will only be checked.")
+		}
+
+		var file io.ReadCloser = os.Stdin
+		if loginImportCookiesFlags.file != "-" {
+			var err error
+			file, err = os.Open(loginImportCookiesFlags.file)
+			if err != nil {
+				return err
+			}
+		}
+		defer file.Close()
+
+		cookies, err := personio.ParseCookies(file)
+		if err != nil {
+			return fmt.Errorf("read cookies: %w", err)
+		}
+
+		client, err := newClient()
+		if err != nil {
+			return err
+		}
+		n, err := client.ImportCookies(cookies)
+		if err != nil {
+			return err
+		}
+		log.Debug().Int("cookies", n).Msg("Imported cookies.")
+
+		if err := client.CheckSessionContext(ctx); err != nil {
+			return fmt.Errorf("imported cookies: %w", err)
+		}
+		log.Info().Int("employeeId", client.EmployeeID).
+			Msg("Successfully logged in using the imported cookies.")
+		saveSession(client)
+		return nil
+	},
+}
+
+func init() {
+	loginCmd.AddCommand(loginImportCookiesCmd)
+
+	loginImportCookiesCmd.Flags().StringVarP(&loginImportCookiesFlags.file, "file", "f", "", `Cookies file, in Netscape cookies.txt or JSON format, "-" means STDIN`)
+	loginImportCookiesCmd.MarkFlagFilename("file", "txt", "json")
+	loginImportCookiesCmd.MarkFlagRequired("file")
+}
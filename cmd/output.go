@@ -0,0 +1,204 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/template"
+
+	"github.com/applejag/rootless-personio/pkg/config"
+	"github.com/applejag/rootless-personio/pkg/console"
+	"github.com/itchyny/gojq"
+	"gopkg.in/yaml.v3"
+)
+
+// printOutput prints the model in the output format,
+// after applying the --query flag, if any.
+func printOutput(model any) error {
+	return fprintOutput(os.Stdout, model)
+}
+
+func fprintOutput(w io.Writer, model any) error {
+	if outputQuery == nil {
+		return fprintOutputModel(w, model)
+	}
+	results, err := runOutputQuery(model)
+	if err != nil {
+		return err
+	}
+	for i, result := range results {
+		if i > 0 && cfg.Output == config.OutFormatYAML {
+			fmt.Fprintln(w, "---")
+		}
+		if err := fprintOutputModel(w, result); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func fprintOutputModel(w io.Writer, model any) error {
+	switch cfg.Output {
+	case config.OutFormatYAML:
+		// Encode to JSON first, so we reuse the `json:"field_name"` tags
+		jsonBytes, err := json.Marshal(model)
+		if err != nil {
+			return err
+		}
+		var newModel any
+		if err := yaml.Unmarshal(jsonBytes, &newModel); err != nil {
+			return err
+		}
+
+		// Then encode again using YAML
+		var buf bytes.Buffer
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(2)
+		if err := enc.Encode(newModel); err != nil {
+			return err
+		}
+		fmt.Fprint(w, string(console.ColorizeYAML(buf.Bytes())))
+		return nil
+	case config.OutFormatTemplate:
+		if outputTemplate == nil {
+			return errNoOutputTemplate
+		}
+		var buf bytes.Buffer
+		if err := outputTemplate.Execute(&buf, model); err != nil {
+			return fmt.Errorf("template: %w", err)
+		}
+		if buf.Len() > 0 && !bytes.HasSuffix(buf.Bytes(), []byte("\n")) {
+			buf.WriteByte('\n')
+		}
+		_, err := buf.WriteTo(w)
+		return err
+	case config.OutFormatCSV:
+		return console.WriteCSV(w, model, ',')
+	case config.OutFormatTSV:
+		return console.WriteCSV(w, model, '\t')
+	default:
+		b, err := json.MarshalIndent(model, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w, string(console.ColorizeJSON(b)))
+		return nil
+	}
+}
+
+// outputTemplate is the parsed --template or --template-file flag,
+// or nil when not set.
+var outputTemplate *template.Template
+
+var errNoOutputTemplate = errors.New(`output format "template" requires the --template or --template-file flag`)
+
+func parseOutputTemplate(text, file string) error {
+	name := "--template"
+	if file != "" {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("read --template-file: %w", err)
+		}
+		text, name = string(b), file
+	}
+	tmpl, err := template.New(name).Funcs(template.FuncMap{
+		"json": func(v any) (string, error) {
+			b, err := json.Marshal(v)
+			return string(b), err
+		},
+		"join": joinTemplateValues,
+	}).Parse(text)
+	if err != nil {
+		return fmt.Errorf("parse template: %w", err)
+	}
+	outputTemplate = tmpl
+	return nil
+}
+
+// joinTemplateValues is the "join" template function. It also accepts
+// the lists from --query results, which are not typed as strings.
+func joinTemplateValues(values any, sep string) (string, error) {
+	switch values := values.(type) {
+	case []string:
+		return strings.Join(values, sep), nil
+	case []any:
+		strs := make([]string, 0, len(values))
+		for _, v := range values {
+			strs = append(strs, fmt.Sprint(v))
+		}
+		return strings.Join(strs, sep), nil
+	default:
+		return "", fmt.Errorf("join: want a list, got %T", values)
+	}
+}
+
+// outputQuery is the compiled --query flag, or nil when not set.
+var outputQuery *gojq.Code
+
+func compileOutputQuery(src string) error {
+	query, err := gojq.Parse(src)
+	if err != nil {
+		return fmt.Errorf("parse --query: %w", err)
+	}
+	code, err := gojq.Compile(query)
+	if err != nil {
+		return fmt.Errorf("compile --query: %w", err)
+	}
+	outputQuery = code
+	return nil
+}
+
+// runOutputQuery applies the --query flag on the model, and returns all
+// resulting values, as a jq query can produce any number of values.
+func runOutputQuery(model any) ([]any, error) {
+	// Encode to JSON first, so we reuse the `json:"field_name"` tags,
+	// and so gojq gets the plain maps and slices it expects
+	jsonBytes, err := json.Marshal(model)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(jsonBytes))
+	dec.UseNumber()
+	var input any
+	if err := dec.Decode(&input); err != nil {
+		return nil, err
+	}
+
+	var results []any
+	iter := outputQuery.Run(input)
+	for {
+		v, ok := iter.Next()
+		if !ok {
+			break
+		}
+		if err, ok := v.(error); ok {
+			if err, ok := err.(*gojq.HaltError); ok && err.Value() == nil {
+				break
+			}
+			return nil, fmt.Errorf("query: %w", err)
+		}
+		results = append(results, v)
+	}
+	return results, nil
+}
@@ -0,0 +1,299 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/applejag/rootless-personio/pkg/config"
+	"github.com/fatih/color"
+)
+
+// setOutput sets the output format and compiles the query, and restores
+// them when the test is done.
+func setOutput(t *testing.T, format config.OutFormat, query string) {
+	t.Helper()
+	oldOutput, oldQuery, oldTemplate, oldNoColor := cfg.Output, outputQuery, outputTemplate, color.NoColor
+	t.Cleanup(func() {
+		cfg.Output, outputQuery, outputTemplate, color.NoColor = oldOutput, oldQuery, oldTemplate, oldNoColor
+	})
+	cfg.Output = format
+	outputQuery = nil
+	outputTemplate = nil
+	color.NoColor = true
+	if query != "" {
+		if err := compileOutputQuery(query); err != nil {
+			t.Fatalf("compile query: %s", err)
+		}
+	}
+}
+
+type outputTestModel struct {
+	Date    string `json:"date"`
+	Minutes int    `json:"minutes"`
+}
+
+var outputTestModels = []outputTestModel{
+	{Date: "2023-01-18", Minutes: 480},
+	{Date: "2023-01-19", Minutes: 240},
+}
+
+func TestRunOutputQuery(t *testing.T) {
+	var tests = []struct {
+		name  string
+		query string
+		want  []any
+	}{
+		{
+			name:  "single result",
+			query: "length",
+			want:  []any{2},
+		},
+		{
+			name:  "multiple results",
+			query: ".[].date",
+			want:  []any{"2023-01-18", "2023-01-19"},
+		},
+		{
+			name:  "uses json tags",
+			query: "map(select(.minutes > 300)) | .[0].date",
+			want:  []any{"2023-01-18"},
+		},
+		{
+			name:  "halt",
+			query: `.[0].date, halt, .[1].date`,
+			want:  []any{"2023-01-18"},
+		},
+		{
+			name:  "empty",
+			query: "empty",
+			want:  nil,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			setOutput(t, config.OutFormatJSON, tc.query)
+			got, err := runOutputQuery(outputTestModels)
+			if err != nil {
+				t.Fatalf("run query: %s", err)
+			}
+			// Numbers may be decoded as json.Number, so compare their text
+			if fmt.Sprint(got) != fmt.Sprint(tc.want) {
+				t.Errorf("want %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestOutputQueryErrors(t *testing.T) {
+	setOutput(t, config.OutFormatJSON, "")
+	if err := compileOutputQuery(".[] |"); err == nil || !strings.Contains(err.Error(), "parse --query") {
+		t.Errorf("want parse error, got %v", err)
+	}
+	if err := compileOutputQuery("$undefined"); err == nil || !strings.Contains(err.Error(), "compile --query") {
+		t.Errorf("want compile error, got %v", err)
+	}
+
+	var tests = []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{name: "runtime error", query: ".[0].date.foo", want: "expected an object"},
+		{name: "error function", query: `error("boom")`, want: "boom"},
+		{name: "halt with error", query: `"stopped" | halt_error`, want: "stopped"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			setOutput(t, config.OutFormatJSON, tc.query)
+			_, err := runOutputQuery(outputTestModels)
+			if err == nil {
+				t.Fatal("want error, got nil")
+			}
+			if !strings.HasPrefix(err.Error(), "query: ") || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("want query error containing %q, got %q", tc.want, err)
+			}
+			var sb strings.Builder
+			if err := fprintOutput(&sb, outputTestModels); err == nil {
+				t.Error("print: want error, got nil")
+			} else if sb.Len() > 0 {
+				t.Errorf("print: want no output on error, got %q", sb.String())
+			}
+		})
+	}
+}
+
+func TestPrintOutputQuery(t *testing.T) {
+	var tests = []struct {
+		name   string
+		format config.OutFormat
+		query  string
+		want   string
+	}{
+		{
+			name:   "yaml separates results",
+			format: config.OutFormatYAML,
+			query:  ".[]",
+			want:   "date: \"2023-01-18\"\nminutes: 480\n---\ndate: \"2023-01-19\"\nminutes: 240\n",
+		},
+		{
+			name:   "yaml single result",
+			format: config.OutFormatYAML,
+			query:  ".[0]",
+			want:   "date: \"2023-01-18\"\nminutes: 480\n",
+		},
+		{
+			name:   "json results",
+			format: config.OutFormatJSON,
+			query:  ".[].minutes",
+			want:   "480\n240\n",
+		},
+		{
+			name:   "no results",
+			format: config.OutFormatYAML,
+			query:  "empty",
+			want:   "",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			setOutput(t, tc.format, tc.query)
+			var sb strings.Builder
+			if err := fprintOutput(&sb, outputTestModels); err != nil {
+				t.Fatalf("print: %s", err)
+			}
+			if got := sb.String(); got != tc.want {
+				t.Errorf("want:\n%s\ngot:\n%s", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestOutputTemplate(t *testing.T) {
+	var tests = []struct {
+		name     string
+		template string
+		query    string
+		want     string
+	}{
+		{
+			name:     "range",
+			template: `{{range .}}{{.Date}} {{.Minutes}}{{"\n"}}{{end}}`,
+			want:     "2023-01-18 480\n2023-01-19 240\n",
+		},
+		{
+			name:     "adds missing newline",
+			template: `{{len .}} days`,
+			want:     "2 days\n",
+		},
+		{
+			name:     "json function",
+			template: `{{json (index . 0)}}`,
+			want:     "{\"date\":\"2023-01-18\",\"minutes\":480}\n",
+		},
+		{
+			name:     "join function",
+			template: `{{join . ", "}}`,
+			query:    "[.[].date]",
+			want:     "2023-01-18, 2023-01-19\n",
+		},
+		{
+			name:     "applied to each query result",
+			template: `{{.date}}`,
+			query:    ".[]",
+			want:     "2023-01-18\n2023-01-19\n",
+		},
+		{
+			name:     "no output",
+			template: `{{if false}}never{{end}}`,
+			want:     "",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			setOutput(t, config.OutFormatTemplate, tc.query)
+			if err := parseOutputTemplate(tc.template, ""); err != nil {
+				t.Fatalf("parse template: %s", err)
+			}
+			var sb strings.Builder
+			if err := fprintOutput(&sb, outputTestModels); err != nil {
+				t.Fatalf("print: %s", err)
+			}
+			if got := sb.String(); got != tc.want {
+				t.Errorf("want %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestParseOutputTemplate(t *testing.T) {
+	setOutput(t, config.OutFormatTemplate, "")
+
+	file := filepath.Join(t.TempDir(), "days.tmpl")
+	if err := os.WriteFile(file, []byte(`{{range .}}{{.Date}};{{end}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := parseOutputTemplate("ignored", file); err != nil {
+		t.Fatalf("parse template file: %s", err)
+	}
+	var sb strings.Builder
+	if err := fprintOutput(&sb, outputTestModels); err != nil {
+		t.Fatalf("print: %s", err)
+	}
+	if want := "2023-01-18;2023-01-19;\n"; sb.String() != want {
+		t.Errorf("want %q, got %q", want, sb.String())
+	}
+
+	var tests = []struct {
+		name     string
+		template string
+		file     string
+		want     string
+	}{
+		{name: "parse error", template: `{{range .}}`, want: "parse template"},
+		{name: "unknown function", template: `{{nope .}}`, want: "parse template"},
+		{name: "missing file", file: filepath.Join(t.TempDir(), "missing.tmpl"), want: "read --template-file"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := parseOutputTemplate(tc.template, tc.file)
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("want error containing %q, got %v", tc.want, err)
+			}
+		})
+	}
+
+	// Without a template, such as from "output: template" in a config file
+	outputTemplate = nil
+	if err := fprintOutput(&sb, outputTestModels); !errors.Is(err, errNoOutputTemplate) {
+		t.Errorf("want %v, got %v", errNoOutputTemplate, err)
+	}
+
+	// Errors while executing the template
+	if err := parseOutputTemplate(`{{.Missing}}`, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := fprintOutput(&sb, outputTestModels[0]); err == nil || !strings.HasPrefix(err.Error(), "template: ") {
+		t.Errorf("want template error, got %v", err)
+	}
+}
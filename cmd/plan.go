@@ -0,0 +1,164 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/applejag/rootless-personio/pkg/config"
+	"github.com/applejag/rootless-personio/pkg/console"
+	"github.com/applejag/rootless-personio/pkg/journal"
+	"github.com/applejag/rootless-personio/pkg/personio"
+	"github.com/mattn/go-colorable"
+	"github.com/rs/zerolog/log"
+)
+
+// dayChange is a change of a day's attendance, which is either sent to
+// Personio, or only shown when using the --dry-run or --confirm flags.
+type dayChange struct {
+	date time.Time
+	// old is the day's periods before the change
+	old []personio.Period
+	// periods replaces all of the day's periods, unless delete is set
+	periods []personio.Period
+	// delete deletes the whole day via [personio.Client.DeleteAttendance]
+	delete bool
+}
+
+func (c dayChange) plan() personio.DayPlan {
+	date := c.date.Format(time.DateOnly)
+	if c.delete {
+		return personio.DiffDelete(date, c.old)
+	}
+	return personio.DiffPeriods(date, c.old, c.periods)
+}
+
+// applyDayChanges sends the changes to Personio, and returns true if it did.
+// The changed days are added to the entry, which is written to the journal,
+// also when only some of the changes succeeded.
+//
+// With the --dry-run flag, the planned changes are printed instead, using
+// the output format. With the --confirm flag, the planned changes are
+// printed to STDERR, and the user is asked before they are sent.
+func applyDayChanges(ctx context.Context, client *personio.Client, entry journal.Entry, changes []dayChange) (bool, error) {
+	// Keep the old periods intact, as SetAttendance modifies the periods
+	for i := range changes {
+		changes[i].old = slices.Clone(changes[i].old)
+	}
+
+	if rootFlags.dryRun || rootFlags.confirm {
+		plans := make([]personio.DayPlan, 0, len(changes))
+		for _, change := range changes {
+			plans = append(plans, change.plan())
+		}
+		if rootFlags.dryRun {
+			log.Info().Msg("Dry run, so no changes are sent to Personio.")
+			if cfg.Output != config.OutFormatPretty {
+				return false, printOutput(plans)
+			}
+			names, err := planProjectNames(ctx, client, plans)
+			if err != nil {
+				return false, err
+			}
+			console.PrintPlan(plans, names)
+			return false, nil
+		}
+
+		names, err := planProjectNames(ctx, client, plans)
+		if err != nil {
+			return false, err
+		}
+		console.WritePlan(colorable.NewColorableStderr(), plans, names)
+		if !slices.ContainsFunc(plans, personio.DayPlan.HasChanges) {
+			return false, nil
+		}
+		apply := false
+		if err := survey.AskOne(&survey.Confirm{
+			Message: "Apply these changes?",
+		}, &apply, survey.WithStdio(os.Stdin, os.Stderr, os.Stderr)); err != nil {
+			return false, err
+		}
+		if !apply {
+			return false, errors.New("aborted, no changes were sent to Personio")
+		}
+	}
+
+	entry.BaseURL = client.BaseURL
+	entry.EmployeeID = client.EmployeeID
+	defer func() {
+		writeJournal(entry)
+	}()
+	for _, change := range changes {
+		if change.delete {
+			if err := client.DeleteAttendanceContext(ctx, change.date); err != nil {
+				return false, err
+			}
+		} else if err := client.SetAttendanceContext(ctx, change.date, change.periods); err != nil {
+			return false, err
+		}
+		entry.Days = append(entry.Days, journalDay(ctx, client, change))
+	}
+	return true, nil
+}
+
+// journalDay returns the state of the day before and after the change.
+func journalDay(ctx context.Context, client *personio.Client, change dayChange) journal.Day {
+	day := journal.Day{
+		Date:    change.date.Format(time.DateOnly),
+		Deleted: change.delete,
+		Before:  journal.NewPeriods(change.old),
+		After:   []journal.Period{},
+	}
+	if !change.delete {
+		// SetAttendance has given the new periods their IDs
+		day.After = journal.NewPeriods(change.periods)
+	}
+	// The day ID is cached by the client at this point
+	if id, err := client.GetDayUUIDContext(ctx, change.date); err == nil {
+		day.DayID = id
+	}
+	return day
+}
+
+// planProjectNames looks up the names of all projects used in the plans.
+func planProjectNames(ctx context.Context, client *personio.Client, plans []personio.DayPlan) (map[int]string, error) {
+	names := map[int]string{}
+	for _, plan := range plans {
+		for _, change := range plan.Changes {
+			for _, p := range []*personio.Period{change.Old, change.New} {
+				if p == nil || p.ProjectID == nil {
+					continue
+				}
+				if _, ok := names[*p.ProjectID]; ok {
+					continue
+				}
+				name, err := client.GetProjectNameContext(ctx, *p.ProjectID)
+				if err != nil {
+					return nil, err
+				}
+				names[*p.ProjectID] = name
+			}
+		}
+	}
+	return names, nil
+}
@@ -45,6 +45,7 @@ var rawCmd = &cobra.Command{
 as a logged in user, and print the resulting JSON data.`,
 	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
 		urlArg := args[0]
 		baseURL, err := getBaseURL(urlArg)
 		if err != nil {
@@ -54,7 +55,7 @@ as a logged in user, and print the resulting JSON data.`,
 			cfg.BaseURL = baseURL
 		}
 
-		client, err := newLoggedInClient()
+		client, err := newLoggedInClient(ctx)
 		if err != nil {
 			return err
 		}
@@ -74,7 +75,7 @@ as a logged in user, and print the resulting JSON data.`,
 			method = rawFlags.method
 		}
 
-		req, err := http.NewRequest(method, urlArg, body)
+		req, err := http.NewRequestWithContext(ctx, method, urlArg, body)
 		if err != nil {
 			return err
 		}
@@ -109,9 +110,11 @@ as a logged in user, and print the resulting JSON data.`,
 				if err := json.Unmarshal(respBody, &model); err != nil {
 					return err
 				}
-				if err := printOutputJSONOrYAML(model); err != nil {
+				if err := printOutput(model); err != nil {
 					return err
 				}
+			} else if outputQuery != nil {
+				return fmt.Errorf("cannot apply --query, as the response is not JSON but %q", resp.Header.Get("Content-Type"))
 			} else {
 				fmt.Println(string(respBody))
 			}
@@ -23,14 +23,17 @@ package cmd
 
 import (
 	"bytes"
-	"encoding/json"
+	"context"
 	"errors"
 	"fmt"
+	"maps"
 	"os"
-	"path/filepath"
+	"os/signal"
 	"strings"
+	"syscall"
 
 	"github.com/AlecAivazis/survey/v2"
+	"github.com/applejag/rootless-personio/pkg/cassette"
 	"github.com/applejag/rootless-personio/pkg/config"
 	"github.com/applejag/rootless-personio/pkg/console"
 	"github.com/applejag/rootless-personio/pkg/personio"
@@ -40,18 +43,50 @@ import (
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"golang.org/x/term"
 	"gopkg.in/yaml.v3"
 )
 
 var cfg config.Config
-var cfgFileFlag string
+
+// cfgWithoutProfile is the config before the selected profile was applied.
+var cfgWithoutProfile config.Config
+
+// cfgDefaults is the config before any files, env vars or flags were read.
+var cfgDefaults config.Config
+
+// cfgFiles are the config file locations, and whether they were loaded.
+var cfgFiles []config.File
+
+// cfgErr is set if the config could not be loaded, and is returned
+// by all commands except those that override the PersistentPreRunE,
+// such as "config validate", which helps fixing the config.
+var cfgErr error
+
+// configKeyFlags maps config keys to the names of the global flags
+// that override them.
+var configKeyFlags = map[string]string{
+	"baseUrl":    "url",
+	"auth.email": "auth.email",
+	"profile":    "profile",
+	"output":     "output",
+	"log.level":  "log.level",
+	"log.format": "log.format",
+}
 
 var rootFlags = struct {
-	config   string
-	showHelp bool
-	verbose  int
-	quiet    bool
-	noLogin  bool
+	config       string
+	showHelp     bool
+	verbose      int
+	quiet        bool
+	noLogin      bool
+	record       string
+	replay       string
+	query        string
+	template     string
+	templateFile string
+	dryRun       bool
+	confirm      bool
 }{}
 
 var rootCmd = &cobra.Command{
@@ -61,23 +96,41 @@ var rootCmd = &cobra.Command{
 instead of obtaining admin/root API credentials.`,
 	SilenceErrors: true,
 	SilenceUsage:  true,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return cfgErr
+	},
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute(defaultConfig config.Config) {
 	cfg = defaultConfig
+	cfgDefaults = defaultConfig
+	// Viper decodes into the existing map, so it must not be shared
+	cfgDefaults.Profiles = maps.Clone(defaultConfig.Profiles)
 	initLogger() // set up logging first using default config
 
 	rootCmd.PersistentFlags().String("url", "", "Base URL used to access Personio")
 	rootCmd.PersistentFlags().String("auth.email", "", "Email used when logging in")
+	rootCmd.PersistentFlags().String("profile", "", "Profile from the config to use")
 	// Using pflag.Var here instead of pflag.String to get enum validation.
 	rootCmd.PersistentFlags().VarP(&cfg.Output, "output", "o", "Sets the output format")
 	rootCmd.PersistentFlags().Var(&cfg.Log.Level, "log.level", "Sets the logging level")
 	rootCmd.PersistentFlags().Var(&cfg.Log.Format, "log.format", "Sets the logging format")
-	viper.BindPFlags(rootCmd.PersistentFlags())
+	for key, flagName := range configKeyFlags {
+		viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(flagName))
+	}
 
-	err := rootCmd.Execute()
+	// Cancel any ongoing requests on Ctrl+C
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	err := rootCmd.ExecuteContext(ctx)
+	stop()
+	if errors.Is(err, context.Canceled) {
+		log.Warn().Msg("Interrupted.")
+		os.Exit(130)
+	}
 	if err != nil {
 		log.Error().Msgf("Failed: %s", err)
 		os.Exit(1)
@@ -88,11 +141,21 @@ func init() {
 	rootCmd.SetUsageTemplate(console.UsageTemplate())
 	cobra.OnInitialize(initConfig)
 
-	rootCmd.PersistentFlags().StringVar(&rootFlags.config, "config", rootFlags.config, "Config file (default is $HOME/.rootless-personio.yaml)")
+	rootCmd.PersistentFlags().StringVar(&rootFlags.config, "config", rootFlags.config, `Config file, read after the default locations (see "config path")`)
 	rootCmd.PersistentFlags().BoolP("help", "h", false, "Show this help text")
 	rootCmd.PersistentFlags().CountVarP(&rootFlags.verbose, "verbose", "v", `Shows verbose logging (-v=info, -vv=debug, -vvv=trace)`)
 	rootCmd.PersistentFlags().BoolVarP(&rootFlags.quiet, "quiet", "q", false, `Disables logging (same as "--log.level disabled")`)
 	rootCmd.PersistentFlags().BoolVar(&rootFlags.noLogin, "no-login", false, `Skip logging in before the request`)
+	rootCmd.PersistentFlags().StringVar(&rootFlags.record, "record", "", `Record redacted HTTP requests and responses to a directory, e.g for bug reports`)
+	rootCmd.PersistentFlags().StringVar(&rootFlags.replay, "replay", "", `Replay HTTP responses from a directory written by --record, instead of accessing Personio`)
+	rootCmd.PersistentFlags().StringVarP(&rootFlags.query, "query", "Q", "", `Filter the output with a jq expression, e.g ".[].date" (replaces "--output pretty" with json)`)
+	rootCmd.PersistentFlags().StringVar(&rootFlags.template, "template", "", `Format the output with a Go template, e.g '{{range .}}{{.Date}}{{"\n"}}{{end}}' (implies "--output template")`)
+	rootCmd.PersistentFlags().StringVar(&rootFlags.templateFile, "template-file", "", `Format the output with a Go template read from a file (implies "--output template")`)
+	rootCmd.PersistentFlags().BoolVar(&rootFlags.dryRun, "dry-run", false, `Show the changes to the attendance instead of sending them to Personio`)
+	rootCmd.PersistentFlags().BoolVar(&rootFlags.confirm, "confirm", false, `Show the changes to the attendance and ask before sending them to Personio`)
+	rootCmd.MarkFlagsMutuallyExclusive("record", "replay")
+	rootCmd.MarkFlagsMutuallyExclusive("dry-run", "confirm")
+	rootCmd.MarkFlagsMutuallyExclusive("template", "template-file")
 }
 
 func initConfig() {
@@ -108,34 +171,58 @@ func initConfig() {
 		os.Exit(1)
 	}
 
-	files := []string{"/etc/rootless-personio/personio.yaml"}
-
-	if homePath, err := os.UserHomeDir(); err == nil {
-		files = append(files, filepath.Join(homePath, ".personio.yaml"))
+	var err error
+	cfgFiles, err = mergeInConfigFiles(config.DefaultCandidateFiles(rootFlags.config))
+	if err != nil {
+		cfgErr = fmt.Errorf("decoding config file: %w\n"+
+			`Run "rootless-personio config validate" to find the invalid configs`, err)
 	}
-	if cfgPath, err := os.UserConfigDir(); err == nil {
-		files = append(files, filepath.Join(cfgPath, "personio.yaml"))
+
+	cfgWithoutProfile = cfg
+	if cfgErr == nil && cfg.Profile != "" {
+		if err := mergeInProfile(cfg.Profile); err != nil {
+			cfgErr = fmt.Errorf("select profile: %w", err)
+		}
 	}
 
-	files = append(files, ".personio.yaml")
+	if cfgErr == nil {
+		if _, err := cfg.Location(); err != nil {
+			cfgErr = err
+		}
+	}
 
-	if cfgFileFlag != "" {
-		files = append(files, cfgFileFlag)
+	if rootFlags.query != "" && cfgErr == nil {
+		if err := compileOutputQuery(rootFlags.query); err != nil {
+			cfgErr = err
+		}
+		// Queries don't apply to the pretty output
+		if cfg.Output == config.OutFormatPretty {
+			cfg.Output = config.OutFormatJSON
+		}
 	}
 
-	filesLoaded, err := mergeInConfigFiles(files)
-	if err != nil {
-		log.Error().Msgf("Failed decoding config file:\n%s", err)
-		os.Exit(1)
+	if (rootFlags.template != "" || rootFlags.templateFile != "") && cfgErr == nil {
+		if err := parseOutputTemplate(rootFlags.template, rootFlags.templateFile); err != nil {
+			cfgErr = err
+		}
+		cfg.Output = config.OutFormatTemplate
+	}
+	if cfg.Output == config.OutFormatTemplate && outputTemplate == nil && cfgErr == nil {
+		cfgErr = errNoOutputTemplate
 	}
 
 	// Set up logger last time, now that we've read in the new config
 	initLogger()
 
-	for _, file := range filesLoaded {
-		log.Debug().
-			Str("file", util.PrettyPath(file)).
-			Msg("Loaded configuration.")
+	for _, file := range cfgFiles {
+		if file.Loaded {
+			log.Debug().
+				Str("file", util.PrettyPath(file.Path)).
+				Msg("Loaded configuration.")
+		}
+	}
+	if cfg.Profile != "" {
+		log.Debug().Str("profile", cfg.Profile).Msg("Using profile.")
 	}
 }
 
@@ -148,29 +235,72 @@ func registerConfigsInViper(defaults config.Config) error {
 	return nil
 }
 
-func mergeInConfigFiles(files []string) ([]string, error) {
-	var filesLoaded []string
+func mergeInConfigFiles(files []config.File) ([]config.File, error) {
+	files, err := config.MergeFiles(viper.GetViper(), files)
+	if err != nil {
+		return nil, err
+	}
+	if err := unmarshalConfig(); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
 
-	for _, file := range files {
-		viper.SetConfigFile(file)
-		if err := viper.MergeInConfig(); err != nil {
-			if os.IsNotExist(err) {
-				continue
-			}
-			return nil, err
+// mergeInProfile merges the profile's fields on top of the config files,
+// so that env vars and flags still override the profile.
+func mergeInProfile(name string) error {
+	// Validates the profile name
+	withProfile, err := cfg.ApplyProfile(name)
+	if err != nil {
+		return err
+	}
+	profile, _ := viper.Get("profiles." + withProfile.Profile).(map[string]any)
+	if cfg.Profiles[withProfile.Profile].BaseURL != "" {
+		profile = withoutInheritedSecrets(profile)
+	}
+	if err := viper.MergeConfigMap(profile); err != nil {
+		return err
+	}
+	if err := unmarshalConfig(); err != nil {
+		return err
+	}
+	cfg.Profile = withProfile.Profile
+	return nil
+}
+
+// withoutInheritedSecrets returns a copy of the profile that clears the
+// top-level secrets that the profile does not set itself, as done by
+// [config.Config.ApplyProfile]. Env vars and flags still override them.
+func withoutInheritedSecrets(profile map[string]any) map[string]any {
+	profile = maps.Clone(profile)
+	if profile == nil {
+		profile = map[string]any{}
+	}
+	auth, _ := profile["auth"].(map[string]any)
+	auth = maps.Clone(auth)
+	if auth == nil {
+		auth = map[string]any{}
+	}
+	for _, key := range config.ProfileSecretAuthKeys {
+		// Viper lowercases all keys when reading the config files
+		key = strings.ToLower(key)
+		if _, ok := auth[key]; !ok {
+			auth[key] = ""
 		}
-		filesLoaded = append(filesLoaded, file)
 	}
+	profile["auth"] = auth
+	return profile
+}
 
+func unmarshalConfig() error {
 	if err := viper.Unmarshal(&cfg, viper.DecodeHook(mapstructure.ComposeDecodeHookFunc(
 		mapstructure.TextUnmarshallerHookFunc(),
 		mapstructure.StringToTimeDurationHookFunc(), // default hook
 		mapstructure.StringToSliceHookFunc(","),     // default hook
 	))); err != nil {
-		return nil, fmt.Errorf("unmarshalling config: %w", err)
+		return fmt.Errorf("unmarshalling config: %w", err)
 	}
-
-	return filesLoaded, nil
+	return nil
 }
 
 func initLogger() {
@@ -204,13 +334,13 @@ func overrideLoggerSettings() {
 	}
 }
 
-func newLoggedInClient() (*personio.Client, error) {
+func newLoggedInClient(ctx context.Context) (*personio.Client, error) {
 	if cfg.BaseURL == "" {
 		log.Error().Msg("Missing base URL! Must set baseUrl config or PERSONIO_BASEURL env var.")
 		return nil, errors.New("missing base URL")
 	}
 
-	client, err := personio.New(cfg.BaseURL)
+	client, err := newClient()
 	if err != nil {
 		return nil, err
 	}
@@ -220,35 +350,115 @@ func newLoggedInClient() (*personio.Client, error) {
 		return client, nil
 	}
 
-	var missingCredentials bool
-	if !cfg.Auth.Keepass {
-		if cfg.Auth.Email == "" {
-			missingCredentials = true
-			log.Error().Msg("Missing email! Must set auth.email config or PERSONIO_AUTH_EMAIL env var.")
-		}
-		if cfg.Auth.Password == "" {
-			missingCredentials = true
-			log.Error().Msg("Missing password! Must set auth.password config or PERSONIO_AUTH_PASSWORD env var.")
-		}
-	}
-	if missingCredentials {
-		return nil, errors.New("missing credentials")
+	if restoreSession(ctx, client) {
+		log.Info().Int("employeeId", client.EmployeeID).
+			Msg("Reusing stored session.")
+		return client, nil
 	}
-	if err := client.Login(cfg.Auth); err != nil {
-		if err := handleLoginError(client, err, cfg.Auth); err != nil {
+
+	if err := client.LoginContext(ctx, cfg.Auth); err != nil {
+		if err := handleLoginError(ctx, client, err, cfg.Auth); err != nil {
 			return nil, err
 		}
-		return nil, err
 	}
 	log.Info().Int("employeeId", client.EmployeeID).
 		Msg("Successfully logged in.")
+	saveSession(client)
+	return client, nil
+}
+
+// newClient creates a client from the config, without logging in.
+func newClient() (*personio.Client, error) {
+	loc, err := cfg.Location()
+	if err != nil {
+		return nil, err
+	}
+	opts := []personio.Option{personio.WithLocation(loc)}
+	if cfg.LoginURL != "" {
+		opts = append(opts, personio.WithLoginURL(cfg.LoginURL))
+	}
+	if len(cfg.AllowedHosts) > 0 {
+		opts = append(opts, personio.WithAllowedHosts(cfg.AllowedHosts...))
+	}
+	retryPolicy := personio.RetryPolicy{
+		MaxAttempts: cfg.Retry.MaxAttempts,
+		MinBackoff:  cfg.Retry.MinBackoff,
+		MaxBackoff:  cfg.Retry.MaxBackoff,
+	}
+
+	switch {
+	case rootFlags.record != "":
+		recorder, err := cassette.NewRecorder(rootFlags.record, nil)
+		if err != nil {
+			return nil, fmt.Errorf("record: %w", err)
+		}
+		opts = append(opts, personio.WithTransport(recorder))
+		log.Info().Str("dir", util.PrettyPath(rootFlags.record)).
+			Msg("Recording HTTP requests.")
+	case rootFlags.replay != "":
+		replayer, err := cassette.NewReplayer(rootFlags.replay)
+		if err != nil {
+			return nil, fmt.Errorf("replay: %w", err)
+		}
+		opts = append(opts, personio.WithTransport(replayer))
+		// Replayed responses will not change by retrying
+		retryPolicy.MaxAttempts = 1
+		log.Info().Str("dir", util.PrettyPath(rootFlags.replay)).
+			Msg("Replaying HTTP requests.")
+	}
+
+	opts = append(opts,
+		personio.WithRetryPolicy(retryPolicy),
+		personio.WithRateLimit(cfg.RateLimit.RequestsPerSecond, cfg.RateLimit.Burst),
+	)
+	client, err := personio.New(cfg.BaseURL, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if rootFlags.replay != "" {
+		// The recorded credentials and 2FA code are redacted,
+		// so any values will do.
+		client.CredentialProvider = personio.StaticCredentials{
+			Email:    "replay@example.com",
+			Password: cassette.Redacted,
+			TOTP:     "000000",
+		}
+	}
 	return client, nil
 }
 
-func handleLoginError(client *personio.Client, err error, auth config.Auth) error {
+func handleLoginError(ctx context.Context, client *personio.Client, err error, auth config.Auth) error {
 	if !errors.Is(err, personio.ErrUnlockRequired) {
 		return err
 	}
+	// Store the half-finished login, so it can be continued
+	// by the "unlock" command.
+	saveSession(client)
+
+	if auth.EmailToken != "" {
+		// The token only works once, so a token left in the config is most
+		// likely from an earlier login confirmation.
+		unlockErr := client.UnlockAndLoginContext(ctx, auth, auth.EmailToken)
+		if unlockErr == nil {
+			log.Info().Msg("Successfully unlocked and logged into account using auth.emailToken config.\n" +
+				"\tThe token can only be used once, so please remove it from the config.")
+			return nil
+		}
+		log.Warn().Err(unlockErr).Msg("Failed to unlock account using auth.emailToken config.\n" +
+			"\tThe token can only be used once, so please remove it from the config.")
+		if ctx.Err() != nil {
+			return unlockErr
+		}
+	}
+
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		log.Warn().Msg("Login confirmation required.\n" +
+			"\tPlease open your inbox and find the email named \"[Personio] Confirm login in your account\"\n" +
+			"\tCopy the login token from the email and run:\n" +
+			"\t\trootless-personio unlock --token <token>")
+		return err
+	}
+
 	log.Warn().Msg("Login confirmation required.\n" +
 		"\tPlease open your inbox and find the email named \"[Personio] Confirm login in your account\"\n" +
 		"\tCopy the login token from the email and enter it here:")
@@ -267,57 +477,10 @@ func handleLoginError(client *personio.Client, err error, auth config.Auth) erro
 		log.Warn().Err(err).Msg("Failed to ask for token. Please try again, and make sure to run rootless-personio from a tty (don't pipe the output).")
 		return err
 	}
-	if err := client.UnlockAndLogin(auth, resp.Token); err != nil {
+	if err := client.UnlockAndLoginContext(ctx, auth, resp.Token); err != nil {
 		return err
 	}
 	log.Info().Msg("Successfully unlocked and logged into account.")
 
 	return nil
 }
-
-func printOutputJSONOrYAML(model any) error {
-	switch cfg.Output {
-	case config.OutFormatYAML:
-		// Encode to JSON first, so we reuse the `json:"field_name"` tags
-		jsonBytes, err := json.Marshal(model)
-		if err != nil {
-			return err
-		}
-		var newModel any
-		if err := yaml.Unmarshal(jsonBytes, &newModel); err != nil {
-			return err
-		}
-
-		// Then encode again using YAML
-		var buf bytes.Buffer
-		enc := yaml.NewEncoder(&buf)
-		enc.SetIndent(2)
-		if err := enc.Encode(newModel); err != nil {
-			return err
-		}
-		b := buf.Bytes()
-		prettyBytes, err := util.ColorizeYAML(b)
-		if err != nil {
-			// Swallow error, as colorizing is not a citical feature
-			log.Debug().Err(err).Msg("Failed colorizing YAML.")
-			fmt.Println(string(b))
-			return nil
-		}
-		fmt.Println(string(prettyBytes))
-		return nil
-	default:
-		b, err := json.MarshalIndent(model, "", "  ")
-		if err != nil {
-			return err
-		}
-		prettyBytes, err := util.ColorizeJSON(b)
-		if err != nil {
-			// Swallow error, as colorizing is not a citical feature
-			log.Debug().Err(err).Msg("Failed colorizing JSON.")
-			fmt.Println(string(b))
-			return nil
-		}
-		fmt.Println(string(prettyBytes))
-		return nil
-	}
-}
@@ -0,0 +1,147 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/applejag/rootless-personio/pkg/personio"
+	"github.com/applejag/rootless-personio/pkg/util"
+	"github.com/rs/zerolog/log"
+)
+
+func sessionFilePath() (string, error) {
+	if cfg.Session.File != "" {
+		return profileFilePath(cfg.Session.File), nil
+	}
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	name := "session.json"
+	if cfg.Profile != "" {
+		// Keep the sessions of different profiles apart
+		name = fmt.Sprintf("session-%s.json", safeFileName(cfg.Profile))
+	}
+	return filepath.Join(cacheDir, "rootless-personio", name), nil
+}
+
+// profileFilePath adds the profile's name to a configured file path,
+// e.g "session.json" becomes "session-work.json", so the files of different
+// profiles are kept apart.
+func profileFilePath(path string) string {
+	if cfg.Profile == "" {
+		return path
+	}
+	ext := filepath.Ext(path)
+	return fmt.Sprintf("%s-%s%s", strings.TrimSuffix(path, ext), safeFileName(cfg.Profile), ext)
+}
+
+// safeFileName replaces all characters that may not be safe to use
+// in a file name.
+func safeFileName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == '_' || r == '.' || unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// sessionDisabled returns true if the session file should not be used,
+// which includes when recording or replaying, so the login is included.
+func sessionDisabled() bool {
+	return cfg.Session.Disabled || rootFlags.record != "" || rootFlags.replay != ""
+}
+
+// restoreSession tries to load the stored session into the client,
+// and returns true if the session is still valid.
+func restoreSession(ctx context.Context, client *personio.Client) bool {
+	if sessionDisabled() {
+		return false
+	}
+	session, err := loadSession()
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			log.Warn().Err(err).Msg("Failed to read session file.")
+		}
+		return false
+	}
+	if session.UnlockCSRFToken != "" {
+		log.Debug().Msg("Ignoring stored session, as it is waiting to be unlocked.")
+		return false
+	}
+	if err := client.RestoreSession(session); err != nil {
+		log.Debug().Err(err).Msg("Ignoring stored session.")
+		return false
+	}
+	if err := client.CheckSessionContext(ctx); err != nil {
+		log.Info().Err(err).Msg("Stored session has expired, logging in again.")
+		return false
+	}
+	// Store it again, as Personio may have refreshed some cookies
+	saveSession(client)
+	return true
+}
+
+func loadSession() (*personio.Session, error) {
+	path, err := sessionFilePath()
+	if err != nil {
+		return nil, err
+	}
+	session, err := personio.LoadSessionFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", util.PrettyPath(path), err)
+	}
+	return session, nil
+}
+
+func saveSession(client *personio.Client) {
+	if sessionDisabled() {
+		return
+	}
+	path, err := sessionFilePath()
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to resolve session file path.")
+		return
+	}
+	if err := client.Session().SaveFile(path); err != nil {
+		log.Warn().Err(err).Str("file", util.PrettyPath(path)).
+			Msg("Failed to write session file.")
+		return
+	}
+	log.Debug().Str("file", util.PrettyPath(path)).Msg("Saved session.")
+}
+
+func removeSession() error {
+	path, err := sessionFilePath()
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	log.Info().Str("file", util.PrettyPath(path)).Msg("Removed stored session.")
+	return nil
+}
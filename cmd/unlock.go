@@ -18,14 +18,78 @@
 package cmd
 
 import (
+	"errors"
+	"os"
+
+	"github.com/applejag/rootless-personio/pkg/personio"
+	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 )
 
+var unlockFlags = struct {
+	token string
+}{}
+
 var unlockCmd = &cobra.Command{
 	Use:   "unlock",
 	Short: "Unlock your account",
+	Long: `Unlock your account by finishing a login that Personio
+wants you to confirm via email, as it was made from a new device.
+
+Run any command that logs in (e.g "attendance calendar") to trigger the
+confirmation email, then copy the token from the email named
+"[Personio] Confirm login in your account" and run:
+
+    rootless-personio unlock --token <token>
+
+The half-finished login is read from the stored session.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		token := unlockFlags.token
+		if token == "" {
+			token = cfg.Auth.EmailToken
+		}
+		if token == "" {
+			return errors.New("missing token, must set --token flag or auth.emailToken config")
+		}
+		if cfg.BaseURL == "" {
+			return errors.New("missing base URL, must set baseUrl config or PERSONIO_BASEURL env var")
+		}
+
+		client, err := newClient()
+		if err != nil {
+			return err
+		}
+
+		session, err := loadSession()
+		if errors.Is(err, os.ErrNotExist) {
+			session = &personio.Session{BaseURL: client.BaseURL}
+		} else if err != nil {
+			return err
+		}
+		if cfg.Auth.CSRFToken != "" {
+			session.UnlockCSRFToken = cfg.Auth.CSRFToken
+		}
+		if session.UnlockCSRFToken == "" {
+			log.Warn().Msg("No pending login found in the stored session. Trying to unlock anyway.")
+		}
+		if err := client.RestoreSession(session); err != nil {
+			return err
+		}
+
+		if err := client.UnlockWithTokenContext(ctx, token); err != nil {
+			return err
+		}
+		log.Info().Int("employeeId", client.EmployeeID).
+			Msg("Successfully unlocked and logged into account.")
+		saveSession(client)
+		return nil
+	},
 }
 
 func init() {
 	rootCmd.AddCommand(unlockCmd)
+
+	unlockCmd.Flags().StringVar(&unlockFlags.token, "token", "", "Token from the login confirmation email (default is the auth.emailToken config)")
 }
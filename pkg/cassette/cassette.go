@@ -0,0 +1,377 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package cassette records HTTP requests and responses to files, and
+// replays them back later without any network access.
+//
+// A cassette is a directory with one JSON file per request, named by its
+// sequence number, e.g "0001.json". Passwords, 2FA codes, cookies and tokens
+// are redacted before writing the files, so they can be attached to bug
+// reports.
+package cassette
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// Redacted is the value that replaces secrets in the recorded files.
+const Redacted = "REDACTED"
+
+var (
+	ErrUnexpectedRequest = errors.New("unexpected request")
+	ErrCassetteEnded     = errors.New("no more recorded requests")
+)
+
+// Interaction is a single recorded request and its response.
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Request is a recorded HTTP request.
+type Request struct {
+	Method     string      `json:"method"`
+	URL        string      `json:"url"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+	BodyBase64 []byte      `json:"bodyBase64,omitempty"`
+}
+
+// Response is a recorded HTTP response.
+type Response struct {
+	StatusCode int         `json:"statusCode"`
+	Status     string      `json:"status"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+	BodyBase64 []byte      `json:"bodyBase64,omitempty"`
+}
+
+// Recorder is a [http.RoundTripper] that sends requests using another
+// RoundTripper, and writes each request and response to the cassette
+// directory.
+type Recorder struct {
+	dir  string
+	next http.RoundTripper
+
+	mu sync.Mutex
+	n  int
+}
+
+// NewRecorder returns a [Recorder] that writes to the directory, creating
+// it if needed. Sends the requests using next, or [http.DefaultTransport]
+// if nil.
+func NewRecorder(dir string, next http.RoundTripper) (*Recorder, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &Recorder{dir: dir, next: next}, nil
+}
+
+// RoundTrip implements [http.RoundTripper].
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	reqBody, err := readRequestBody(req)
+	if err != nil {
+		return nil, fmt.Errorf("record request body: %w", err)
+	}
+	resp, err := r.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("record response body: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	interaction := Interaction{
+		Request: Request{
+			Method: req.Method,
+			URL:    redactURL(req.URL),
+			Header: redactHeader(req.Header),
+		},
+		Response: Response{
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+			Header:     redactHeader(resp.Header),
+		},
+	}
+	interaction.Request.Body, interaction.Request.BodyBase64 = encodeBody(
+		redactBody(req.Header.Get("Content-Type"), reqBody))
+	interaction.Response.Body, interaction.Response.BodyBase64 = encodeBody(
+		redactBody(resp.Header.Get("Content-Type"), respBody))
+
+	if err := r.write(interaction); err != nil {
+		return nil, fmt.Errorf("record interaction: %w", err)
+	}
+	return resp, nil
+}
+
+func (r *Recorder) write(interaction Interaction) error {
+	b, err := json.MarshalIndent(interaction, "", "  ")
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.n++
+	return os.WriteFile(filepath.Join(r.dir, fmt.Sprintf("%04d.json", r.n)), b, 0600)
+}
+
+// Replayer is a [http.RoundTripper] that responds with the interactions
+// recorded by a [Recorder], in the same order as they were recorded.
+//
+// Returns [ErrUnexpectedRequest] if a request does not match the recorded
+// method and URL, and [ErrCassetteEnded] when all interactions have been
+// replayed.
+type Replayer struct {
+	interactions []Interaction
+
+	mu  sync.Mutex
+	pos int
+}
+
+// NewReplayer reads all interactions from the cassette directory.
+func NewReplayer(dir string) (*Replayer, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no recorded interactions found in %s", dir)
+	}
+	sort.Strings(files)
+	r := &Replayer{}
+	for _, file := range files {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		var interaction Interaction
+		if err := json.Unmarshal(b, &interaction); err != nil {
+			return nil, fmt.Errorf("parse %s: %w", file, err)
+		}
+		r.interactions = append(r.interactions, interaction)
+	}
+	return r, nil
+}
+
+// Remaining returns the number of interactions that have not been
+// replayed yet.
+func (r *Replayer) Remaining() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.interactions) - r.pos
+}
+
+// RoundTrip implements [http.RoundTripper].
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.pos >= len(r.interactions) {
+		return nil, fmt.Errorf("%w: got %s %s", ErrCassetteEnded, req.Method, redactURL(req.URL))
+	}
+	interaction := r.interactions[r.pos]
+	gotURL := redactURL(req.URL)
+	if req.Method != interaction.Request.Method || gotURL != interaction.Request.URL {
+		return nil, fmt.Errorf("%w #%d: want %s %s, got %s %s", ErrUnexpectedRequest, r.pos+1,
+			interaction.Request.Method, interaction.Request.URL, req.Method, gotURL)
+	}
+	r.pos++
+
+	body := []byte(interaction.Response.Body)
+	if interaction.Response.BodyBase64 != nil {
+		body = interaction.Response.BodyBase64
+	}
+	return &http.Response{
+		Status:        interaction.Response.Status,
+		StatusCode:    interaction.Response.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        interaction.Response.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		defer body.Close()
+		return io.ReadAll(body)
+	}
+	b, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(b))
+	return b, nil
+}
+
+func encodeBody(b []byte) (string, []byte) {
+	if utf8.Valid(b) {
+		return string(b), nil
+	}
+	return "", b
+}
+
+// sensitiveParams are form fields and query parameters whose values
+// are redacted.
+var sensitiveParams = map[string]bool{
+	"password": true,
+	"username": true,
+	"email":    true,
+	"code":     true,
+	"token":    true,
+	"_token":   true,
+	"state":    true,
+}
+
+// sensitiveHeaders are headers whose values are redacted.
+var sensitiveHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"X-Csrf-Token":        true,
+	"X-Xsrf-Token":        true,
+	"X-Athena-Xsrf-Token": true,
+}
+
+var (
+	hiddenInputRegex = regexp.MustCompile(`(<input[^>]*type="hidden"[^>]*value=")[^"]*(")`)
+	csrfMetaRegex    = regexp.MustCompile(`(<meta[^>]*name="csrf-token"[^>]*content=")[^"]*(")`)
+	jsonSecretRegex  = regexp.MustCompile(`("(?:password|email|token|_token|csrfToken|csrf_token|code|fullName|firstName|lastName|preferredName|first_name|last_name)"\s*:\s*")(?:\\"|[^"])*(")`)
+)
+
+func redactURL(u *url.URL) string {
+	clone := *u
+	clone.User = nil
+	if clone.RawQuery != "" {
+		clone.RawQuery = redactValues(clone.Query()).Encode()
+	}
+	return clone.String()
+}
+
+func redactValues(values url.Values) url.Values {
+	for key, vals := range values {
+		if sensitiveParams[strings.ToLower(key)] {
+			for i := range vals {
+				vals[i] = Redacted
+			}
+		}
+	}
+	return values
+}
+
+func redactHeader(header http.Header) http.Header {
+	clone := header.Clone()
+	for key, values := range clone {
+		// Personio needs some headers in non-canonical form, such as X-CSRF-Token
+		switch key := http.CanonicalHeaderKey(key); {
+		case sensitiveHeaders[key]:
+			for i := range values {
+				values[i] = Redacted
+			}
+		case key == "Cookie":
+			for i, value := range values {
+				values[i] = redactCookies(value)
+			}
+		case key == "Set-Cookie":
+			for i, value := range values {
+				values[i] = redactSetCookie(value)
+			}
+		case key == "Location":
+			for i, value := range values {
+				if u, err := url.Parse(value); err == nil {
+					values[i] = redactURL(u)
+				}
+			}
+		}
+	}
+	return clone
+}
+
+// redactCookies redacts the values in a Cookie request header,
+// e.g "a=1; b=2" becomes "a=REDACTED; b=REDACTED".
+func redactCookies(header string) string {
+	parts := strings.Split(header, ";")
+	for i, part := range parts {
+		name, _, _ := strings.Cut(strings.TrimSpace(part), "=")
+		parts[i] = name + "=" + Redacted
+	}
+	return strings.Join(parts, "; ")
+}
+
+// redactSetCookie redacts the value in a Set-Cookie response header,
+// but keeps its attributes.
+func redactSetCookie(header string) string {
+	cookie, attrs, _ := strings.Cut(header, ";")
+	name, _, _ := strings.Cut(cookie, "=")
+	if attrs != "" {
+		return name + "=" + Redacted + ";" + attrs
+	}
+	return name + "=" + Redacted
+}
+
+func redactBody(contentType string, body []byte) []byte {
+	if len(body) == 0 {
+		return body
+	}
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	switch strings.TrimSpace(mediaType) {
+	case "application/x-www-form-urlencoded":
+		values, err := url.ParseQuery(string(body))
+		if err != nil {
+			return []byte(Redacted)
+		}
+		return []byte(redactValues(values).Encode())
+	case "text/html":
+		body = hiddenInputRegex.ReplaceAll(body, []byte("${1}"+Redacted+"${2}"))
+		return csrfMetaRegex.ReplaceAll(body, []byte("${1}"+Redacted+"${2}"))
+	case "application/json":
+		return jsonSecretRegex.ReplaceAll(body, []byte("${1}"+Redacted+"${2}"))
+	default:
+		return body
+	}
+}
@@ -0,0 +1,180 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cassette_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/applejag/rootless-personio/pkg/cassette"
+	"github.com/applejag/rootless-personio/pkg/config"
+	"github.com/applejag/rootless-personio/pkg/personio"
+	"github.com/applejag/rootless-personio/pkg/personio/personiotest"
+)
+
+// recordLogin logs in to a fake server while recording the cassette,
+// and returns the server's secrets that must not end up in the files.
+func recordLogin(t *testing.T, dir string) []string {
+	t.Helper()
+	server := personiotest.NewServer()
+	defer server.Close()
+	server.TOTPSecret = "JBSWY3DPEHPK3PXP"
+
+	recorder, err := cassette.NewRecorder(dir, server.Transport())
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := personio.New(server.BaseURL, personio.WithTransport(recorder))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Login(server.Auth()); err != nil {
+		t.Fatalf("login while recording: %s", err)
+	}
+	if _, err := client.GetMyEmployeeData(); err != nil {
+		t.Fatalf("get employee while recording: %s", err)
+	}
+
+	session := client.Session()
+	secrets := []string{server.Password, server.Email, session.XSRFToken, session.AthenaXSRFToken,
+		server.Employee.FirstName, server.Employee.LastName}
+	for _, cookie := range session.Cookies {
+		secrets = append(secrets, cookie.Value)
+	}
+	return secrets
+}
+
+func TestRecordRedactsSecrets(t *testing.T) {
+	dir := t.TempDir()
+	secrets := recordLogin(t, dir)
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("want recorded files, got none")
+	}
+	for _, file := range files {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, secret := range secrets {
+			if secret != "" && strings.Contains(string(b), secret) {
+				t.Errorf("%s: contains secret %q", filepath.Base(file), secret)
+			}
+		}
+	}
+}
+
+func TestReplayLogin(t *testing.T) {
+	dir := t.TempDir()
+	recordLogin(t, dir)
+
+	replayer, err := cassette.NewReplayer(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := personio.New(personiotest.DefaultBaseURL,
+		personio.WithTransport(replayer),
+		// Replay errors will not go away by retrying
+		personio.WithRetryPolicy(personio.RetryPolicy{MaxAttempts: 1}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The recorded 2FA code is redacted, so any code will do
+	client.CredentialProvider = personio.StaticCredentials{
+		Email:    "someone@example.com",
+		Password: "not-the-password",
+		TOTP:     "000000",
+	}
+	if err := client.Login(config.Auth{}); err != nil {
+		t.Fatalf("replay login: %s", err)
+	}
+	if client.EmployeeID != personiotest.DefaultEmployeeID {
+		t.Errorf("want employee ID %d, got %d", personiotest.DefaultEmployeeID, client.EmployeeID)
+	}
+
+	// Requests that were not recorded are reported with the step
+	_, err = client.GetProjectID("Project X")
+	if !errors.Is(err, cassette.ErrUnexpectedRequest) {
+		t.Fatalf("want ErrUnexpectedRequest, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "/employee-header-bff/") || !strings.Contains(err.Error(), "/api/v1/projects") {
+		t.Errorf("want error to mention recorded and actual URL, got %q", err)
+	}
+}
+
+func TestReplay(t *testing.T) {
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "0001.json"), []byte(`{
+  "request": {"method": "GET", "url": "https://example.personio.de/login?state=REDACTED"},
+  "response": {
+    "statusCode": 200,
+    "status": "200 OK",
+    "header": {"Content-Type": ["text/plain"]},
+    "body": "hello"
+  }
+}`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	replayer, err := cassette.NewReplayer(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wrong, err := http.NewRequest(http.MethodPost, "https://example.personio.de/login", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := replayer.RoundTrip(wrong); !errors.Is(err, cassette.ErrUnexpectedRequest) {
+		t.Errorf("want ErrUnexpectedRequest, got %v", err)
+	}
+
+	// Secrets in the query are redacted before comparing
+	req, err := http.NewRequest(http.MethodGet, "https://example.personio.de/login?state=abc123", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := replayer.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("replay: %s", err)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != 200 || string(body) != "hello" {
+		t.Errorf("want 200 hello, got %d %s", resp.StatusCode, body)
+	}
+	if resp.Request != req {
+		t.Error("want response to reference the request")
+	}
+
+	if _, err := replayer.RoundTrip(req); !errors.Is(err, cassette.ErrCassetteEnded) {
+		t.Errorf("want ErrCassetteEnded, got %v", err)
+	}
+}
@@ -21,6 +21,7 @@
 package config
 
 import (
+	"fmt"
 	"reflect"
 	"time"
 
@@ -39,9 +40,42 @@ type Config struct {
 	//
 	// Any query parameters and fragments will get removed.
 	BaseURL string `yaml:"baseUrl" jsonschema:"oneof_type=string;null" jsonschema_extras:"format=uri"`
+	// LoginURL is the URL of the login pages that Personio redirects to
+	// when logging in. Defaults to https://login.personio.com.
+	LoginURL string `yaml:"loginUrl" jsonschema:"oneof_type=string;null" jsonschema_extras:"format=uri"`
+	// AllowedHosts is a list of extra hosts that the program may send
+	// requests to, such as a reverse proxy in front of Personio. The hosts
+	// of the base URL, the login URL, and *.personio.com are always allowed.
+	//
+	// A host may contain a port, e.g "localhost:8080", and may start
+	// with "*." to allow all its subdomains, e.g "*.example.com".
+	AllowedHosts []string `yaml:"allowedHosts"`
 
 	Auth Auth
 
+	// Profile is the name of the profile to use, whose configs override
+	// the top-level configs. Can also be set via the --profile flag or the
+	// PERSONIO_PROFILE env var.
+	Profile string `yaml:"profile" jsonschema:"oneof_type=string;null"`
+	// Profiles are named sets of configs, such as for different Personio
+	// instances, selected via the profile config. The stored session
+	// is kept separate for each profile.
+	Profiles map[string]Profile `yaml:"profiles"`
+
+	// Session contains settings for how the logged in session is persisted
+	// between invocations of the program.
+	Session Session
+
+	// Journal contains settings for the local log of the changes made to
+	// the attendance, which is used to undo them.
+	Journal Journal
+
+	// Retry contains settings for how failed HTTP requests are retried.
+	Retry Retry
+	// RateLimit contains settings for limiting how many HTTP requests
+	// the program sends to Personio.
+	RateLimit RateLimit `yaml:"rateLimit"`
+
 	// MinimumPeriodDuration is the duration for which attendance periods that
 	// are shorter than will get skipped when creating or updating attendance.
 	//
@@ -56,9 +90,16 @@ type Config struct {
 	// the program needs to create attendance periods, and
 	// the user has not specified a start time.
 	// The value must be in HH:MM format, and the program will
-	// assume that the time is in the local timezone.
+	// assume that the time is in the configured timezone.
 	StandardStartTime string `yaml:"standardStartTime" jsonschema:"type=string"`
 
+	// Timezone is the IANA name of the timezone that your attendance
+	// is tracked in, e.g "Europe/Berlin". Personio stores the attendance
+	// periods as wall-clock times without an offset, so this is used to
+	// convert between the times shown by Personio and the times used by
+	// the program. Defaults to the system's local timezone.
+	Timezone string `yaml:"timezone" jsonschema:"oneof_type=string;null" jsonschema_extras:"format=timezone"`
+
 	// Output is the format of the command line results.
 	// This controls the format of the single command line
 	// result output written to STDOUT.
@@ -66,29 +107,133 @@ type Config struct {
 	Log    Log
 }
 
+// Location returns the timezone from the timezone config,
+// or [time.Local] when it is not set.
+func (c Config) Location() (*time.Location, error) {
+	if c.Timezone == "" {
+		return time.Local, nil
+	}
+	loc, err := time.LoadLocation(c.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("timezone: %w", err)
+	}
+	return loc, nil
+}
+
 // Auth contains configs for how the program should authenticate
 // with Personio.
 type Auth struct {
+	// Source is where the program reads the login credentials from:
+	//
+	// - config: the email and password fields in this config
+	// - keepass: a running KeePassXC instance, including the 2FA code
+	// - command: the email field, and the output of the passwordCommand
+	// - envFile: a file with PERSONIO_AUTH_EMAIL=... and PERSONIO_AUTH_PASSWORD=... lines
+	// - encryptedFile: a passphrase-encrypted file, as written by the
+	//   "rootless-personio credentials encrypt" command
+	Source CredentialSource `yaml:"source"`
+	// Deprecated: Use source: keepass instead.
+	//
 	// When set to true, the program will connect to a running KeepassXC instance
 	// and fetch the credentials from there.
-	Keepass bool `yaml:"keepass,omitempty" jsonschema:"oneof_type=bool;null"`
+	Keepass bool `yaml:"keepass,omitempty" jsonschema:"oneof_type=boolean;null"`
 	// Email is your account's login email address.
 	Email string `jsonschema:"oneof_type=string;null" jsonschema_extras:"format=email"`
 	// Password is your account's login password.
 	Password string `jsonschema:"oneof_type=string;null"`
+	// PasswordCommand is a shell command that prints the password,
+	// e.g "pass show personio". Used with source: command.
+	PasswordCommand string `yaml:"passwordCommand" jsonschema:"oneof_type=string;null"`
+	// EnvFile is the path to a file with the credentials as environment
+	// variables, or "fd:N" to read them from file descriptor N.
+	// Used with source: envFile.
+	EnvFile string `yaml:"envFile" jsonschema:"oneof_type=string;null"`
+	// EncryptedFile is the path to a passphrase-encrypted credentials file.
+	// The passphrase is read from the PERSONIO_CREDENTIALS_PASSPHRASE env var,
+	// or asked for in the terminal. Used with source: encryptedFile.
+	EncryptedFile string `yaml:"encryptedFile" jsonschema:"oneof_type=string;null"`
+	// TOTPSecret is the base32 encoded secret used to generate 2FA codes,
+	// as shown when setting up an authenticator app, or the "otpauth://" URI
+	// from the QR code. When set, the program generates the 2FA code itself
+	// instead of asking for it.
+	TOTPSecret string `yaml:"totpSecret" jsonschema:"oneof_type=string;null"`
+	// TOTPSecretCommand is a shell command that prints the TOTP secret,
+	// e.g "pass show personio-totp". Only used when TOTPSecret is not set.
+	TOTPSecretCommand string `yaml:"totpSecretCommand" jsonschema:"oneof_type=string;null"`
 
 	// CSRFToken is provided by this program when it fails to
-	// log in due to them detecting login via new device. You then need to
-	// run the program again but with the CSRF (Cross-Site-Request-Forgery)
-	// token and email token.
+	// log in due to them detecting login via new device. It is normally
+	// read from the stored session by the "unlock" command, but can be
+	// set here to override it.
 	CSRFToken string `yaml:"csrfToken,omitempty" jsonschema:"oneof_type=string;null"`
 	// EmailToken is sent by Personio to your email when it fails to
-	// log in due to them detecting login via new device. You then need to
-	// run the program again but with the CSRF (Cross-Site-Request-Forgery)
-	// token and email token.
+	// log in due to them detecting login via new device. When set, the
+	// program tries it to finish such logins automatically. It is the
+	// same as passing the token via "rootless-personio unlock --token".
+	// Each token can only be used once, so remove it after use.
 	EmailToken string `yaml:"emailToken,omitempty" jsonschema:"oneof_type=string;null"`
 }
 
+// Session contains configs for how the program should store the logged in
+// session, so that it does not need to log in again on every invocation.
+type Session struct {
+	// Disabled turns off reading and writing the session file, making the
+	// program log in on every invocation.
+	Disabled bool `yaml:"disabled" jsonschema:"oneof_type=boolean;null"`
+	// File is the path to where the session cookies are stored.
+	// Defaults to "rootless-personio/session.json" inside the user's
+	// cache directory, e.g ~/.cache/rootless-personio/session.json on Linux.
+	// When a profile is selected, its name is added to the file name,
+	// e.g session-work.json.
+	File string `yaml:"file" jsonschema:"oneof_type=string;null"`
+}
+
+// Journal contains configs for the local log of attendance changes,
+// as shown by "rootless-personio attendance history".
+type Journal struct {
+	// Disabled turns off writing the journal, which also means that
+	// the changes cannot be undone via "rootless-personio attendance undo".
+	Disabled bool `yaml:"disabled" jsonschema:"oneof_type=boolean;null"`
+	// File is the path to the journal file. Defaults to
+	// "rootless-personio/journal.jsonl" inside the user's cache directory,
+	// e.g ~/.cache/rootless-personio/journal.jsonl on Linux.
+	// When a profile is selected, its name is added to the file name,
+	// e.g journal-work.jsonl.
+	File string `yaml:"file" jsonschema:"oneof_type=string;null"`
+}
+
+// Retry contains configs for how failed HTTP requests are retried.
+//
+// Requests are retried on connection errors and on the HTTP status codes
+// 429, 502, 503 and 504, but only when it is safe to send them again.
+type Retry struct {
+	// MaxAttempts is the maximum number of times a request is sent,
+	// including the first attempt. Set to 1 to disable retries.
+	MaxAttempts int `yaml:"maxAttempts"`
+	// MinBackoff is the wait before the first retry, which is then doubled
+	// for every following retry, with some random jitter added.
+	//
+	// The value is a Go duration, which allows values like:
+	// - 500ms
+	// - 2s
+	MinBackoff time.Duration `yaml:"minBackoff" jsonschema:"type=string"`
+	// MaxBackoff is the longest wait between two attempts. When Personio
+	// asks the program to wait longer than this via the Retry-After
+	// header, the request is not retried.
+	MaxBackoff time.Duration `yaml:"maxBackoff" jsonschema:"type=string"`
+}
+
+// RateLimit contains configs for limiting how many HTTP requests the
+// program sends, using a token bucket.
+type RateLimit struct {
+	// RequestsPerSecond is the rate at which requests may be sent.
+	// Set to 0 to disable the rate limit.
+	RequestsPerSecond float64 `yaml:"requestsPerSecond"`
+	// Burst is the number of requests that may be sent at once,
+	// before the rate limit kicks in.
+	Burst int `yaml:"burst"`
+}
+
 // Log contains configs for the command line logging, which compared
 // to the command line output, loggin is written to STDERR and contains
 // small status reports, and is mostly used for debugging.
@@ -0,0 +1,118 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package config
+
+import (
+	"encoding"
+	"fmt"
+
+	"github.com/invopop/jsonschema"
+	"github.com/spf13/pflag"
+)
+
+// CredentialSource is an enum of different places to read the login
+// credentials from.
+type CredentialSource string
+
+// CredentialSourceDefault is the default credential source.
+// Used in the [CredentialSource.JSONSchema] method.
+var CredentialSourceDefault = CredentialSourceConfig
+
+// Available [CredentialSource] values.
+const (
+	CredentialSourceConfig        CredentialSource = "config"
+	CredentialSourceKeepass       CredentialSource = "keepass"
+	CredentialSourceCommand       CredentialSource = "command"
+	CredentialSourceEnvFile       CredentialSource = "envFile"
+	CredentialSourceEncryptedFile CredentialSource = "encryptedFile"
+)
+
+// CredentialSources is a list of all the [CredentialSource] values.
+var CredentialSources = []CredentialSource{
+	CredentialSourceConfig,
+	CredentialSourceKeepass,
+	CredentialSourceCommand,
+	CredentialSourceEnvFile,
+	CredentialSourceEncryptedFile,
+}
+
+func _() {
+	// Ensure the type implements the interfaces
+	s := CredentialSourceConfig
+	var _ pflag.Value = &s
+	var _ encoding.TextUnmarshaler = &s
+	var _ jsonSchemaInterface = s
+}
+
+// String implements [fmt.Stringer] and [pflag.Value].
+//
+// Used by cobra when showing the default value of a flag.
+func (s CredentialSource) String() string {
+	return string(s)
+}
+
+// Set implements [pflag.Value].
+//
+// Used by cobra when setting the new value for a flag.
+func (s *CredentialSource) Set(value string) error {
+	switch CredentialSource(value) {
+	case CredentialSourceConfig:
+		*s = CredentialSourceConfig
+	case CredentialSourceKeepass:
+		*s = CredentialSourceKeepass
+	case CredentialSourceCommand:
+		*s = CredentialSourceCommand
+	case CredentialSourceEnvFile:
+		*s = CredentialSourceEnvFile
+	case CredentialSourceEncryptedFile:
+		*s = CredentialSourceEncryptedFile
+	default:
+		return fmt.Errorf("unknown credential source: %q, must be one of: config, keepass, command, envFile, encryptedFile", value)
+	}
+	return nil
+}
+
+// Type implements [pflag.Value].
+//
+// Used by cobra when rendering the list of flags and their types.
+func (s *CredentialSource) Type() string {
+	return "credential-source"
+}
+
+// UnmarshalText implements [encoding.TextUnmarshaler].
+//
+// Used when parsing YAML config files.
+func (s *CredentialSource) UnmarshalText(text []byte) error {
+	return s.Set(string(text))
+}
+
+// JSONSchema returns the custom JSON schema definition for this type.
+func (CredentialSource) JSONSchema() *jsonschema.Schema {
+	return &jsonschema.Schema{
+		Type:  "string",
+		Title: "Credential source",
+		Enum: []any{
+			CredentialSourceConfig,
+			CredentialSourceKeepass,
+			CredentialSourceCommand,
+			CredentialSourceEnvFile,
+			CredentialSourceEncryptedFile,
+		},
+		Default: CredentialSourceDefault,
+	}
+}
@@ -0,0 +1,406 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/invopop/jsonschema"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	ErrUnknownKey = errors.New("unknown config key")
+	ErrInvalidKey = errors.New("invalid config key")
+)
+
+// LookupValue returns the YAML node at the dot-separated key, e.g
+// "log.level", from a YAML config, or false if it is not set.
+func LookupValue(data []byte, key string) (*yaml.Node, bool, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, false, err
+	}
+	if len(doc.Content) == 0 {
+		return nil, false, nil
+	}
+	node := doc.Content[0]
+	for _, name := range strings.Split(key, ".") {
+		if node.Kind != yaml.MappingNode {
+			return nil, false, nil
+		}
+		_, value := findMappingKey(node, name)
+		if value == nil {
+			return nil, false, nil
+		}
+		node = value
+	}
+	return node, true, nil
+}
+
+// SetValue sets the dot-separated key, e.g "log.level", to a value in a
+// YAML config, and returns the updated YAML. Any comments in the config
+// are kept, and missing parent fields are added. The file is only
+// reformatted when the value cannot be set by editing its lines directly.
+//
+// The value is converted to the type of the field in the JSON schema from
+// [Schema], where arrays are written as comma-separated values, and is
+// validated before it is set.
+func SetValue(data []byte, key, value string) ([]byte, error) {
+	schema, ok := SchemaForKey(key)
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKey, key)
+	}
+	valueNode, err := newValueNode(schema, value)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", key, err)
+	}
+	v := schemaValidator{root: Schema("")}
+	v.validate(valueNode, schema, key)
+	if len(v.errs) > 0 {
+		return nil, fmt.Errorf("%s: %s", key, v.errs[0].Message)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	names := strings.Split(key, ".")
+	if edited, ok := setValueInPlace(data, &doc, names, valueNode); ok {
+		return edited, nil
+	}
+	return setValueReencode(&doc, names, valueNode)
+}
+
+// setValueInPlace edits the lines of the YAML config directly, so the
+// formatting of the rest of the file is kept exactly as it is, including
+// blank lines. Returns false when the change is too complex for that,
+// such as when setting a multiline string.
+func setValueInPlace(data []byte, doc *yaml.Node, names []string, valueNode *yaml.Node) ([]byte, bool) {
+	text, ok := inlineYAML(valueNode)
+	if !ok {
+		return nil, false
+	}
+	lines := strings.Split(string(data), "\n")
+	if len(doc.Content) == 0 {
+		// Empty file, or only comments
+		if len(lines) > 0 && lines[len(lines)-1] == "" {
+			lines = lines[:len(lines)-1]
+		}
+		lines = append(lines, nestedFieldLines(0, names, text)...)
+		return []byte(strings.Join(lines, "\n") + "\n"), true
+	}
+	node := doc.Content[0]
+	if node.Kind != yaml.MappingNode || node.Style&yaml.FlowStyle != 0 || len(node.Content) == 0 {
+		return nil, false
+	}
+	for i, name := range names {
+		keyNode, value := findMappingKey(node, name)
+		if keyNode == nil {
+			// Add the missing fields on new lines after the mapping's last field
+			lastKey, lastValue := node.Content[len(node.Content)-2], node.Content[len(node.Content)-1]
+			after, ok := lastLine(lastValue)
+			if !ok || lastKey.Column < 1 {
+				return nil, false
+			}
+			lines = slices.Insert(lines, after, nestedFieldLines(lastKey.Column-1, names[i:], text)...)
+			return []byte(strings.Join(lines, "\n")), true
+		}
+		if i < len(names)-1 && value.Kind == yaml.MappingNode &&
+			value.Style&yaml.FlowStyle == 0 && len(value.Content) > 0 {
+			node = value
+			continue
+		}
+		if value.Line != keyNode.Line || value.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0 {
+			return nil, false
+		}
+		line := lines[keyNode.Line-1]
+		start, end, ok := valueSpan(line, keyNode, value)
+		if !ok {
+			return nil, false
+		}
+		if i < len(names)-1 {
+			// Replace an empty object, e.g "profiles: {}", with the new fields
+			isEmpty := (value.Kind == yaml.MappingNode && len(value.Content) == 0) ||
+				(value.Kind == yaml.ScalarNode && value.Tag == "!!null")
+			if !isEmpty {
+				return nil, false
+			}
+			lines[keyNode.Line-1] = strings.TrimRight(line[:start], " ") + line[end:]
+			lines = slices.Insert(lines, keyNode.Line, nestedFieldLines(keyNode.Column+1, names[i+1:], text)...)
+			return []byte(strings.Join(lines, "\n")), true
+		}
+		if start == end {
+			// Empty value, so there is no space after the colon to reuse
+			text = " " + text
+		}
+		lines[keyNode.Line-1] = line[:start] + text + line[end:]
+		return []byte(strings.Join(lines, "\n")), true
+	}
+	return nil, false
+}
+
+// nestedFieldLines returns the YAML lines of the nested fields,
+// indented by 2 spaces per level, where the last field has the value.
+func nestedFieldLines(indent int, names []string, text string) []string {
+	var lines []string
+	for i, name := range names {
+		line := strings.Repeat(" ", indent+2*i) + name + ":"
+		if i == len(names)-1 {
+			line += " " + text
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// inlineYAML returns the node encoded as YAML that fits on a single line.
+func inlineYAML(node *yaml.Node) (string, bool) {
+	clone := *node
+	if clone.Kind == yaml.SequenceNode {
+		clone.Style = yaml.FlowStyle
+	}
+	b, err := yaml.Marshal(&clone)
+	if err != nil {
+		return "", false
+	}
+	text := strings.TrimSuffix(string(b), "\n")
+	return text, !strings.Contains(text, "\n")
+}
+
+// lastLine returns the 1-based line number of the last line of the node.
+func lastLine(node *yaml.Node) (int, bool) {
+	switch node.Kind {
+	case yaml.MappingNode, yaml.SequenceNode:
+		if node.Style&yaml.FlowStyle != 0 || len(node.Content) == 0 {
+			return node.Line, true
+		}
+		return lastLine(node.Content[len(node.Content)-1])
+	case yaml.ScalarNode:
+		if node.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0 || strings.Contains(node.Value, "\n") {
+			return 0, false
+		}
+		return node.Line, true
+	}
+	return 0, false
+}
+
+// valueSpan returns the byte offsets of the value in the line, or the
+// offset right after the colon if the value is empty. Only plain keys
+// are supported.
+func valueSpan(line string, keyNode, value *yaml.Node) (int, int, bool) {
+	keyEnd := keyNode.Column - 1 + len(keyNode.Value)
+	if keyNode.Style != 0 || keyEnd > len(line) || line[keyNode.Column-1:keyEnd] != keyNode.Value {
+		return 0, 0, false
+	}
+	colon := keyEnd + len(line[keyEnd:]) - len(strings.TrimLeft(line[keyEnd:], " "))
+	if colon >= len(line) || line[colon] != ':' {
+		return 0, 0, false
+	}
+	if value.Kind == yaml.ScalarNode && value.Tag == "!!null" && value.Value == "" {
+		return colon + 1, colon + 1, true
+	}
+	start := value.Column - 1
+	if start <= colon || start > len(line) {
+		return 0, 0, false
+	}
+	rest := line[start:]
+	var end int
+	switch {
+	case value.Kind == yaml.SequenceNode || value.Kind == yaml.MappingNode:
+		closing := "]"
+		if value.Kind == yaml.MappingNode {
+			closing = "}"
+		}
+		end = strings.Index(rest, closing) + 1
+	case value.Style&yaml.DoubleQuotedStyle != 0:
+		end = closingQuote(rest, '"')
+	case value.Style&yaml.SingleQuotedStyle != 0:
+		end = closingQuote(rest, '\'')
+	case value.Kind == yaml.ScalarNode && strings.HasPrefix(rest, value.Value):
+		end = len(value.Value)
+	}
+	if end <= 0 {
+		return 0, 0, false
+	}
+	return start, start + end, true
+}
+
+// closingQuote returns the offset right after the closing quote of the
+// quoted string at the start of s, or 0 if it was not found.
+func closingQuote(s string, quote byte) int {
+	for i := 1; i < len(s); i++ {
+		switch {
+		case quote == '"' && s[i] == '\\':
+			i++
+		case s[i] == quote && quote == '\'' && i+1 < len(s) && s[i+1] == '\'':
+			i++
+		case s[i] == quote:
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// setValueReencode edits the parsed YAML nodes and encodes it again,
+// which keeps comments but not blank lines.
+func setValueReencode(doc *yaml.Node, names []string, valueNode *yaml.Node) ([]byte, error) {
+	if len(doc.Content) == 0 {
+		*doc = yaml.Node{
+			Kind:        yaml.DocumentNode,
+			HeadComment: doc.HeadComment,
+			Content:     []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}},
+		}
+	}
+	node := doc.Content[0]
+	if node.Kind == yaml.ScalarNode && node.Tag == "!!null" {
+		*node = yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", HeadComment: node.HeadComment}
+	}
+	for i, name := range names {
+		if node.Kind != yaml.MappingNode {
+			return nil, fmt.Errorf("%w: %q is not an object", ErrInvalidKey, strings.Join(names[:i], "."))
+		}
+		_, existing := findMappingKey(node, name)
+		if i == len(names)-1 {
+			if existing != nil {
+				valueNode.HeadComment = existing.HeadComment
+				valueNode.LineComment = existing.LineComment
+				valueNode.FootComment = existing.FootComment
+				*existing = *valueNode
+			} else {
+				node.Content = append(node.Content, newKeyNode(name), valueNode)
+			}
+			break
+		}
+		if existing == nil || (existing.Kind == yaml.ScalarNode && existing.Tag == "!!null") {
+			child := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			if existing != nil {
+				*existing = *child
+				child = existing
+			} else {
+				node.Content = append(node.Content, newKeyNode(name), child)
+			}
+			existing = child
+		}
+		node = existing
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func findMappingKey(node *yaml.Node, name string) (*yaml.Node, *yaml.Node) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == name {
+			return node.Content[i], node.Content[i+1]
+		}
+	}
+	return nil, nil
+}
+
+func newKeyNode(name string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: name}
+}
+
+// newValueNode converts the string value to a YAML node of the type in
+// the schema. For "oneOf" fields, the first type that the value can be
+// converted to is used.
+func newValueNode(schema *jsonschema.Schema, value string) (*yaml.Node, error) {
+	root := Schema("")
+	schema = resolveSchema(root, schema)
+	types := []string{schema.Type}
+	if len(schema.OneOf) > 0 || len(schema.AnyOf) > 0 {
+		types = nil
+		for _, alt := range append(slices.Clone(schema.OneOf), schema.AnyOf...) {
+			types = append(types, resolveSchema(root, alt).Type)
+		}
+	}
+	var firstErr error
+	for _, typ := range types {
+		node, err := newValueNodeOfType(schema, typ, value)
+		if err == nil {
+			return node, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	return nil, firstErr
+}
+
+func newValueNodeOfType(schema *jsonschema.Schema, typ, value string) (*yaml.Node, error) {
+	node := &yaml.Node{Kind: yaml.ScalarNode}
+	switch typ {
+	case "object":
+		return nil, errors.New("cannot set a whole object, set its fields instead")
+	case "array":
+		node = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				itemNode, err := newValueNode(schema.Items, item)
+				if err != nil {
+					return nil, err
+				}
+				node.Content = append(node.Content, itemNode)
+			}
+		}
+		return node, nil
+	case "null":
+		if value != "null" && value != "" {
+			return nil, fmt.Errorf("want null, got %q", value)
+		}
+		node.Tag, node.Value = "!!null", "null"
+	case "boolean":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("want boolean, got %q", value)
+		}
+		node.Tag, node.Value = "!!bool", strconv.FormatBool(b)
+	case "integer":
+		if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+			return nil, fmt.Errorf("want integer, got %q", value)
+		}
+		node.Tag, node.Value = "!!int", value
+	case "number":
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return nil, fmt.Errorf("want number, got %q", value)
+		}
+		node.Tag, node.Value = "!!float", value
+		if _, err := strconv.ParseInt(value, 10, 64); err == nil {
+			node.Tag = "!!int"
+		}
+	default:
+		// Sets the quoting style if the value would otherwise be read
+		// as another type, e.g "true" or "123"
+		node.SetString(value)
+	}
+	return node, nil
+}
@@ -0,0 +1,280 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package config
+
+import (
+	"testing"
+)
+
+func TestSetValue(t *testing.T) {
+	const base = `# Personio settings
+---
+baseUrl: # https://example.personio.de
+allowedHosts: []
+
+auth:
+  source: config # config | keepass
+  email: "jane@example.com"
+profiles: {}
+
+log:
+  level: warn # trace | debug
+`
+	var tests = []struct {
+		name  string
+		yaml  string
+		key   string
+		value string
+		want  string
+	}{
+		{
+			name:  "set empty value",
+			yaml:  base,
+			key:   "baseUrl",
+			value: "https://example.personio.de",
+			want: `# Personio settings
+---
+baseUrl: https://example.personio.de # https://example.personio.de
+allowedHosts: []
+
+auth:
+  source: config # config | keepass
+  email: "jane@example.com"
+profiles: {}
+
+log:
+  level: warn # trace | debug
+`,
+		},
+		{
+			name:  "replace value",
+			yaml:  base,
+			key:   "log.level",
+			value: "debug",
+			want: `# Personio settings
+---
+baseUrl: # https://example.personio.de
+allowedHosts: []
+
+auth:
+  source: config # config | keepass
+  email: "jane@example.com"
+profiles: {}
+
+log:
+  level: debug # trace | debug
+`,
+		},
+		{
+			name:  "replace quoted value",
+			yaml:  base,
+			key:   "auth.email",
+			value: "john@example.com",
+			want: `# Personio settings
+---
+baseUrl: # https://example.personio.de
+allowedHosts: []
+
+auth:
+  source: config # config | keepass
+  email: john@example.com
+profiles: {}
+
+log:
+  level: warn # trace | debug
+`,
+		},
+		{
+			name:  "set list",
+			yaml:  base,
+			key:   "allowedHosts",
+			value: "localhost:8080, *.example.com",
+			want: `# Personio settings
+---
+baseUrl: # https://example.personio.de
+allowedHosts: ['localhost:8080', '*.example.com']
+
+auth:
+  source: config # config | keepass
+  email: "jane@example.com"
+profiles: {}
+
+log:
+  level: warn # trace | debug
+`,
+		},
+		{
+			name:  "add field",
+			yaml:  base,
+			key:   "auth.password",
+			value: "123",
+			want: `# Personio settings
+---
+baseUrl: # https://example.personio.de
+allowedHosts: []
+
+auth:
+  source: config # config | keepass
+  email: "jane@example.com"
+  password: "123"
+profiles: {}
+
+log:
+  level: warn # trace | debug
+`,
+		},
+		{
+			name:  "add to empty object",
+			yaml:  base,
+			key:   "profiles.work.auth.email",
+			value: "jane@work.example.com",
+			want: `# Personio settings
+---
+baseUrl: # https://example.personio.de
+allowedHosts: []
+
+auth:
+  source: config # config | keepass
+  email: "jane@example.com"
+profiles:
+  work:
+    auth:
+      email: jane@work.example.com
+
+log:
+  level: warn # trace | debug
+`,
+		},
+		{
+			name:  "add nested field",
+			yaml:  base,
+			key:   "retry.maxAttempts",
+			value: "2",
+			want: `# Personio settings
+---
+baseUrl: # https://example.personio.de
+allowedHosts: []
+
+auth:
+  source: config # config | keepass
+  email: "jane@example.com"
+profiles: {}
+
+log:
+  level: warn # trace | debug
+retry:
+  maxAttempts: 2
+`,
+		},
+		{
+			name:  "empty file",
+			yaml:  "# only comments\n",
+			key:   "output",
+			value: "json",
+			want:  "# only comments\noutput: json\n",
+		},
+		{
+			name:  "reencode flow style",
+			yaml:  "log: {level: warn} # logging\n",
+			key:   "log.format",
+			value: "json",
+			want:  "log: {level: warn, format: json} # logging\n",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := SetValue([]byte(tc.yaml), tc.key, tc.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tc.want {
+				t.Errorf("want:\n%s\ngot:\n%s", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestSetValueErrors(t *testing.T) {
+	var tests = []struct {
+		name  string
+		key   string
+		value string
+		want  string
+	}{
+		{
+			name:  "unknown key",
+			key:   "auth.username",
+			value: "jane",
+			want:  `unknown config key: "auth.username"`,
+		},
+		{
+			name:  "bad enum",
+			key:   "output",
+			value: "xml",
+			want:  `output: invalid value "xml", must be one of: pretty, json, yaml, csv, tsv`,
+		},
+		{
+			name:  "unknown timezone",
+			key:   "profiles.work.timezone",
+			value: "Not/AZone",
+			want:  `profiles.work.timezone: unknown timezone "Not/AZone"`,
+		},
+		{
+			name:  "bad integer",
+			key:   "retry.maxAttempts",
+			value: "many",
+			want:  `retry.maxAttempts: want integer, got "many"`,
+		},
+		{
+			name:  "object",
+			key:   "log",
+			value: "debug",
+			want:  "log: cannot set a whole object, set its fields instead",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := SetValue(nil, tc.key, tc.value)
+			if err == nil {
+				t.Fatal("want error, got nil")
+			}
+			if err.Error() != tc.want {
+				t.Errorf("want %q, got %q", tc.want, err.Error())
+			}
+		})
+	}
+}
+
+func TestLookupValue(t *testing.T) {
+	yaml := []byte("log:\n  level: debug\n")
+	node, ok, err := LookupValue(yaml, "log.level")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok || node.Value != "debug" {
+		t.Errorf("want debug, got %v (found: %t)", node, ok)
+	}
+	if _, ok, _ := LookupValue(yaml, "log.format"); ok {
+		t.Error("want log.format not found")
+	}
+	if _, _, err := LookupValue([]byte("log: ["), "log"); err == nil {
+		t.Error("want parse error, got nil")
+	}
+}
@@ -0,0 +1,257 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+// File is a config file location, in the order they are read.
+type File struct {
+	Path string `json:"path"`
+	// Required is true for files passed explicitly, e.g via the --config
+	// flag, which must exist.
+	Required bool `json:"required"`
+	// Loaded is true if the file was found and merged into the config.
+	Loaded bool `json:"loaded"`
+}
+
+// CandidateFiles returns the config files to look for, where the latter
+// override the values from the former. The explicit file, if any,
+// is read last.
+func CandidateFiles(goos, homeDir, configDir, explicit string) []File {
+	var files []File
+	if goos != "windows" {
+		files = append(files, File{Path: "/etc/rootless-personio/personio.yaml"})
+	}
+	if homeDir != "" {
+		files = append(files, File{Path: filepath.Join(homeDir, ".personio.yaml")})
+	}
+	if configDir != "" {
+		files = append(files, File{Path: filepath.Join(configDir, "personio.yaml")})
+	}
+	files = append(files, File{Path: ".personio.yaml"})
+	if explicit != "" {
+		files = append(files, File{Path: explicit, Required: true})
+	}
+	return files
+}
+
+// DefaultCandidateFiles returns [CandidateFiles] for the current user
+// and operating system.
+func DefaultCandidateFiles(explicit string) []File {
+	homeDir, _ := os.UserHomeDir()
+	configDir, _ := os.UserConfigDir()
+	return CandidateFiles(runtime.GOOS, homeDir, configDir, explicit)
+}
+
+// UserConfigFile returns the path to the config file in the user's config
+// directory, e.g ~/.config/personio.yaml on Linux.
+func UserConfigFile() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "personio.yaml"), nil
+}
+
+// MergeFiles merges the config files that exist into the viper instance,
+// in order. Returns a copy of the files where the Loaded field is set.
+func MergeFiles(v *viper.Viper, files []File) ([]File, error) {
+	result := make([]File, len(files))
+	for i, file := range files {
+		result[i] = file
+		v.SetConfigFile(file.Path)
+		if err := v.MergeInConfig(); err != nil {
+			if errors.Is(err, fs.ErrNotExist) && !file.Required {
+				continue
+			}
+			return result, fmt.Errorf("%s: %w", file.Path, err)
+		}
+		result[i].Loaded = true
+	}
+	return result, nil
+}
+
+// Available [ValueSource] kinds, in increasing priority.
+const (
+	SourceDefault = "default"
+	SourceFile    = "file"
+	SourceProfile = "profile"
+	SourceEnv     = "env"
+	SourceFlag    = "flag"
+)
+
+// ValueSource describes where the effective value of a config field
+// was read from.
+type ValueSource struct {
+	Key string `json:"key"`
+	// Source is one of "default", "file", "profile", "env" or "flag".
+	Source string `json:"source"`
+	// Detail is the file path, env var, flag or profile name.
+	Detail string `json:"detail,omitempty"`
+}
+
+// SourceOptions are the inputs to [ValueSources].
+type SourceOptions struct {
+	// Defaults is the config with the default values, and decides
+	// which keys are reported.
+	Defaults Config
+	// Files are the config files, as returned by [MergeFiles].
+	Files []File
+	// EnvPrefix is the prefix of the env vars, e.g "PERSONIO".
+	EnvPrefix string
+	// LookupEnv is used to look up env vars, e.g [os.LookupEnv].
+	LookupEnv func(key string) (string, bool)
+	// Flags maps config keys to the flags bound to them.
+	Flags map[string]*pflag.Flag
+	// Profile is the name of the selected profile, if any, and Profiles
+	// are all profiles.
+	Profile  string
+	Profiles map[string]Profile
+}
+
+// ValueSources returns where the effective value of each config field was
+// read from: flags override env vars, which override the selected profile,
+// which overrides the files, which override the defaults.
+func ValueSources(opts SourceOptions) ([]ValueSource, error) {
+	keys, err := configKeys(opts.Defaults)
+	if err != nil {
+		return nil, err
+	}
+	var fileVipers []*viper.Viper
+	var filePaths []string
+	for _, file := range opts.Files {
+		if !file.Loaded {
+			continue
+		}
+		v := viper.New()
+		v.SetConfigType("yaml")
+		v.SetConfigFile(file.Path)
+		if err := v.ReadInConfig(); err != nil {
+			return nil, fmt.Errorf("%s: %w", file.Path, err)
+		}
+		fileVipers = append(fileVipers, v)
+		filePaths = append(filePaths, file.Path)
+	}
+	profileKeys := map[string]bool{}
+	if profile, ok := opts.Profiles[opts.Profile]; ok {
+		for _, key := range profileOverrideKeys(profile) {
+			profileKeys[key] = true
+		}
+	}
+
+	sources := make([]ValueSource, 0, len(keys))
+	for _, key := range keys {
+		source := ValueSource{Key: key, Source: SourceDefault}
+		envKey := strings.ToUpper(opts.EnvPrefix + "_" + strings.ReplaceAll(key, ".", "_"))
+		switch {
+		case opts.Flags[key] != nil && opts.Flags[key].Changed:
+			source.Source, source.Detail = SourceFlag, "--"+opts.Flags[key].Name
+		case opts.LookupEnv != nil && isEnvSet(opts.LookupEnv, envKey):
+			source.Source, source.Detail = SourceEnv, envKey
+		case profileKeys[key]:
+			source.Source, source.Detail = SourceProfile, opts.Profile
+		default:
+			for i := len(fileVipers) - 1; i >= 0; i-- {
+				if fileVipers[i].IsSet(key) {
+					source.Source, source.Detail = SourceFile, filePaths[i]
+					break
+				}
+			}
+		}
+		sources = append(sources, source)
+	}
+	return sources, nil
+}
+
+func isEnvSet(lookupEnv func(string) (string, bool), key string) bool {
+	// Viper ignores empty env vars by default
+	value, ok := lookupEnv(key)
+	return ok && value != ""
+}
+
+// configKeys returns the dot-separated keys of all fields in the config,
+// in the casing used in the config files, sorted alphabetically.
+func configKeys(cfg Config) ([]string, error) {
+	b, err := yaml.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]any
+	if err := yaml.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+	var keys []string
+	var walk func(prefix string, m map[string]any)
+	walk = func(prefix string, m map[string]any) {
+		for k, v := range m {
+			if child, ok := v.(map[string]any); ok && len(child) > 0 {
+				walk(prefix+k+".", child)
+				continue
+			}
+			keys = append(keys, prefix+k)
+		}
+	}
+	walk("", m)
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// profileOverrideKeys returns the keys of the fields that the profile
+// overrides in [Config.ApplyProfile].
+func profileOverrideKeys(p Profile) []string {
+	var keys []string
+	if p.BaseURL != "" {
+		keys = append(keys, "baseUrl")
+	}
+	if p.StandardStartTime != "" {
+		keys = append(keys, "standardStartTime")
+	}
+	if p.MinimumPeriodDuration != 0 {
+		keys = append(keys, "minimumPeriodDuration")
+	}
+	if p.Timezone != "" {
+		keys = append(keys, "timezone")
+	}
+	auth := reflect.ValueOf(p.Auth)
+	for i := 0; i < auth.NumField(); i++ {
+		if auth.Field(i).IsZero() {
+			continue
+		}
+		field := auth.Type().Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		keys = append(keys, "auth."+name)
+	}
+	return keys
+}
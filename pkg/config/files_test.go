@@ -0,0 +1,191 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package config
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+func TestCandidateFiles(t *testing.T) {
+	var tests = []struct {
+		name      string
+		goos      string
+		homeDir   string
+		configDir string
+		explicit  string
+		want      []File
+	}{
+		{
+			name:      "linux",
+			goos:      "linux",
+			homeDir:   "/home/jane",
+			configDir: "/home/jane/.config",
+			want: []File{
+				{Path: "/etc/rootless-personio/personio.yaml"},
+				{Path: "/home/jane/.personio.yaml"},
+				{Path: "/home/jane/.config/personio.yaml"},
+				{Path: ".personio.yaml"},
+			},
+		},
+		{
+			name:      "windows",
+			goos:      "windows",
+			homeDir:   "/Users/jane",
+			configDir: "/Users/jane/AppData/Roaming",
+			want: []File{
+				{Path: filepath.Join("/Users/jane", ".personio.yaml")},
+				{Path: filepath.Join("/Users/jane/AppData/Roaming", "personio.yaml")},
+				{Path: ".personio.yaml"},
+			},
+		},
+		{
+			name:     "explicit without home",
+			goos:     "linux",
+			explicit: "/tmp/personio.yaml",
+			want: []File{
+				{Path: "/etc/rootless-personio/personio.yaml"},
+				{Path: ".personio.yaml"},
+				{Path: "/tmp/personio.yaml", Required: true},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := CandidateFiles(tc.goos, tc.homeDir, tc.configDir, tc.explicit)
+			if !reflect.DeepEqual(tc.want, got) {
+				t.Errorf("want:\n%+v\ngot:\n%+v", tc.want, got)
+			}
+		})
+	}
+}
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMergeFiles(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "first.yaml")
+	second := filepath.Join(dir, "second.yaml")
+	missing := filepath.Join(dir, "missing.yaml")
+	writeTestFile(t, first, "baseUrl: https://first.personio.de\nauth:\n  email: jane@example.com\n")
+	writeTestFile(t, second, "baseUrl: https://second.personio.de\n")
+
+	v := viper.New()
+	v.SetConfigType("yaml")
+	got, err := MergeFiles(v, []File{{Path: first}, {Path: missing}, {Path: second}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []File{{Path: first, Loaded: true}, {Path: missing}, {Path: second, Loaded: true}}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want:\n%+v\ngot:\n%+v", want, got)
+	}
+	if got := v.GetString("baseUrl"); got != "https://second.personio.de" {
+		t.Errorf("want base URL from second file, got %q", got)
+	}
+	if got := v.GetString("auth.email"); got != "jane@example.com" {
+		t.Errorf("want email from first file, got %q", got)
+	}
+
+	_, err = MergeFiles(viper.New(), []File{{Path: missing, Required: true}})
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("want fs.ErrNotExist for required file, got %v", err)
+	}
+}
+
+func TestValueSources(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "first.yaml")
+	second := filepath.Join(dir, "second.yaml")
+	writeTestFile(t, first, "baseUrl: https://first.personio.de\nauth:\n  email: jane@example.com\n")
+	writeTestFile(t, second, "baseUrl: https://second.personio.de\n")
+
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	flags.String("output", "", "")
+	flags.String("log.level", "", "")
+	if err := flags.Parse([]string{"--output", "json"}); err != nil {
+		t.Fatal(err)
+	}
+
+	sources, err := ValueSources(SourceOptions{
+		Defaults: Config{},
+		Files: []File{
+			{Path: first, Loaded: true},
+			{Path: filepath.Join(dir, "missing.yaml")},
+			{Path: second, Loaded: true},
+		},
+		EnvPrefix: "PERSONIO",
+		LookupEnv: func(key string) (string, bool) {
+			switch key {
+			case "PERSONIO_AUTH_PASSWORD":
+				return "hunter2", true
+			case "PERSONIO_LOG_FORMAT":
+				return "", true
+			}
+			return "", false
+		},
+		Flags: map[string]*pflag.Flag{
+			"output":    flags.Lookup("output"),
+			"log.level": flags.Lookup("log.level"),
+		},
+		Profile: "work",
+		Profiles: map[string]Profile{
+			"work": {StandardStartTime: "08:00", Auth: Auth{TOTPSecretCommand: "pass totp"}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]ValueSource{
+		"baseUrl":                {Key: "baseUrl", Source: SourceFile, Detail: second},
+		"auth.email":             {Key: "auth.email", Source: SourceFile, Detail: first},
+		"auth.password":          {Key: "auth.password", Source: SourceEnv, Detail: "PERSONIO_AUTH_PASSWORD"},
+		"auth.totpSecretCommand": {Key: "auth.totpSecretCommand", Source: SourceProfile, Detail: "work"},
+		"standardStartTime":      {Key: "standardStartTime", Source: SourceProfile, Detail: "work"},
+		"output":                 {Key: "output", Source: SourceFlag, Detail: "--output"},
+		"log.level":              {Key: "log.level", Source: SourceDefault},
+		"log.format":             {Key: "log.format", Source: SourceDefault},
+		"retry.maxAttempts":      {Key: "retry.maxAttempts", Source: SourceDefault},
+	}
+	found := 0
+	for _, source := range sources {
+		if w, ok := want[source.Key]; ok {
+			found++
+			if source != w {
+				t.Errorf("want %+v, got %+v", w, source)
+			}
+		}
+	}
+	if found != len(want) {
+		t.Errorf("want %d keys, found %d in: %+v", len(want), found, sources)
+	}
+}
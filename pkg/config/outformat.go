@@ -38,6 +38,15 @@ const (
 	OutFormatPretty OutFormat = "pretty"
 	OutFormatJSON   OutFormat = "json"
 	OutFormatYAML   OutFormat = "yaml"
+	// OutFormatTemplate formats the output using a Go template,
+	// given via the --template or --template-file flags. It is left out
+	// of the JSON schema, as the config files can't set the template.
+	OutFormatTemplate OutFormat = "template"
+	// OutFormatCSV formats lists as comma-separated values,
+	// where nested lists are flattened into multiple rows.
+	OutFormatCSV OutFormat = "csv"
+	// OutFormatTSV is the same as [OutFormatCSV], but uses tabs.
+	OutFormatTSV OutFormat = "tsv"
 )
 
 func _() {
@@ -66,8 +75,14 @@ func (f *OutFormat) Set(value string) error {
 		*f = OutFormatJSON
 	case OutFormatYAML:
 		*f = OutFormatYAML
+	case OutFormatTemplate:
+		*f = OutFormatTemplate
+	case OutFormatCSV:
+		*f = OutFormatCSV
+	case OutFormatTSV:
+		*f = OutFormatTSV
 	default:
-		return fmt.Errorf("unknown output format: %q, must be one of: pretty, json, yaml", value)
+		return fmt.Errorf("unknown output format: %q, must be one of: pretty, json, yaml, template, csv, tsv", value)
 	}
 	return nil
 }
@@ -95,6 +110,8 @@ func (OutFormat) JSONSchema() *jsonschema.Schema {
 			OutFormatPretty,
 			OutFormatJSON,
 			OutFormatYAML,
+			OutFormatCSV,
+			OutFormatTSV,
 		},
 		Default: OutFormatDefault,
 	}
@@ -0,0 +1,46 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestOutFormatSet(t *testing.T) {
+	for _, want := range []OutFormat{
+		OutFormatPretty,
+		OutFormatJSON,
+		OutFormatYAML,
+		OutFormatTemplate,
+		OutFormatCSV,
+		OutFormatTSV,
+	} {
+		var got OutFormat
+		if err := got.Set(string(want)); err != nil {
+			t.Errorf("%s: unexpected error: %s", want, err)
+		} else if got != want {
+			t.Errorf("want %q, got %q", want, got)
+		}
+	}
+
+	var f OutFormat
+	if err := f.Set("xml"); err == nil || !strings.Contains(err.Error(), "template, csv, tsv") {
+		t.Errorf("want error listing the formats, got %v", err)
+	}
+}
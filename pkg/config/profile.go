@@ -0,0 +1,170 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+)
+
+var (
+	ErrProfileNotFound = errors.New("profile not found")
+)
+
+// Redacted is the value that secrets are replaced with by
+// [Config.RedactSecrets].
+const Redacted = "/redacted/"
+
+// ProfileSecretAuthKeys are the keys of the auth configs that are not taken
+// from the top-level auth config by profiles that set their own baseUrl,
+// so the credentials of one Personio instance are never sent to another.
+var ProfileSecretAuthKeys = []string{
+	"password",
+	"passwordCommand",
+	"envFile",
+	"encryptedFile",
+	"totpSecret",
+	"totpSecretCommand",
+	"csrfToken",
+	"emailToken",
+}
+
+// Profile is a named set of configs, such as for a second Personio instance,
+// that override the top-level configs when selected.
+type Profile struct {
+	// BaseURL is the URL to this profile's Personio instance.
+	BaseURL string `yaml:"baseUrl" jsonschema:"oneof_type=string;null" jsonschema_extras:"format=uri"`
+	// Auth contains the login configs for this profile. Fields that are
+	// left empty are taken from the top-level auth config, except for the
+	// passwords, secrets and tokens when the profile sets its own baseUrl.
+	Auth Auth
+	// StandardStartTime overrides the top-level standardStartTime config.
+	StandardStartTime string `yaml:"standardStartTime" jsonschema:"oneof_type=string;null"`
+	// MinimumPeriodDuration overrides the top-level minimumPeriodDuration
+	// config.
+	MinimumPeriodDuration time.Duration `yaml:"minimumPeriodDuration" jsonschema:"oneof_type=string;null"`
+	// Timezone overrides the top-level timezone config.
+	Timezone string `yaml:"timezone" jsonschema:"oneof_type=string;null" jsonschema_extras:"format=timezone"`
+}
+
+// ProfileNames returns the names of all profiles, sorted alphabetically.
+func (c Config) ProfileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// ApplyProfile returns a copy of the config where the non-empty fields of
+// the named profile override the top-level fields. Profile names are
+// case-insensitive.
+//
+// Returns [ErrProfileNotFound] if there is no profile with that name.
+func (c Config) ApplyProfile(name string) (Config, error) {
+	profile, ok := c.Profiles[name]
+	if !ok {
+		// Viper lowercases all keys when reading the config files
+		name = strings.ToLower(name)
+		profile, ok = c.Profiles[name]
+	}
+	if !ok {
+		if len(c.Profiles) == 0 {
+			return c, fmt.Errorf("%w: %q, no profiles are configured", ErrProfileNotFound, name)
+		}
+		return c, fmt.Errorf("%w: %q, must be one of: %s", ErrProfileNotFound, name,
+			strings.Join(c.ProfileNames(), ", "))
+	}
+	c.Profile = name
+	if profile.BaseURL != "" {
+		c.BaseURL = profile.BaseURL
+		clearAuthKeys(reflect.ValueOf(&c.Auth).Elem(), ProfileSecretAuthKeys)
+	}
+	if profile.StandardStartTime != "" {
+		c.StandardStartTime = profile.StandardStartTime
+	}
+	if profile.MinimumPeriodDuration != 0 {
+		c.MinimumPeriodDuration = profile.MinimumPeriodDuration
+	}
+	if profile.Timezone != "" {
+		c.Timezone = profile.Timezone
+	}
+	overrideNonZeroFields(reflect.ValueOf(&c.Auth).Elem(), reflect.ValueOf(profile.Auth))
+	return c, nil
+}
+
+// RedactSecrets returns a copy of the config where the non-empty values of
+// [ProfileSecretAuthKeys] are replaced with [Redacted], both in the
+// top-level auth config and in the auth configs of all profiles.
+func (c Config) RedactSecrets() Config {
+	redactAuthKeys(reflect.ValueOf(&c.Auth).Elem(), ProfileSecretAuthKeys)
+	if c.Profiles != nil {
+		profiles := make(map[string]Profile, len(c.Profiles))
+		for name, profile := range c.Profiles {
+			redactAuthKeys(reflect.ValueOf(&profile.Auth).Elem(), ProfileSecretAuthKeys)
+			profiles[name] = profile
+		}
+		c.Profiles = profiles
+	}
+	return c
+}
+
+// clearAuthKeys sets the fields to their zero value, by their YAML key.
+func clearAuthKeys(v reflect.Value, keys []string) {
+	forEachAuthKey(v, keys, func(field reflect.Value) {
+		field.SetZero()
+	})
+}
+
+// redactAuthKeys replaces the non-empty fields with [Redacted], by their
+// YAML key.
+func redactAuthKeys(v reflect.Value, keys []string) {
+	forEachAuthKey(v, keys, func(field reflect.Value) {
+		if field.Kind() == reflect.String && field.String() != "" {
+			field.SetString(Redacted)
+		}
+	})
+}
+
+func forEachAuthKey(v reflect.Value, keys []string, f func(field reflect.Value)) {
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		key, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if key == "" {
+			key = field.Name
+		}
+		if slices.ContainsFunc(keys, func(k string) bool {
+			return strings.EqualFold(k, key)
+		}) {
+			f(v.Field(i))
+		}
+	}
+}
+
+func overrideNonZeroFields(dst, src reflect.Value) {
+	for i := 0; i < src.NumField(); i++ {
+		if field := src.Field(i); !field.IsZero() {
+			dst.Field(i).Set(field)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package config
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestApplyProfile(t *testing.T) {
+	cfg := Config{
+		BaseURL: "https://a.personio.de",
+		Auth: Auth{
+			Source:     CredentialSourceConfig,
+			Email:      "jane@a.example.com",
+			Password:   "hunter2",
+			TOTPSecret: "JBSWY3DPEHPK3PXP",
+			EmailToken: "email-token",
+			CSRFToken:  "csrf-token",
+		},
+		StandardStartTime:     "09:00",
+		MinimumPeriodDuration: time.Minute,
+		Timezone:              "Europe/Berlin",
+		Profiles: map[string]Profile{
+			"work": {
+				BaseURL: "https://b.personio.de",
+				Auth: Auth{
+					Email: "jane@b.example.com",
+				},
+				MinimumPeriodDuration: 5 * time.Minute,
+				Timezone:              "Europe/London",
+			},
+			"other-user": {
+				Auth: Auth{
+					Email: "john@a.example.com",
+				},
+			},
+		},
+	}
+
+	got, err := cfg.ApplyProfile("Work")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := cfg
+	want.Profile = "work"
+	want.BaseURL = "https://b.personio.de"
+	want.Auth.Email = "jane@b.example.com"
+	// Secrets are not sent to the profile's other Personio instance
+	want.Auth.Password = ""
+	want.Auth.TOTPSecret = ""
+	want.Auth.EmailToken = ""
+	want.Auth.CSRFToken = ""
+	want.MinimumPeriodDuration = 5 * time.Minute
+	want.Timezone = "Europe/London"
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want:\n%+v\ngot:\n%+v", want, got)
+	}
+	if cfg.Auth.Email != "jane@a.example.com" {
+		t.Errorf("want original config unchanged, got email %q", cfg.Auth.Email)
+	}
+
+	// Same Personio instance, so the secrets are inherited
+	got, err = cfg.ApplyProfile("other-user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = cfg
+	want.Profile = "other-user"
+	want.Auth.Email = "john@a.example.com"
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want:\n%+v\ngot:\n%+v", want, got)
+	}
+
+	if _, err := cfg.ApplyProfile("home"); !errors.Is(err, ErrProfileNotFound) {
+		t.Errorf("want ErrProfileNotFound, got %v", err)
+	}
+}
+
+func TestRedactSecrets(t *testing.T) {
+	cfg := Config{
+		Auth: Auth{
+			Email:      "jane@a.example.com",
+			Password:   "hunter2",
+			TOTPSecret: "JBSWY3DPEHPK3PXP",
+		},
+		Profiles: map[string]Profile{
+			"work": {
+				Auth: Auth{
+					Email:           "jane@b.example.com",
+					PasswordCommand: "pass show personio",
+					EmailToken:      "email-token",
+				},
+			},
+		},
+	}
+
+	got := cfg.RedactSecrets()
+	want := Config{
+		Auth: Auth{
+			Email:      "jane@a.example.com",
+			Password:   Redacted,
+			TOTPSecret: Redacted,
+		},
+		Profiles: map[string]Profile{
+			"work": {
+				Auth: Auth{
+					Email:           "jane@b.example.com",
+					PasswordCommand: Redacted,
+					EmailToken:      Redacted,
+				},
+			},
+		},
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want:\n%+v\ngot:\n%+v", want, got)
+	}
+	if cfg.Profiles["work"].Auth.EmailToken != "email-token" {
+		t.Errorf("want original profiles unchanged, got email token %q", cfg.Profiles["work"].Auth.EmailToken)
+	}
+}
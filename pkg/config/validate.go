@@ -0,0 +1,284 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package config
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/invopop/jsonschema"
+	"gopkg.in/yaml.v3"
+)
+
+// ValidationError is a problem found in a config file by [Validate].
+type ValidationError struct {
+	File   string `json:"file,omitempty"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+	// Key is the dot-separated path to the invalid field, e.g "log.level".
+	Key     string `json:"key"`
+	Message string `json:"message"`
+}
+
+// Error implements [error].
+func (e ValidationError) Error() string {
+	var sb strings.Builder
+	if e.File != "" {
+		sb.WriteString(e.File)
+		sb.WriteByte(':')
+	}
+	fmt.Fprintf(&sb, "%d:%d: ", e.Line, e.Column)
+	if e.Key != "" {
+		fmt.Fprintf(&sb, "%s: ", e.Key)
+	}
+	sb.WriteString(e.Message)
+	return sb.String()
+}
+
+// ValidateFile reads and validates a config file using [Validate].
+func ValidateFile(path string) ([]ValidationError, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	errs, err := Validate(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for i := range errs {
+		errs[i].File = path
+	}
+	return errs, nil
+}
+
+// Validate checks a YAML config against the JSON schema from [Schema],
+// and reports unknown keys, values of the wrong type, values not
+// allowed by enums, such as an invalid output format, and values of
+// the formats that the program can check, such as an unknown timezone.
+//
+// Only returns an error if the YAML itself could not be parsed.
+func Validate(data []byte) ([]ValidationError, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 {
+		return nil, nil
+	}
+	v := schemaValidator{root: Schema("")}
+	v.validate(doc.Content[0], v.root, "")
+	return v.errs, nil
+}
+
+type schemaValidator struct {
+	root *jsonschema.Schema
+	errs []ValidationError
+}
+
+func (v *schemaValidator) addError(node *yaml.Node, key, format string, args ...any) {
+	v.errs = append(v.errs, ValidationError{
+		Line:    node.Line,
+		Column:  node.Column,
+		Key:     key,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+func (v *schemaValidator) validate(node *yaml.Node, schema *jsonschema.Schema, key string) {
+	schema = resolveSchema(v.root, schema)
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	format, _ := schema.Extras["format"].(string)
+	if check, ok := formatCheckers[format]; ok &&
+		node.Kind == yaml.ScalarNode && node.Tag == "!!str" && node.Value != "" {
+		if err := check(node.Value); err != nil {
+			v.addError(node, key, "%s", err)
+			return
+		}
+	}
+	if alternatives := append(slices.Clone(schema.OneOf), schema.AnyOf...); len(alternatives) > 0 {
+		v.validateAlternatives(node, alternatives, key)
+		return
+	}
+	if schema.Type != "" && !nodeHasType(node, schema.Type) {
+		v.addError(node, key, "want %s, got %s", schema.Type, describeNode(node))
+		return
+	}
+	if len(schema.Enum) > 0 && node.Kind == yaml.ScalarNode {
+		var allowed []string
+		for _, e := range schema.Enum {
+			allowed = append(allowed, fmt.Sprint(e))
+		}
+		if !slices.Contains(allowed, node.Value) {
+			v.addError(node, key, "invalid value %q, must be one of: %s", node.Value, strings.Join(allowed, ", "))
+		}
+	}
+	switch node.Kind {
+	case yaml.MappingNode:
+		v.validateMapping(node, schema, key)
+	case yaml.SequenceNode:
+		if schema.Items != nil {
+			for i, item := range node.Content {
+				v.validate(item, schema.Items, fmt.Sprintf("%s[%d]", key, i))
+			}
+		}
+	}
+}
+
+func (v *schemaValidator) validateAlternatives(node *yaml.Node, alternatives []*jsonschema.Schema, key string) {
+	var types []string
+	for _, alt := range alternatives {
+		sub := schemaValidator{root: v.root}
+		sub.validate(node, alt, key)
+		if len(sub.errs) == 0 {
+			return
+		}
+		if alt := resolveSchema(v.root, alt); alt.Type != "" {
+			types = append(types, alt.Type)
+		}
+	}
+	v.addError(node, key, "want %s, got %s", strings.Join(types, " or "), describeNode(node))
+}
+
+func (v *schemaValidator) validateMapping(node *yaml.Node, schema *jsonschema.Schema, key string) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		keyNode, valueNode := node.Content[i], node.Content[i+1]
+		childKey := joinKey(key, keyNode.Value)
+		child, ok := propertySchema(schema, keyNode.Value)
+		if !ok {
+			if schema.AdditionalProperties == jsonschema.FalseSchema {
+				v.addError(keyNode, childKey, "unknown key")
+			}
+			continue
+		}
+		v.validate(valueNode, child, childKey)
+	}
+}
+
+// formatCheckers are the string formats from the "format" keyword in the
+// JSON schema that are checked by [Validate]. Other formats, such as
+// "email", are only used as hints by editors.
+var formatCheckers = map[string]func(string) error{
+	"timezone": func(value string) error {
+		if _, err := time.LoadLocation(value); err != nil {
+			return fmt.Errorf("unknown timezone %q", value)
+		}
+		return nil
+	},
+}
+
+// SchemaForKey returns the schema of the field at the dot-separated key,
+// e.g "log.level" or "profiles.work.baseUrl", or false if there is no
+// such field.
+func SchemaForKey(key string) (*jsonschema.Schema, bool) {
+	root := Schema("")
+	schema := root
+	for _, name := range strings.Split(key, ".") {
+		var ok bool
+		schema, ok = propertySchema(resolveSchema(root, schema), name)
+		if !ok {
+			return nil, false
+		}
+	}
+	return resolveSchema(root, schema), true
+}
+
+func propertySchema(schema *jsonschema.Schema, name string) (*jsonschema.Schema, bool) {
+	if schema.Properties != nil {
+		if prop, ok := schema.Properties.Get(name); ok {
+			if s, ok := prop.(*jsonschema.Schema); ok {
+				return s, true
+			}
+		}
+	}
+	for pattern, s := range schema.PatternProperties {
+		if ok, _ := regexp.MatchString(pattern, name); ok {
+			return s, true
+		}
+	}
+	return nil, false
+}
+
+// resolveSchema follows "$ref" to the schema's definition, while keeping
+// any "oneOf" alternatives of the referencing schema.
+func resolveSchema(root, schema *jsonschema.Schema) *jsonschema.Schema {
+	for schema.Ref != "" {
+		name, ok := strings.CutPrefix(schema.Ref, "#/$defs/")
+		if !ok {
+			return schema
+		}
+		def, ok := root.Definitions[name]
+		if !ok {
+			return schema
+		}
+		schema = def
+	}
+	return schema
+}
+
+func nodeHasType(node *yaml.Node, typ string) bool {
+	switch typ {
+	case "object":
+		return node.Kind == yaml.MappingNode
+	case "array":
+		return node.Kind == yaml.SequenceNode
+	case "null":
+		return node.Kind == yaml.ScalarNode && node.Tag == "!!null"
+	case "boolean":
+		return node.Kind == yaml.ScalarNode && node.Tag == "!!bool"
+	case "integer":
+		return node.Kind == yaml.ScalarNode && node.Tag == "!!int"
+	case "number":
+		return node.Kind == yaml.ScalarNode && (node.Tag == "!!int" || node.Tag == "!!float")
+	case "string":
+		// Numbers are decoded as strings just fine, e.g durations like "0"
+		return node.Kind == yaml.ScalarNode &&
+			(node.Tag == "!!str" || node.Tag == "!!int" || node.Tag == "!!float")
+	}
+	return true
+}
+
+func describeNode(node *yaml.Node) string {
+	switch node.Kind {
+	case yaml.MappingNode:
+		return "object"
+	case yaml.SequenceNode:
+		return "array"
+	}
+	switch node.Tag {
+	case "!!null":
+		return "null"
+	case "!!bool":
+		return fmt.Sprintf("boolean %s", node.Value)
+	case "!!int", "!!float":
+		return fmt.Sprintf("number %s", node.Value)
+	}
+	return fmt.Sprintf("string %q", node.Value)
+}
+
+func joinKey(parent, name string) string {
+	if parent == "" {
+		return name
+	}
+	return parent + "." + name
+}
@@ -0,0 +1,161 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package config
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	var tests = []struct {
+		name string
+		yaml string
+		want []ValidationError
+	}{
+		{
+			name: "valid",
+			yaml: `
+baseUrl: https://example.personio.de
+allowedHosts: [localhost:8080]
+auth:
+  source: keepass
+  email: # not set
+retry:
+  maxAttempts: 3
+rateLimit:
+  requestsPerSecond: 0.5
+profiles:
+  work:
+    baseUrl: https://work.personio.de
+output: json
+log:
+  level: debug
+`,
+		},
+		{
+			name: "unknown keys",
+			yaml: `
+foo: bar
+auth:
+  username: jane
+profiles:
+  work:
+    url: https://work.personio.de
+`,
+			want: []ValidationError{
+				{Line: 2, Column: 1, Key: "foo", Message: "unknown key"},
+				{Line: 4, Column: 3, Key: "auth.username", Message: "unknown key"},
+				{Line: 7, Column: 5, Key: "profiles.work.url", Message: "unknown key"},
+			},
+		},
+		{
+			name: "wrong types",
+			yaml: `
+baseUrl: [https://example.personio.de]
+allowedHosts: localhost
+retry:
+  maxAttempts: many
+session:
+  disabled: maybe
+`,
+			want: []ValidationError{
+				{Line: 2, Column: 10, Key: "baseUrl", Message: "want string or null, got array"},
+				{Line: 3, Column: 15, Key: "allowedHosts", Message: `want array, got string "localhost"`},
+				{Line: 5, Column: 16, Key: "retry.maxAttempts", Message: `want integer, got string "many"`},
+				{Line: 7, Column: 13, Key: "session.disabled", Message: `want boolean or null, got string "maybe"`},
+			},
+		},
+		{
+			name: "bad enums",
+			yaml: `
+output: xml
+log:
+  level: loud
+`,
+			want: []ValidationError{
+				{Line: 2, Column: 9, Key: "output", Message: `invalid value "xml", must be one of: pretty, json, yaml, csv, tsv`},
+				{Line: 4, Column: 10, Key: "log.level", Message: `invalid value "loud", must be one of: trace, debug, info, warn, error, fatal, panic, disabled`},
+			},
+		},
+		{
+			name: "unknown timezones",
+			yaml: `
+timezone: Not/AZone
+profiles:
+  work:
+    timezone: Europe/Berlin
+  home:
+    timezone: Nowhere
+`,
+			want: []ValidationError{
+				{Line: 2, Column: 11, Key: "timezone", Message: `unknown timezone "Not/AZone"`},
+				{Line: 7, Column: 15, Key: "profiles.home.timezone", Message: `unknown timezone "Nowhere"`},
+			},
+		},
+		{
+			// Only set via the --template and --template-file flags
+			name: "template output",
+			yaml: "output: template\n",
+			want: []ValidationError{
+				{Line: 1, Column: 9, Key: "output", Message: `invalid value "template", must be one of: pretty, json, yaml, csv, tsv`},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := Validate([]byte(tc.yaml))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(tc.want, got) {
+				t.Errorf("want:\n%v\ngot:\n%v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestValidateExampleConfig(t *testing.T) {
+	errs, err := ValidateFile("../../personio.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range errs {
+		t.Error(e)
+	}
+}
+
+func TestValidateFile(t *testing.T) {
+	path := t.TempDir() + "/personio.yaml"
+	if err := os.WriteFile(path, []byte("output: xml\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	errs, err := ValidateFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(errs) != 1 {
+		t.Fatalf("want 1 error, got %v", errs)
+	}
+	want := path + `:1:9: output: invalid value "xml", must be one of: pretty, json, yaml, csv, tsv`
+	if got := errs[0].Error(); got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
@@ -0,0 +1,299 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package console
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+var (
+	syntaxKeyColor     = color.New(color.FgBlue, color.Bold)
+	syntaxStringColor  = color.New(color.FgGreen)
+	syntaxNumberColor  = color.New(color.FgCyan)
+	syntaxBoolColor    = color.New(color.FgYellow)
+	syntaxNullColor    = color.New(color.FgHiBlack)
+	syntaxCommentColor = color.New(color.FgHiBlack, color.Italic)
+)
+
+// ColorizeJSON adds syntax highlighting to JSON, for printing it to the
+// console. Invalid JSON is kept as-is, but may be partially colored.
+//
+// The JSON is returned unchanged when colors are disabled, which is when
+// STDOUT is not a terminal, or the NO_COLOR env var is set.
+func ColorizeJSON(data []byte) []byte {
+	if color.NoColor {
+		return data
+	}
+	var buf bytes.Buffer
+	buf.Grow(len(data) * 2)
+	for i := 0; i < len(data); {
+		end := i + 1
+		switch b := data[i]; {
+		case b == '"':
+			end = jsonStringEnd(data, i)
+			if isJSONKey(data[end:]) {
+				buf.WriteString(syntaxKeyColor.Sprint(string(data[i:end])))
+			} else {
+				buf.WriteString(syntaxStringColor.Sprint(string(data[i:end])))
+			}
+		case b == '-' || (b >= '0' && b <= '9'):
+			for end < len(data) && strings.IndexByte("0123456789+-.eE", data[end]) != -1 {
+				end++
+			}
+			buf.WriteString(syntaxNumberColor.Sprint(string(data[i:end])))
+		case bytes.HasPrefix(data[i:], []byte("true")):
+			end = i + len("true")
+			buf.WriteString(syntaxBoolColor.Sprint("true"))
+		case bytes.HasPrefix(data[i:], []byte("false")):
+			end = i + len("false")
+			buf.WriteString(syntaxBoolColor.Sprint("false"))
+		case bytes.HasPrefix(data[i:], []byte("null")):
+			end = i + len("null")
+			buf.WriteString(syntaxNullColor.Sprint("null"))
+		default:
+			buf.WriteByte(b)
+		}
+		i = end
+	}
+	return buf.Bytes()
+}
+
+// jsonStringEnd returns the offset right after the closing quote of the
+// string starting at the offset, or the end of the data if not closed.
+func jsonStringEnd(data []byte, start int) int {
+	for i := start + 1; i < len(data); i++ {
+		switch data[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1
+		}
+	}
+	return len(data)
+}
+
+// isJSONKey returns true if the data after a string starts with a colon,
+// which means the string is an object key.
+func isJSONKey(after []byte) bool {
+	after = bytes.TrimLeft(after, " \t\r\n")
+	return len(after) > 0 && after[0] == ':'
+}
+
+// ColorizeYAML adds syntax highlighting to YAML, for printing it to the
+// console. It is meant for YAML in block style, as written by the YAML
+// encoder, and keeps anything it does not recognize as-is.
+//
+// The YAML is returned unchanged when colors are disabled, which is when
+// STDOUT is not a terminal, or the NO_COLOR env var is set.
+func ColorizeYAML(data []byte) []byte {
+	if color.NoColor {
+		return data
+	}
+	var buf bytes.Buffer
+	buf.Grow(len(data) * 2)
+	// Indentation of the line that started a block scalar, like "key: |",
+	// or -1 when not inside a block scalar
+	blockIndent := -1
+	lines := strings.SplitAfter(string(data), "\n")
+	for _, line := range lines {
+		content, newline := strings.CutSuffix(line, "\n")
+		indent := len(content) - len(strings.TrimLeft(content, " "))
+		if blockIndent != -1 {
+			if strings.TrimSpace(content) == "" || indent > blockIndent {
+				buf.WriteString(content[:indent])
+				if content[indent:] != "" {
+					buf.WriteString(syntaxStringColor.Sprint(content[indent:]))
+				}
+				if newline {
+					buf.WriteByte('\n')
+				}
+				continue
+			}
+			blockIndent = -1
+		}
+		if colorizeYAMLLine(&buf, content) {
+			blockIndent = indent
+		}
+		if newline {
+			buf.WriteByte('\n')
+		}
+	}
+	return buf.Bytes()
+}
+
+// colorizeYAMLLine writes a single line of YAML with syntax highlighting,
+// and returns true if the line starts a block scalar.
+func colorizeYAMLLine(buf *bytes.Buffer, line string) bool {
+	rest := strings.TrimLeft(line, " ")
+	buf.WriteString(line[:len(line)-len(rest)])
+	if strings.HasPrefix(rest, "#") {
+		buf.WriteString(syntaxCommentColor.Sprint(rest))
+		return false
+	}
+	if rest == "---" || rest == "..." {
+		buf.WriteString(rest)
+		return false
+	}
+	// Sequence items, including nested ones like "- - foo"
+	for rest == "-" || strings.HasPrefix(rest, "- ") {
+		buf.WriteString("-")
+		rest = rest[1:]
+		trimmed := strings.TrimLeft(rest, " ")
+		buf.WriteString(rest[:len(rest)-len(trimmed)])
+		rest = trimmed
+	}
+	if keyEnd := yamlKeyEnd(rest); keyEnd != -1 {
+		buf.WriteString(syntaxKeyColor.Sprint(rest[:keyEnd]))
+		buf.WriteByte(':')
+		rest = rest[keyEnd+1:]
+		trimmed := strings.TrimLeft(rest, " ")
+		buf.WriteString(rest[:len(rest)-len(trimmed)])
+		rest = trimmed
+	}
+	value, comment := rest, ""
+	if i := yamlCommentStart(rest); i != -1 {
+		value, comment = rest[:i], rest[i:]
+	}
+	trimmedValue := strings.TrimRight(value, " ")
+	isBlockScalar := strings.HasPrefix(trimmedValue, "|") || strings.HasPrefix(trimmedValue, ">")
+	if isBlockScalar {
+		buf.WriteString(trimmedValue)
+	} else {
+		colorizeYAMLValue(buf, trimmedValue)
+	}
+	buf.WriteString(value[len(trimmedValue):])
+	if comment != "" {
+		buf.WriteString(syntaxCommentColor.Sprint(comment))
+	}
+	return isBlockScalar
+}
+
+// colorizeYAMLValue writes a scalar or flow collection, like "[a, b]".
+func colorizeYAMLValue(buf *bytes.Buffer, value string) {
+	if value == "" {
+		return
+	}
+	if value[0] != '[' && value[0] != '{' {
+		buf.WriteString(yamlScalarColor(value).Sprint(value))
+		return
+	}
+	for value != "" {
+		i := strings.IndexAny(value, "[]{},:")
+		if i == -1 {
+			i = len(value)
+		}
+		if token := strings.TrimSpace(value[:i]); token != "" {
+			start := strings.Index(value, token)
+			buf.WriteString(value[:start])
+			if i < len(value) && value[i] == ':' {
+				buf.WriteString(syntaxKeyColor.Sprint(token))
+			} else {
+				buf.WriteString(yamlScalarColor(token).Sprint(token))
+			}
+			buf.WriteString(value[start+len(token) : i])
+		} else {
+			buf.WriteString(value[:i])
+		}
+		if i < len(value) {
+			buf.WriteByte(value[i])
+			i++
+		}
+		value = value[i:]
+	}
+}
+
+func yamlScalarColor(value string) *color.Color {
+	switch value {
+	case "~", "null", "Null", "NULL":
+		return syntaxNullColor
+	case "true", "True", "TRUE", "false", "False", "FALSE":
+		return syntaxBoolColor
+	}
+	if _, err := strconv.ParseFloat(value, 64); err == nil {
+		return syntaxNumberColor
+	}
+	if _, err := strconv.ParseInt(value, 0, 64); err == nil {
+		return syntaxNumberColor
+	}
+	return syntaxStringColor
+}
+
+// yamlKeyEnd returns the offset of the colon after a mapping key at the
+// start of the line, or -1 if the line does not start with a key.
+func yamlKeyEnd(s string) int {
+	if s == "" {
+		return -1
+	}
+	start := 0
+	if s[0] == '"' || s[0] == '\'' {
+		start = yamlQuoteEnd(s)
+		if start == -1 {
+			return -1
+		}
+	} else if strings.IndexByte("[{#|>", s[0]) != -1 {
+		return -1
+	}
+	for i := start; i < len(s); i++ {
+		if s[i] == ':' && (i+1 == len(s) || s[i+1] == ' ') {
+			return i
+		}
+		if s[i] == ' ' && i+1 < len(s) && s[i+1] == '#' {
+			return -1
+		}
+	}
+	return -1
+}
+
+// yamlQuoteEnd returns the offset right after the closing quote of the
+// quoted string at the start of s, or -1 if it is not closed.
+func yamlQuoteEnd(s string) int {
+	quote := s[0]
+	for i := 1; i < len(s); i++ {
+		switch {
+		case quote == '"' && s[i] == '\\':
+			i++
+		case quote == '\'' && s[i] == '\'' && i+1 < len(s) && s[i+1] == '\'':
+			i++
+		case s[i] == quote:
+			return i + 1
+		}
+	}
+	return -1
+}
+
+// yamlCommentStart returns the offset of a trailing comment in the value,
+// or -1 if there is none.
+func yamlCommentStart(s string) int {
+	if strings.HasPrefix(s, "#") {
+		return 0
+	}
+	start := 0
+	if s != "" && (s[0] == '"' || s[0] == '\'') {
+		if start = yamlQuoteEnd(s); start == -1 {
+			return -1
+		}
+	}
+	if i := strings.Index(s[start:], " #"); i != -1 {
+		return start + i + 1
+	}
+	return -1
+}
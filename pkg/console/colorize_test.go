@@ -0,0 +1,89 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package console
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+var update = flag.Bool("update", false, "update the .golden files in testdata")
+
+func TestColorize(t *testing.T) {
+	var tests = []struct {
+		name     string
+		file     string
+		colorize func([]byte) []byte
+	}{
+		{name: "json", file: "colorize.json", colorize: ColorizeJSON},
+		{name: "yaml", file: "colorize.yaml", colorize: ColorizeYAML},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			setNoColor(t, false)
+			input, err := os.ReadFile(filepath.Join("testdata", tc.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := tc.colorize(input)
+
+			goldenFile := filepath.Join("testdata", tc.file+".golden")
+			if *update {
+				if err := os.WriteFile(goldenFile, got, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(goldenFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(want) != string(got) {
+				t.Errorf("want:\n%s\ngot:\n%s", want, got)
+			}
+		})
+	}
+}
+
+func TestColorizeNoColor(t *testing.T) {
+	setNoColor(t, true)
+	for _, file := range []string{"colorize.json", "colorize.yaml"} {
+		input, err := os.ReadFile(filepath.Join("testdata", file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := ColorizeJSON(input); string(got) != string(input) {
+			t.Errorf("%s: want unchanged JSON, got:\n%s", file, got)
+		}
+		if got := ColorizeYAML(input); string(got) != string(input) {
+			t.Errorf("%s: want unchanged YAML, got:\n%s", file, got)
+		}
+	}
+}
+
+// setNoColor overrides the [color.NoColor] value, which is otherwise set
+// from the NO_COLOR env var and whether STDOUT is a terminal.
+func setNoColor(t *testing.T, noColor bool) {
+	old := color.NoColor
+	color.NoColor = noColor
+	t.Cleanup(func() { color.NoColor = old })
+}
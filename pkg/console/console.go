@@ -22,6 +22,7 @@ package console
 
 import (
 	"fmt"
+	"io"
 	"strconv"
 	"strings"
 	"time"
@@ -36,19 +37,38 @@ var (
 	stdout = colorable.NewColorableStdout()
 	stderr = colorable.NewColorableStderr()
 
-	calendarMonthColor       = color.New(color.FgHiBlack, color.Italic)
-	calendarWeekdayColor     = color.New(color.FgWhite, color.Underline)
-	calendarEmptyColor       = color.New(color.Italic)
-	calendarAttendedColor    = color.New(color.FgHiYellow)
-	calendarAttendedDurColor = color.New(color.FgYellow)
-	calendarWeekSumColor     = color.New(color.FgHiBlack, color.Italic)
-	calendarAbsenceColor     = color.New(color.FgMagenta, color.Italic)
+	calendarMonthColor    = color.New(color.FgHiBlack, color.Italic)
+	calendarWeekdayColor  = color.New(color.FgWhite, color.Underline)
+	calendarEmptyColor    = color.New(color.Italic)
+	calendarUnderColor    = color.New(color.FgYellow)
+	calendarMetColor      = color.New(color.FgGreen)
+	calendarOverColor     = color.New(color.FgCyan)
+	calendarPendingColor  = color.New(color.FgBlue)
+	calendarRejectedColor = color.New(color.FgRed)
+	calendarAbsenceColor  = color.New(color.FgMagenta, color.Italic)
+	calendarWeekSumColor  = color.New(color.FgHiBlack, color.Italic)
 
 	usageHeaderColor = color.New(color.FgYellow, color.Underline, color.Italic)
 	usageHelpColor   = color.New(color.FgHiBlack, color.Italic)
 )
 
-func PrintCalendarMonth(month time.Time, cal []personio.Timecard) {
+// PrintCalendarMonth prints the attendance of a month as a calendar,
+// where each day shows the tracked work time compared to the target,
+// and each week shows the overtime balance.
+//
+// The balance is the one Personio reports for the last past day of each
+// week. Days where Personio does not report a balance instead add their
+// tracked time minus the target to the last reported balance, which for
+// a calendar without any reported balances is the sum of the shown days.
+//
+// The current time decides which days are in the future, and should be
+// in the timezone that the attendance is tracked in.
+func PrintCalendarMonth(month time.Time, cal []personio.Timecard, now time.Time) {
+	fprintCalendarMonth(stdout, month, cal, now)
+}
+
+func fprintCalendarMonth(w io.Writer, month time.Time, cal []personio.Timecard, now time.Time) {
+	today := now.Format(time.DateOnly)
 	t := Table{}
 
 	t.SetSpacing("  ")
@@ -61,52 +81,38 @@ func PrintCalendarMonth(month time.Time, cal []personio.Timecard) {
 		"Thursday",
 		"Friday",
 		"Saturday",
-		"Sunday")
-	switch month.Weekday() {
-	case time.Monday:
-	case time.Tuesday:
-		t.WriteCell("")
-	case time.Wednesday:
-		t.WriteCell("")
-		t.WriteCell("")
-	case time.Thursday:
-		t.WriteCell("")
-		t.WriteCell("")
-		t.WriteCell("")
-	case time.Friday:
-		t.WriteCell("")
-		t.WriteCell("")
-		t.WriteCell("")
-		t.WriteCell("")
-	case time.Saturday:
-		t.WriteCell("")
-		t.WriteCell("")
-		t.WriteCell("")
-		t.WriteCell("")
-		t.WriteCell("")
-	case time.Sunday:
-		t.WriteCell("")
-		t.WriteCell("")
-		t.WriteCell("")
-		t.WriteCell("")
-		t.WriteCell("")
+		"Sunday",
+		"Week",
+		"Balance")
+	// Monday is the first column, while time.Sunday is 0
+	for i := 0; i < (int(month.Weekday())+6)%7; i++ {
 		t.WriteCell("")
 	}
 
+	// The balance also includes the days before this month, so it
+	// continues from the previous month when showing multiple months
+	monthStart := month.Format(time.DateOnly)
+	var balance time.Duration
+	for _, calDay := range cal {
+		if calDay.Date < monthStart && calDay.Date <= today {
+			balance = nextBalance(balance, calDay)
+		}
+	}
+
 	day := month
 	m := day.Month()
-	var weekTime time.Duration
+	var weekTracked, weekTarget time.Duration
+	weekStart := monthStart
 	for {
 		dayStr := strconv.Itoa(day.Day())
-		if calDay, ok := findCalendarDayAttendance(day, cal); ok && !calDay.IsOffDay {
-			dur := time.Minute * time.Duration(calDay.TargetHours.ContractualWorkDurationMinutes)
-			durStr := FormatDuration(dur)
-			t.WriteCellWidth(fmt.Sprintf(
-				"%s (%s)",
-				calendarAttendedColor.Sprint(dayStr),
-				calendarAttendedDurColor.Sprint(durStr),
-			), len(dayStr)+len(durStr)+3)
-			weekTime += dur
+		if calDay, ok := findCalendarDayAttendance(day, cal); ok {
+			text, c := calendarDayCell(dayStr, calDay, today)
+			t.WriteCellColor(text, c)
+			weekTracked += calDay.TrackedDuration()
+			if calDay.Date <= today {
+				weekTarget += calDay.TargetDuration()
+				balance = nextBalance(balance, calDay)
+			}
 		} else {
 			t.WriteCellColor(dayStr, calendarEmptyColor)
 		}
@@ -115,9 +121,18 @@ func PrintCalendarMonth(month time.Time, cal []personio.Timecard) {
 			for len(t.pendingRow) < 7 {
 				t.WriteCell("")
 			}
-			t.WriteCellColor(fmt.Sprintf("∑ %s", FormatDuration(weekTime)), calendarWeekSumColor)
+			t.WriteCellColor(fmt.Sprintf("∑ %s/%s", FormatDuration(weekTracked), FormatDuration(weekTarget)), calendarWeekSumColor)
+			switch {
+			case weekStart > today:
+				// No balance to show for future weeks
+			case balance < 0:
+				t.WriteCellColor(formatBalance(balance), calendarUnderColor)
+			default:
+				t.WriteCellColor(formatBalance(balance), calendarMetColor)
+			}
 			t.CommitRow()
-			weekTime = 0
+			weekTracked, weekTarget = 0, 0
+			weekStart = nextDay.Format(time.DateOnly)
 		}
 		if nextDay.Month() != m {
 			break
@@ -125,10 +140,68 @@ func PrintCalendarMonth(month time.Time, cal []personio.Timecard) {
 		day = nextDay
 	}
 
-	w := t.Width()
+	width := t.Width()
 	monthStr := month.Month().String()
-	calendarMonthColor.Printf("%s=== %s ===\n", strings.Repeat(" ", typ.Max(0, w/2-len(monthStr)/2-4)), monthStr)
-	t.Fprintln(stdout)
+	calendarMonthColor.Fprintf(w, "%s=== %s ===\n", strings.Repeat(" ", typ.Max(0, width/2-len(monthStr)/2-4)), monthStr)
+	t.Fprintln(w)
+	if !color.NoColor {
+		fmt.Fprintf(w, "  %s  %s  %s  %s  %s  %s\n",
+			calendarUnderColor.Sprint("under target"),
+			calendarMetColor.Sprint("on target"),
+			calendarOverColor.Sprint("over target"),
+			calendarPendingColor.Sprint("pending approval"),
+			calendarRejectedColor.Sprint("rejected"),
+			calendarAbsenceColor.Sprint("absence"))
+	}
+}
+
+// calendarDayCell returns the text and color of a day in the calendar,
+// e.g "18 7:30/8:00", where the days that have not happened yet are not
+// compared to their target.
+func calendarDayCell(dayStr string, calDay personio.Timecard, today string) (string, *color.Color) {
+	tracked := calDay.TrackedDuration()
+	target := calDay.TargetDuration()
+	text := fmt.Sprintf("%s %s/%s", dayStr, FormatDuration(tracked), FormatDuration(target))
+	switch {
+	case calDay.ApprovalStatus() == personio.ApprovalStatusRejected:
+		return text, calendarRejectedColor
+	case calDay.ApprovalStatus() == personio.ApprovalStatusPending:
+		return text, calendarPendingColor
+	case calDay.HasTimeOff() && tracked == 0:
+		if target == 0 {
+			return dayStr + " off", calendarAbsenceColor
+		}
+		return text, calendarAbsenceColor
+	case tracked == 0 && target == 0:
+		return dayStr, calendarEmptyColor
+	case calDay.Date > today:
+		if tracked == 0 {
+			return text, calendarEmptyColor
+		}
+		return text, calendarMetColor
+	case tracked < target:
+		return text, calendarUnderColor
+	case tracked > target:
+		return text, calendarOverColor
+	default:
+		return text, calendarMetColor
+	}
+}
+
+// nextBalance returns the overtime balance after the given day, preferring
+// the balance from Personio over adding up the days locally.
+func nextBalance(balance time.Duration, calDay personio.Timecard) time.Duration {
+	if total, ok := calDay.OvertimeBalance(); ok {
+		return total
+	}
+	return balance + calDay.OvertimeDuration()
+}
+
+func formatBalance(d time.Duration) string {
+	if d < 0 {
+		return FormatDuration(d)
+	}
+	return "+" + FormatDuration(d)
 }
 
 // UsageTemplate returns a lightly colored usage template for Cobra.
@@ -0,0 +1,327 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package console
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/journal"
+	"github.com/applejag/rootless-personio/pkg/personio"
+)
+
+func TestPrintCalendarMonth(t *testing.T) {
+	setNoColor(t, true)
+
+	workDay := func(date string, target, tracked time.Duration) personio.Timecard {
+		start, err := time.Parse(time.DateTime, date+" 08:00:00")
+		if err != nil {
+			t.Fatal(err)
+		}
+		card := personio.Timecard{Date: date}
+		card.TargetHours.EffectiveWorkDurationMinutes = int(target.Minutes())
+		if tracked > 0 {
+			card.Periods = []personio.Period{{
+				Type:  personio.PeriodTypeWork,
+				Start: personio.PersonioTime{Time: start},
+				End:   personio.PersonioTime{Time: start.Add(tracked)},
+			}}
+		}
+		return card
+	}
+	vacation := workDay("2023-02-03", 0, 0)
+	vacation.TimeOff = &personio.TimeOff{AggregatedDurationMinutes: 8 * 60}
+
+	cal := []personio.Timecard{
+		// Before the month, but still part of the balance
+		workDay("2023-01-31", 8*time.Hour, 9*time.Hour),
+		workDay("2023-02-01", 8*time.Hour, 8*time.Hour),
+		workDay("2023-02-02", 8*time.Hour, 6*time.Hour),
+		vacation,
+		workDay("2023-02-04", 0, 0),
+		workDay("2023-02-05", 0, 0),
+		workDay("2023-02-06", 8*time.Hour, 8*time.Hour+30*time.Minute),
+		// After "now", so not part of the balance
+		workDay("2023-02-07", 8*time.Hour, 0),
+	}
+	now := time.Date(2023, 2, 6, 18, 0, 0, 0, time.UTC)
+
+	var sb strings.Builder
+	fprintCalendarMonth(&sb, time.Date(2023, 2, 1, 0, 0, 0, 0, time.UTC), cal, now)
+	lines := strings.Split(sb.String(), "\n")
+	if len(lines) < 4 {
+		t.Fatalf("want at least 4 lines, got:\n%s", sb.String())
+	}
+	if !strings.Contains(lines[0], "=== February ===") {
+		t.Errorf("want month header, got %q", lines[0])
+	}
+
+	var tests = []struct {
+		line int
+		want []string
+	}{
+		{
+			line: 2,
+			want: []string{"1 8:00/8:00", "2 6:00/8:00", "3 off", "∑ 14:00/16:00", "-1:00"},
+		},
+		{
+			line: 3,
+			want: []string{"6 8:30/8:00", "7 0:00/8:00", "∑ 8:30/8:00", "-0:30"},
+		},
+	}
+	if strings.Contains(lines[4], "-0:30") {
+		t.Errorf("want no balance for future weeks, got %q", lines[4])
+	}
+	for _, tc := range tests {
+		for _, want := range tc.want {
+			if !strings.Contains(lines[tc.line], want) {
+				t.Errorf("line %d: want %q, got %q", tc.line, want, lines[tc.line])
+			}
+		}
+	}
+}
+
+func TestPrintCalendarMonthPersonioBalance(t *testing.T) {
+	setNoColor(t, true)
+
+	workDay := func(date string, target, tracked time.Duration, total *int) personio.Timecard {
+		start, err := time.Parse(time.DateTime, date+" 08:00:00")
+		if err != nil {
+			t.Fatal(err)
+		}
+		card := personio.Timecard{Date: date}
+		card.TargetHours.EffectiveWorkDurationMinutes = int(target.Minutes())
+		card.Periods = []personio.Period{{
+			Type:  personio.PeriodTypeWork,
+			Start: personio.PersonioTime{Time: start},
+			End:   personio.PersonioTime{Time: start.Add(tracked)},
+		}}
+		if total != nil {
+			card.Overtime = &personio.Overtime{TotalOvertimeMinutes: total}
+		}
+		return card
+	}
+	minutes := func(m int) *int { return &m }
+
+	cal := []personio.Timecard{
+		// Personio's balance includes days that were never fetched
+		workDay("2023-01-31", 8*time.Hour, 9*time.Hour, minutes(10*60)),
+		workDay("2023-02-01", 8*time.Hour, 8*time.Hour, minutes(10*60)),
+		workDay("2023-02-02", 8*time.Hour, 7*time.Hour, minutes(9*60)),
+		workDay("2023-02-06", 8*time.Hour, 9*time.Hour, minutes(10*60)),
+		// No balance from Personio, so continues from the last one
+		workDay("2023-02-07", 8*time.Hour, 8*time.Hour+30*time.Minute, nil),
+	}
+	now := time.Date(2023, 2, 7, 18, 0, 0, 0, time.UTC)
+
+	var sb strings.Builder
+	fprintCalendarMonth(&sb, time.Date(2023, 2, 1, 0, 0, 0, 0, time.UTC), cal, now)
+	lines := strings.Split(sb.String(), "\n")
+	if len(lines) < 4 {
+		t.Fatalf("want at least 4 lines, got:\n%s", sb.String())
+	}
+	if !strings.HasSuffix(strings.TrimSpace(lines[2]), "+9:00") {
+		t.Errorf("line 2: want balance +9:00, got %q", lines[2])
+	}
+	if !strings.HasSuffix(strings.TrimSpace(lines[3]), "+10:30") {
+		t.Errorf("line 3: want balance +10:30, got %q", lines[3])
+	}
+}
+
+func TestPrintDaySummaries(t *testing.T) {
+	setNoColor(t, true)
+
+	start := time.Date(2023, 1, 18, 8, 0, 0, 0, time.UTC)
+	projectID := 1
+	days := []personio.DaySummary{
+		{
+			Date:           "2023-01-18",
+			ApprovalStatus: personio.ApprovalStatusPending,
+			Periods: []personio.PeriodSummary{
+				{
+					Start:           personio.PersonioTime{Time: start},
+					End:             personio.PersonioTime{Time: start.Add(4 * time.Hour)},
+					Type:            personio.PeriodTypeWork,
+					ProjectID:       &projectID,
+					Project:         "Project X",
+					Comment:         "Before lunch",
+					DurationMinutes: 4 * 60,
+				},
+				{
+					Start:           personio.PersonioTime{Time: start.Add(4 * time.Hour)},
+					End:             personio.PersonioTime{Time: start.Add(5 * time.Hour)},
+					Type:            personio.PeriodTypeBreak,
+					DurationMinutes: 60,
+				},
+				{
+					Start:           personio.PersonioTime{Time: start.Add(15 * time.Hour)},
+					End:             personio.PersonioTime{Time: start.Add(17*time.Hour + 30*time.Minute)},
+					Type:            personio.PeriodTypeWork,
+					DurationMinutes: 2*60 + 30,
+				},
+			},
+			Projects: []personio.ProjectSummary{
+				{ProjectID: &projectID, Project: "Project X", TrackedMinutes: 4 * 60},
+				{TrackedMinutes: 2*60 + 30},
+			},
+			TrackedMinutes:    6*60 + 30,
+			BreakMinutes:      60,
+			TargetMinutes:     8 * 60,
+			DifferenceMinutes: -90,
+		},
+		{
+			Date:     "2023-01-21",
+			IsOffDay: true,
+		},
+	}
+
+	var sb strings.Builder
+	fprintDaySummaries(&sb, days)
+	lines := strings.Split(sb.String(), "\n")
+
+	var tests = []struct {
+		line int
+		want []string
+	}{
+		{line: 0, want: []string{"Wednesday 2023-01-18", "pending approval"}},
+		{line: 1, want: []string{"#1", "08:00–12:00", "work", "4:00", "Project X", "Before lunch"}},
+		{line: 2, want: []string{"12:00–13:00", "break", "1:00"}},
+		{line: 3, want: []string{"#3", "23:00–01:30+1", "work", "2:30"}},
+		{line: 4, want: []string{"Project X", "4:00"}},
+		{line: 5, want: []string{"No project", "2:30"}},
+		{line: 6, want: []string{"Breaks", "1:00"}},
+		{line: 7, want: []string{"Total", "6:30/8:00", "-1:30"}},
+		{line: 9, want: []string{"Saturday 2023-01-21", "off day"}},
+		{line: 10, want: []string{"No attendance"}},
+	}
+	if len(lines) <= 10 {
+		t.Fatalf("want at least 11 lines, got:\n%s", sb.String())
+	}
+	for _, tc := range tests {
+		for _, want := range tc.want {
+			if !strings.Contains(lines[tc.line], want) {
+				t.Errorf("line %d: want %q, got %q", tc.line, want, lines[tc.line])
+			}
+		}
+	}
+}
+
+func TestWritePlan(t *testing.T) {
+	setNoColor(t, true)
+
+	start := time.Date(2023, 1, 18, 8, 0, 0, 0, time.UTC)
+	projectID := 1
+	oldPeriod := personio.Period{
+		Start: personio.PersonioTime{Time: start},
+		End:   personio.PersonioTime{Time: start.Add(4 * time.Hour)},
+		Type:  personio.PeriodTypeWork,
+	}
+	newPeriod := oldPeriod
+	newPeriod.End = personio.PersonioTime{Time: start.Add(5 * time.Hour)}
+	newPeriod.ProjectID = &projectID
+	plans := []personio.DayPlan{
+		{
+			Date: "2023-01-18",
+			Changes: []personio.PeriodChange{
+				{Change: personio.ChangeModify, Old: &oldPeriod, New: &newPeriod},
+			},
+		},
+		{Date: "2023-01-19"},
+		{Date: "2023-01-20", DeleteDay: true, Changes: []personio.PeriodChange{
+			{Change: personio.ChangeRemove, Old: &oldPeriod},
+		}},
+	}
+
+	var sb strings.Builder
+	WritePlan(&sb, plans, map[int]string{projectID: "Project X"})
+	lines := strings.Split(sb.String(), "\n")
+
+	var tests = []struct {
+		line int
+		want []string
+	}{
+		{line: 0, want: []string{"2023-01-18"}},
+		{line: 1, want: []string{"-", "08:00–12:00", "work", "4:00"}},
+		{line: 2, want: []string{"+", "08:00–13:00", "work", "5:00", "Project X"}},
+		{line: 3, want: []string{"2023-01-20", "delete day"}},
+		{line: 4, want: []string{"-", "08:00–12:00"}},
+		{line: 5, want: []string{"2 days changed", "0 added", "1 modified", "1 removed"}},
+	}
+	if len(lines) <= 5 {
+		t.Fatalf("want at least 6 lines, got:\n%s", sb.String())
+	}
+	for _, tc := range tests {
+		for _, want := range tc.want {
+			if !strings.Contains(lines[tc.line], want) {
+				t.Errorf("line %d: want %q, got %q", tc.line, want, lines[tc.line])
+			}
+		}
+	}
+
+	sb.Reset()
+	WritePlan(&sb, []personio.DayPlan{{Date: "2023-01-19"}}, nil)
+	if got := sb.String(); got != "No changes\n" {
+		t.Errorf("want %q, got %q", "No changes\n", got)
+	}
+}
+
+func TestPrintJournal(t *testing.T) {
+	setNoColor(t, true)
+
+	entries := []journal.Entry{
+		{ID: 1, Command: "attendance add", Days: []journal.Day{{Date: "2023-01-18"}}},
+		{ID: 2, Command: "attendance set", Days: []journal.Day{
+			{Date: "2023-01-20"}, {Date: "2023-01-18"}, {Date: "2023-01-19"},
+		}},
+		{ID: 3, Command: "attendance undo", UndoOf: []int{2}, Days: []journal.Day{{Date: "2023-01-18"}}},
+	}
+
+	var sb strings.Builder
+	fprintJournal(&sb, entries)
+	lines := strings.Split(sb.String(), "\n")
+
+	var tests = []struct {
+		line int
+		want []string
+	}{
+		{line: 0, want: []string{"#", "Time", "Command", "Days"}},
+		{line: 1, want: []string{"#3", "attendance undo", "2023-01-18", "undo of #2"}},
+		{line: 2, want: []string{"#2", "attendance set", "2023-01-18..2023-01-20 (3 days)", "undone by #3"}},
+		{line: 3, want: []string{"#1", "attendance add", "2023-01-18"}},
+	}
+	if len(lines) <= 3 {
+		t.Fatalf("want at least 4 lines, got:\n%s", sb.String())
+	}
+	for _, tc := range tests {
+		for _, want := range tc.want {
+			if !strings.Contains(lines[tc.line], want) {
+				t.Errorf("line %d: want %q, got %q", tc.line, want, lines[tc.line])
+			}
+		}
+	}
+	if strings.Contains(lines[3], "undone") {
+		t.Errorf("line 3: want not undone, got %q", lines[3])
+	}
+
+	sb.Reset()
+	fprintJournal(&sb, nil)
+	if got := sb.String(); got != "No changes recorded\n" {
+		t.Errorf("want %q, got %q", "No changes recorded\n", got)
+	}
+}
@@ -0,0 +1,253 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package console
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// WriteCSV writes the model as CSV with a header line, using the comma as
+// the separator, such as ',' for CSV and '\t' for TSV.
+//
+// The model is encoded as JSON first, so the column names are the JSON
+// field names. Nested objects are flattened into columns with
+// dot-separated names, like "target_hours.start_time", and lists of
+// objects are flattened into one row per item, where the fields of the
+// parent object are repeated on each row. Lists of plain values are
+// joined with commas.
+func WriteCSV(w io.Writer, model any, comma rune) error {
+	b, err := json.Marshal(model)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	value, err := decodeOrderedJSON(dec)
+	if err != nil {
+		return err
+	}
+	var rows []csvRow
+	if list, ok := value.([]any); ok {
+		// Plain values in the top-level list also get one row each
+		for _, v := range list {
+			rows = append(rows, flattenRows(v, "")...)
+		}
+	} else {
+		rows = flattenRows(value, "")
+	}
+
+	var header []string
+	for _, row := range rows {
+		// Keep new columns next to the columns they were found together with,
+		// or else add them last
+		next := len(header)
+		for _, cell := range row {
+			if i := slices.Index(header, cell.key); i != -1 {
+				next = i
+				break
+			}
+		}
+		for _, cell := range row {
+			if i := slices.Index(header, cell.key); i != -1 {
+				next = i + 1
+				continue
+			}
+			header = slices.Insert(header, next, cell.key)
+			next++
+		}
+	}
+
+	cw := csv.NewWriter(w)
+	cw.Comma = comma
+	if len(header) > 0 {
+		if err := cw.Write(header); err != nil {
+			return err
+		}
+	}
+	record := make([]string, len(header))
+	for _, row := range rows {
+		clear(record)
+		for _, cell := range row {
+			record[slices.Index(header, cell.key)] = cell.value
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+type csvCell struct {
+	key   string
+	value string
+}
+
+type csvRow []csvCell
+
+// orderedObject is a JSON object that keeps the order of its fields,
+// which are in the order of the struct fields they were encoded from.
+type orderedObject []orderedField
+
+type orderedField struct {
+	key   string
+	value any
+}
+
+func decodeOrderedJSON(dec *json.Decoder) (any, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch tok {
+	case json.Delim('{'):
+		var obj orderedObject
+		for dec.More() {
+			keyTok, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			key, ok := keyTok.(string)
+			if !ok {
+				return nil, fmt.Errorf("want object key, got %v", keyTok)
+			}
+			value, err := decodeOrderedJSON(dec)
+			if err != nil {
+				return nil, err
+			}
+			obj = append(obj, orderedField{key: key, value: value})
+		}
+		_, err := dec.Token() // closing brace
+		return obj, err
+	case json.Delim('['):
+		arr := []any{}
+		for dec.More() {
+			value, err := decodeOrderedJSON(dec)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, value)
+		}
+		_, err := dec.Token() // closing bracket
+		return arr, err
+	case json.Delim('}'), json.Delim(']'):
+		return nil, errors.New("unexpected end of JSON object or array")
+	}
+	return tok, nil
+}
+
+// flattenRows returns the rows of a decoded JSON value.
+func flattenRows(value any, prefix string) []csvRow {
+	switch value := value.(type) {
+	case orderedObject:
+		fieldRows := make([][]csvRow, len(value))
+		var lists []int
+		for i, field := range value {
+			fieldRows[i] = flattenRows(field.value, joinColumn(prefix, field.key))
+			if len(fieldRows[i]) > 1 {
+				lists = append(lists, i)
+			}
+		}
+		if len(lists) == 0 {
+			var row csvRow
+			for _, rows := range fieldRows {
+				for _, r := range rows {
+					row = append(row, r...)
+				}
+			}
+			return []csvRow{row}
+		}
+		// One row per list item, where the other fields are repeated.
+		// Multiple lists are written after each other, instead of
+		// writing all combinations of their items.
+		var rows []csvRow
+		for _, list := range lists {
+			for _, listRow := range fieldRows[list] {
+				var row csvRow
+				for i, fieldRow := range fieldRows {
+					switch {
+					case i == list:
+						row = append(row, listRow...)
+					case len(fieldRow) == 1:
+						row = append(row, fieldRow[0]...)
+					}
+				}
+				rows = append(rows, row)
+			}
+		}
+		return rows
+	case []any:
+		if !containsCollection(value) {
+			if len(value) == 0 {
+				return nil
+			}
+			values := make([]string, len(value))
+			for i, v := range value {
+				values[i] = csvScalar(v)
+			}
+			return []csvRow{{{key: columnName(prefix), value: strings.Join(values, ",")}}}
+		}
+		var rows []csvRow
+		for _, v := range value {
+			rows = append(rows, flattenRows(v, prefix)...)
+		}
+		return rows
+	default:
+		return []csvRow{{{key: columnName(prefix), value: csvScalar(value)}}}
+	}
+}
+
+func containsCollection(values []any) bool {
+	for _, v := range values {
+		switch v.(type) {
+		case orderedObject, []any:
+			return true
+		}
+	}
+	return false
+}
+
+func csvScalar(value any) string {
+	if value == nil {
+		return ""
+	}
+	return fmt.Sprint(value)
+}
+
+func joinColumn(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+// columnName returns the name of the column, where plain values that are
+// not inside an object get the name "value".
+func columnName(prefix string) string {
+	if prefix == "" {
+		return "value"
+	}
+	return prefix
+}
@@ -0,0 +1,169 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package console
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/personio"
+)
+
+func TestWriteCSV(t *testing.T) {
+	type item struct {
+		Name  string   `json:"name"`
+		Tags  []string `json:"tags"`
+		Inner struct {
+			Value *int `json:"value"`
+		} `json:"inner"`
+	}
+	one := 1
+	withValue := item{Name: "b", Tags: []string{"x", "y"}}
+	withValue.Inner.Value = &one
+
+	var tests = []struct {
+		name  string
+		model any
+		comma rune
+		want  string
+	}{
+		{
+			name:  "list of structs",
+			model: []item{{Name: "a"}, withValue},
+			comma: ',',
+			want: `name,tags,inner.value
+a,,
+b,"x,y",1
+`,
+		},
+		{
+			name:  "tabs",
+			model: []item{withValue},
+			comma: '\t',
+			want:  "name\ttags\tinner.value\nb\tx,y\t1\n",
+		},
+		{
+			name:  "plain values",
+			model: []string{"a", "b"},
+			comma: ',',
+			want:  "value\na\nb\n",
+		},
+		{
+			name: "multiple lists",
+			model: map[string]any{
+				"a": []map[string]int{{"x": 1}, {"x": 2}},
+				"b": []map[string]int{{"y": 3}, {"y": 4}},
+			},
+			comma: ',',
+			want: `a.x,b.y
+1,
+2,
+,3
+,4
+`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var sb strings.Builder
+			if err := WriteCSV(&sb, tc.model, tc.comma); err != nil {
+				t.Fatal(err)
+			}
+			if sb.String() != tc.want {
+				t.Errorf("want:\n%s\ngot:\n%s", tc.want, sb.String())
+			}
+		})
+	}
+}
+
+func TestWriteCSVTimecards(t *testing.T) {
+	projectID := 12
+	start := time.Date(2023, 1, 18, 8, 0, 0, 0, time.UTC)
+	cards := []personio.Timecard{
+		{
+			Date:  "2023-01-18",
+			State: "trackable",
+			Periods: []personio.Period{
+				{
+					Start:     personio.PersonioTime{Time: start},
+					End:       personio.PersonioTime{Time: start.Add(4 * time.Hour)},
+					ProjectID: &projectID,
+					Type:      personio.PeriodTypeWork,
+				},
+				{
+					Start: personio.PersonioTime{Time: start.Add(4 * time.Hour)},
+					End:   personio.PersonioTime{Time: start.Add(5 * time.Hour)},
+					Type:  personio.PeriodTypeBreak,
+				},
+			},
+		},
+		{
+			Date:     "2023-01-21",
+			State:    "empty",
+			IsOffDay: true,
+			Periods:  []personio.Period{},
+		},
+	}
+
+	var sb strings.Builder
+	if err := WriteCSV(&sb, cards, ','); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(sb.String(), "\n"), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("want header and 3 rows, got %d lines:\n%s", len(lines), sb.String())
+	}
+
+	header := strings.Split(lines[0], ",")
+	for _, column := range []string{"date", "state", "periods.start", "periods.end", "periods.project_id", "periods.type", "target_hours.start_time"} {
+		if !strings.Contains(lines[0], column) {
+			t.Errorf("want column %q in header, got %v", column, header)
+		}
+	}
+	column := func(line, name string) string {
+		values := strings.Split(line, ",")
+		for i, h := range header {
+			if h == name && i < len(values) {
+				return values[i]
+			}
+		}
+		return "<missing>"
+	}
+	var tests = []struct {
+		line   int
+		column string
+		want   string
+	}{
+		{line: 1, column: "date", want: "2023-01-18"},
+		{line: 1, column: "periods.start", want: "2023-01-18T08:00:00"},
+		{line: 1, column: "periods.project_id", want: "12"},
+		{line: 2, column: "date", want: "2023-01-18"},
+		{line: 2, column: "periods.type", want: "break"},
+		{line: 2, column: "periods.project_id", want: ""},
+		{line: 3, column: "date", want: "2023-01-21"},
+		{line: 3, column: "is_off_day", want: "true"},
+		{line: 3, column: "periods.start", want: ""},
+	}
+	for _, tc := range tests {
+		if got := column(lines[tc.line], tc.column); got != tc.want {
+			t.Errorf("row %d, column %s: want %q, got %q", tc.line, tc.column, tc.want, got)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package console
+
+import (
+	"github.com/applejag/rootless-personio/pkg/personio"
+	"github.com/fatih/color"
+)
+
+var (
+	diagnosticNameColor    = color.New(color.FgWhite, color.Bold)
+	diagnosticOKColor      = color.New(color.FgGreen)
+	diagnosticWarningColor = color.New(color.FgYellow)
+	diagnosticFailedColor  = color.New(color.FgRed)
+	diagnosticSkippedColor = color.New(color.FgHiBlack, color.Italic)
+)
+
+// PrintDiagnosis prints each step of the diagnosis on its own line,
+// prefixed by a symbol for its status.
+func PrintDiagnosis(d *personio.Diagnosis) {
+	t := Table{}
+	t.SetSpacing("  ")
+	t.SetPrefix("  ")
+
+	for _, step := range d.Steps {
+		symbol, c := diagnosticSymbol(step.Status)
+		t.WriteCellColor(symbol, c)
+		t.WriteCellColor(step.Name, diagnosticNameColor)
+		t.WriteCellColor(step.Message, c)
+		t.CommitRow()
+	}
+	t.Fprintln(stdout)
+}
+
+func diagnosticSymbol(status personio.DiagnosticStatus) (string, *color.Color) {
+	switch status {
+	case personio.DiagnosticOK:
+		return "✓", diagnosticOKColor
+	case personio.DiagnosticWarning:
+		return "!", diagnosticWarningColor
+	case personio.DiagnosticFailed:
+		return "✗", diagnosticFailedColor
+	default:
+		return "-", diagnosticSkippedColor
+	}
+}
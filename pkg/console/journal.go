@@ -0,0 +1,99 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package console
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/applejag/rootless-personio/pkg/journal"
+	"github.com/fatih/color"
+)
+
+var (
+	journalUndoneColor = color.New(color.FgHiBlack)
+)
+
+// PrintJournal prints the journal entries, newest first, with the days each
+// entry changed and whether it was undone.
+func PrintJournal(entries []journal.Entry) {
+	fprintJournal(stdout, entries)
+}
+
+func fprintJournal(w io.Writer, entries []journal.Entry) {
+	if len(entries) == 0 {
+		calendarEmptyColor.Fprintln(w, "No changes recorded")
+		return
+	}
+	undoneBy := map[int]int{}
+	for _, entry := range entries {
+		for _, id := range entry.UndoOf {
+			undoneBy[id] = entry.ID
+		}
+	}
+
+	t := Table{}
+	t.SetSpacing("  ")
+	t.WriteColoredRow(summaryTotalColor, "#", "Time", "Command", "Days", "")
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+		c := color.New()
+		status := ""
+		switch {
+		case entry.IsUndo():
+			ids := make([]string, 0, len(entry.UndoOf))
+			for _, id := range entry.UndoOf {
+				ids = append(ids, fmt.Sprintf("#%d", id))
+			}
+			status = "undo of " + strings.Join(ids, ", ")
+		case undoneBy[entry.ID] != 0:
+			c = journalUndoneColor
+			status = fmt.Sprintf("undone by #%d", undoneBy[entry.ID])
+		}
+		t.WriteCellColor(fmt.Sprintf("#%d", entry.ID), summaryPositionColor)
+		t.WriteCellColor(entry.Time.Format("2006-01-02 15:04"), c)
+		t.WriteCellColor(entry.Command, c)
+		t.WriteCellColor(journalDays(entry.Days), c)
+		t.WriteCellColor(status, c)
+		t.CommitRow()
+	}
+	t.Fprintln(w)
+}
+
+// journalDays returns the dates of the days, shortened to the first and
+// last date when there are many.
+func journalDays(days []journal.Day) string {
+	switch len(days) {
+	case 0:
+		return ""
+	case 1, 2:
+		dates := make([]string, 0, len(days))
+		for _, day := range days {
+			dates = append(dates, day.Date)
+		}
+		return strings.Join(dates, ", ")
+	default:
+		first, last := days[0].Date, days[0].Date
+		for _, day := range days[1:] {
+			first = min(first, day.Date)
+			last = max(last, day.Date)
+		}
+		return fmt.Sprintf("%s..%s (%d days)", first, last, len(days))
+	}
+}
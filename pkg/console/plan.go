@@ -0,0 +1,111 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package console
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/personio"
+	"github.com/fatih/color"
+)
+
+var (
+	planAddColor    = color.New(color.FgGreen)
+	planRemoveColor = color.New(color.FgRed)
+)
+
+// PrintPlan calls [WritePlan] on STDOUT.
+func PrintPlan(plans []personio.DayPlan, projectNames map[int]string) {
+	WritePlan(stdout, plans, projectNames)
+}
+
+// WritePlan writes the planned changes of each day as a colored diff,
+// where removed periods are prefixed with "-" and added periods with "+".
+// Modified periods are shown as the old period removed and the new one
+// added. The project names are looked up in the given map, by project ID.
+func WritePlan(w io.Writer, plans []personio.DayPlan, projectNames map[int]string) {
+	var added, removed, modified, days int
+	for _, plan := range plans {
+		if !plan.HasChanges() {
+			continue
+		}
+		days++
+		summaryDayColor.Fprint(w, plan.Date)
+		if plan.DeleteDay {
+			fmt.Fprint(w, "  ")
+			planRemoveColor.Fprint(w, "delete day")
+		}
+		fmt.Fprintln(w)
+
+		t := Table{}
+		t.SetSpacing("  ")
+		t.SetPrefix("  ")
+		for _, change := range plan.Changes {
+			switch change.Change {
+			case personio.ChangeAdd:
+				added++
+			case personio.ChangeRemove:
+				removed++
+			case personio.ChangeModify:
+				modified++
+			}
+			if change.Old != nil {
+				writePlanPeriod(&t, "-", *change.Old, projectNames, planRemoveColor)
+			}
+			if change.New != nil {
+				writePlanPeriod(&t, "+", *change.New, projectNames, planAddColor)
+			}
+		}
+		t.Fprintln(w)
+	}
+	if days == 0 {
+		calendarEmptyColor.Fprintln(w, "No changes")
+		return
+	}
+	fmt.Fprintf(w, "%d %s changed: %s, %s, %s\n",
+		days, pluralize(days, "day", "days"),
+		planAddColor.Sprintf("%d added", added),
+		calendarUnderColor.Sprintf("%d modified", modified),
+		planRemoveColor.Sprintf("%d removed", removed))
+}
+
+func writePlanPeriod(t *Table, prefix string, p personio.Period, projectNames map[int]string, c *color.Color) {
+	project := ""
+	if p.ProjectID != nil {
+		project = projectNames[*p.ProjectID]
+		if project == "" {
+			project = fmt.Sprintf("project %d", *p.ProjectID)
+		}
+	}
+	t.WriteCellColor(prefix, c)
+	t.WriteCellColor(fmt.Sprintf("%s–%s", p.Start.Format("15:04"), p.End.Format("15:04")), c)
+	t.WriteCellColor(string(p.Type), c)
+	t.WriteCellColor(FormatDuration(p.End.Sub(p.Start.Time).Truncate(time.Minute)), c)
+	t.WriteCellColor(project, c)
+	t.WriteCellColor(p.GetComment(), c)
+	t.CommitRow()
+}
+
+func pluralize(n int, singular, plural string) string {
+	if n == 1 {
+		return singular
+	}
+	return plural
+}
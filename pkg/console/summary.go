@@ -0,0 +1,165 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package console
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/personio"
+	"github.com/fatih/color"
+)
+
+var (
+	summaryDayColor      = color.New(color.FgWhite, color.Bold)
+	summaryApprovedColor = color.New(color.FgGreen)
+	summaryBreakColor    = color.New(color.FgHiBlack)
+	summaryTotalColor    = color.New(color.FgWhite, color.Underline)
+	summaryPositionColor = color.New(color.FgHiBlack)
+)
+
+// PrintDaySummaries prints each day's attendance periods, followed by the
+// tracked work time per project, the breaks, and the difference between
+// the tracked and target work time.
+func PrintDaySummaries(days []personio.DaySummary) {
+	fprintDaySummaries(stdout, days)
+}
+
+func fprintDaySummaries(w io.Writer, days []personio.DaySummary) {
+	for i, day := range days {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fprintDaySummary(w, day)
+	}
+}
+
+func fprintDaySummary(w io.Writer, day personio.DaySummary) {
+	header := day.Date
+	if date, err := time.Parse(time.DateOnly, day.Date); err == nil {
+		header = date.Format("Monday 2006-01-02")
+	}
+	summaryDayColor.Fprint(w, header)
+	if text, c := summaryDayStatus(day); text != "" {
+		fmt.Fprint(w, "  ")
+		c.Fprint(w, text)
+	}
+	fmt.Fprintln(w)
+
+	if len(day.Periods) == 0 {
+		calendarEmptyColor.Fprintln(w, "  No attendance")
+		if day.TargetMinutes == 0 {
+			return
+		}
+	}
+
+	periods := Table{}
+	periods.SetSpacing("  ")
+	periods.SetPrefix("  ")
+	for i, p := range day.Periods {
+		c := color.New()
+		if p.Type == personio.PeriodTypeBreak {
+			c = summaryBreakColor
+		}
+		// The position is used to reference the period in other commands
+		periods.WriteCellColor(fmt.Sprintf("#%d", i+1), summaryPositionColor)
+		periods.WriteCellColor(fmt.Sprintf("%s–%s", p.Start.Format("15:04"), formatPeriodEnd(p)), c)
+		periods.WriteCellColor(string(p.Type), c)
+		periods.WriteCellColor(FormatDuration(minutesDuration(p.DurationMinutes)), c)
+		periods.WriteCellColor(p.Project, c)
+		periods.WriteCellColor(p.Comment, c)
+		periods.CommitRow()
+	}
+	periods.Fprintln(w)
+
+	totals := Table{}
+	totals.SetSpacing("  ")
+	totals.SetPrefix("  ")
+	for _, p := range day.Projects {
+		name := p.Project
+		if name == "" {
+			name = "No project"
+		}
+		totals.WriteCell(name)
+		totals.WriteCell(FormatDuration(minutesDuration(p.TrackedMinutes)))
+		totals.CommitRow()
+	}
+	if day.BreakMinutes > 0 {
+		totals.WriteCellColor("Breaks", summaryBreakColor)
+		totals.WriteCellColor(FormatDuration(minutesDuration(day.BreakMinutes)), summaryBreakColor)
+		totals.CommitRow()
+	}
+	if day.TimeOffMinutes > 0 {
+		totals.WriteCellColor("Time off", calendarAbsenceColor)
+		totals.WriteCellColor(FormatDuration(minutesDuration(day.TimeOffMinutes)), calendarAbsenceColor)
+		totals.CommitRow()
+	}
+	totals.WriteCellColor("Total", summaryTotalColor)
+	totals.WriteCell(fmt.Sprintf("%s/%s",
+		FormatDuration(minutesDuration(day.TrackedMinutes)),
+		FormatDuration(minutesDuration(day.TargetMinutes))))
+	diff := minutesDuration(day.DifferenceMinutes)
+	switch {
+	case diff < 0:
+		totals.WriteCellColor(formatBalance(diff), calendarUnderColor)
+	case diff > 0:
+		totals.WriteCellColor(formatBalance(diff), calendarOverColor)
+	default:
+		totals.WriteCellColor(formatBalance(diff), calendarMetColor)
+	}
+	totals.CommitRow()
+	totals.Fprintln(w)
+}
+
+// summaryDayStatus returns the approval status of the day, or "off day"
+// for days without attendance or target.
+func summaryDayStatus(day personio.DaySummary) (string, *color.Color) {
+	switch day.ApprovalStatus {
+	case personio.ApprovalStatusApproved:
+		return day.ApprovalStatus, summaryApprovedColor
+	case personio.ApprovalStatusPending:
+		return "pending approval", calendarPendingColor
+	case personio.ApprovalStatusRejected:
+		return day.ApprovalStatus, calendarRejectedColor
+	case "":
+		if day.IsOffDay {
+			return "off day", calendarEmptyColor
+		}
+		return "", nil
+	default:
+		return day.ApprovalStatus, calendarEmptyColor
+	}
+}
+
+// formatPeriodEnd returns the end time of the period, with the number
+// of days added when it ends on a later day than it started, e.g "01:30+1".
+func formatPeriodEnd(p personio.PeriodSummary) string {
+	end := p.End.Format("15:04")
+	startYear, startMonth, startDay := p.Start.Date()
+	days := int(time.Date(p.End.Year(), p.End.Month(), p.End.Day(), 0, 0, 0, 0, time.UTC).
+		Sub(time.Date(startYear, startMonth, startDay, 0, 0, 0, 0, time.UTC)).Hours() / 24)
+	if days > 0 {
+		return fmt.Sprintf("%s+%d", end, days)
+	}
+	return end
+}
+
+func minutesDuration(minutes int) time.Duration {
+	return time.Duration(minutes) * time.Minute
+}
@@ -23,6 +23,12 @@ import (
 	"github.com/spf13/pflag"
 )
 
+// Date is a calendar date without a time of day, such as 2023-01-18.
+//
+// It is stored as midnight UTC, which keeps the date the same when
+// formatted, regardless of the timezone of the attendance. Combine it with
+// a time of day in the right location via [time.Date] instead of adding
+// durations to it.
 type Date time.Time
 
 // ensure it implements the interface
@@ -56,7 +62,7 @@ func (d *Date) Set(value string) error {
 	if err != nil {
 		return err
 	}
-	*d = Date(t.UTC())
+	*d = Date(t)
 	return nil
 }
 
@@ -0,0 +1,260 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package journal keeps a local log of the changes made to the attendance,
+// so they can be undone later.
+//
+// The journal is a file with one JSON [Entry] per line, where each entry
+// contains the state of each changed day before and after the change.
+// Entries are only ever appended, and undoing an entry appends a new
+// entry that refers to the undone entries.
+package journal
+
+import (
+	"bufio"
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/personio"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrNothingToUndo = errors.New("nothing to undo")
+)
+
+// Entry is a single change of one or more days, such as made by one
+// invocation of a command.
+type Entry struct {
+	// ID is the sequence number of the entry in the journal, starting at 1.
+	ID         int       `json:"id"`
+	Time       time.Time `json:"time"`
+	Command    string    `json:"command"`
+	BaseURL    string    `json:"baseUrl"`
+	EmployeeID int       `json:"employeeId"`
+	// UndoOf contains the IDs of the entries that this entry undid,
+	// or is empty if this entry is not an undo.
+	UndoOf []int `json:"undoOf,omitempty"`
+	Days   []Day `json:"days"`
+}
+
+// Day is the state of a day before and after it was changed.
+type Day struct {
+	Date string `json:"date"`
+	// DayID is the ID of the day in Personio, if known.
+	DayID *uuid.UUID `json:"dayId"`
+	// Deleted is true if the whole day was deleted.
+	Deleted bool     `json:"deleted,omitempty"`
+	Before  []Period `json:"before"`
+	After   []Period `json:"after"`
+}
+
+// Period is an attendance period in the journal.
+//
+// Compared to [personio.Period], the times are stored with their offset,
+// so they refer to the same point in time even if the timezone config
+// is changed later.
+type Period struct {
+	ID        uuid.UUID           `json:"id"`
+	Start     time.Time           `json:"start"`
+	End       time.Time           `json:"end"`
+	ProjectID *int                `json:"projectId"`
+	Comment   *string             `json:"comment"`
+	Type      personio.PeriodType `json:"type"`
+}
+
+// NewPeriods converts the Personio periods into journal periods.
+func NewPeriods(periods []personio.Period) []Period {
+	result := make([]Period, 0, len(periods))
+	for _, p := range periods {
+		result = append(result, Period{
+			ID:        p.ID,
+			Start:     p.Start.Time,
+			End:       p.End.Time,
+			ProjectID: p.ProjectID,
+			Comment:   p.Comment,
+			Type:      p.Type,
+		})
+	}
+	return result
+}
+
+// PersonioPeriods converts the journal periods back into Personio periods,
+// with the times in the given location.
+func PersonioPeriods(periods []Period, loc *time.Location) []personio.Period {
+	result := make([]personio.Period, 0, len(periods))
+	for _, p := range periods {
+		result = append(result, personio.Period{
+			ID:        p.ID,
+			Start:     personio.PersonioTime{Time: p.Start.In(loc)},
+			End:       personio.PersonioTime{Time: p.End.In(loc)},
+			ProjectID: p.ProjectID,
+			Comment:   p.Comment,
+			Type:      p.Type,
+		})
+	}
+	return result
+}
+
+// IsUndo returns true if the entry undid other entries.
+func (e Entry) IsUndo() bool {
+	return len(e.UndoOf) > 0
+}
+
+// ReadFile reads all entries from a journal file, oldest first.
+// Returns no entries and no error if the file does not exist.
+func ReadFile(path string) ([]Entry, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	// Bulk changes can make for long lines
+	scanner.Buffer(nil, 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// AppendFile adds the entry to the end of a journal file, which is only
+// readable by the current user, creating the file and its parent
+// directories as needed.
+//
+// The entry's ID is set to follow the last entry in the file, and its
+// time is set to the current time if it is zero. The updated entry is
+// returned.
+func AppendFile(path string, entry Entry) (Entry, error) {
+	entries, err := ReadFile(path)
+	if err != nil {
+		return entry, err
+	}
+	entry.ID = 1
+	if len(entries) > 0 {
+		entry.ID = entries[len(entries)-1].ID + 1
+	}
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return entry, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return entry, err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return entry, err
+	}
+	if _, err := f.Write(append(b, '\n')); err != nil {
+		f.Close()
+		return entry, err
+	}
+	return entry, f.Close()
+}
+
+// Undoable returns the n most recent entries that can be undone, newest
+// first. Entries that are undos themselves, or that have already been
+// undone, are skipped.
+//
+// Returns [ErrNothingToUndo] if there are fewer than n such entries.
+func Undoable(entries []Entry, n int) ([]Entry, error) {
+	undone := map[int]bool{}
+	for _, entry := range entries {
+		for _, id := range entry.UndoOf {
+			undone[id] = true
+		}
+	}
+	var result []Entry
+	for i := len(entries) - 1; i >= 0 && len(result) < n; i-- {
+		if entries[i].IsUndo() || undone[entries[i].ID] {
+			continue
+		}
+		result = append(result, entries[i])
+	}
+	if len(result) < n {
+		if len(result) == 0 {
+			return nil, ErrNothingToUndo
+		}
+		return nil, fmt.Errorf("%w: want to undo %d changes, but only %d can be undone",
+			ErrNothingToUndo, n, len(result))
+	}
+	return result, nil
+}
+
+// UndoDays returns the days to restore to undo the entries, as given
+// newest first by [Undoable], sorted by date.
+//
+// For each day, the Before field is the state to restore, which is the
+// state before the oldest of the entries that changed the day, and the
+// After field is the state that the newest of the entries left it in.
+func UndoDays(entries []Entry) []Day {
+	var days []Day
+	for _, entry := range entries {
+		for _, day := range entry.Days {
+			i := slices.IndexFunc(days, func(d Day) bool {
+				return d.Date == day.Date
+			})
+			if i == -1 {
+				days = append(days, day)
+				continue
+			}
+			// Entries are newest first, so this one is older
+			days[i].Before = day.Before
+			if days[i].DayID == nil {
+				days[i].DayID = day.DayID
+			}
+		}
+	}
+	slices.SortFunc(days, func(a, b Day) int {
+		return cmp.Compare(a.Date, b.Date)
+	})
+	return days
+}
+
+// Plan returns the changes that were made to the day, with the times in
+// the given location.
+func (d Day) Plan(loc *time.Location) personio.DayPlan {
+	before := PersonioPeriods(d.Before, loc)
+	if d.Deleted {
+		return personio.DiffDelete(d.Date, before)
+	}
+	return personio.DiffPeriods(d.Date, before, PersonioPeriods(d.After, loc))
+}
@@ -0,0 +1,244 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package journal
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/personio"
+	"github.com/google/uuid"
+)
+
+func TestAppendAndReadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sub", "journal.jsonl")
+
+	entries, err := ReadFile(path)
+	if err != nil {
+		t.Fatalf("read missing file: %s", err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("want no entries, got %d", len(entries))
+	}
+
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2023, 1, 18, 8, 0, 0, 0, berlin)
+	periods := []personio.Period{{
+		ID:    uuid.New(),
+		Start: personio.PersonioTime{Time: start},
+		End:   personio.PersonioTime{Time: start.Add(8 * time.Hour)},
+		Type:  personio.PeriodTypeWork,
+	}}
+	first, err := AppendFile(path, Entry{
+		Command: "attendance set",
+		Days: []Day{{
+			Date:  "2023-01-18",
+			After: NewPeriods(periods),
+		}},
+	})
+	if err != nil {
+		t.Fatalf("append: %s", err)
+	}
+	second, err := AppendFile(path, Entry{Command: "attendance undo", UndoOf: []int{first.ID}})
+	if err != nil {
+		t.Fatalf("append: %s", err)
+	}
+	if first.ID != 1 || second.ID != 2 {
+		t.Errorf("want IDs 1 and 2, got %d and %d", first.ID, second.ID)
+	}
+	if first.Time.IsZero() {
+		t.Error("want time to be set, got zero")
+	}
+
+	stat, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := stat.Mode().Perm(); perm != 0600 {
+		t.Errorf("want file mode 0600, got %o", perm)
+	}
+
+	entries, err = ReadFile(path)
+	if err != nil {
+		t.Fatalf("read: %s", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("want 2 entries, got %d", len(entries))
+	}
+	if !entries[1].IsUndo() {
+		t.Error("want second entry to be an undo")
+	}
+	restored := PersonioPeriods(entries[0].Days[0].After, berlin)
+	if len(restored) != 1 {
+		t.Fatalf("want 1 period, got %d", len(restored))
+	}
+	if !restored[0].Start.Equal(start) || restored[0].Start.Location() != berlin {
+		t.Errorf("want start %s, got %s", start, restored[0].Start.Time)
+	}
+	if restored[0].ID != periods[0].ID {
+		t.Errorf("want period ID %s, got %s", periods[0].ID, restored[0].ID)
+	}
+}
+
+func TestUndoable(t *testing.T) {
+	entries := []Entry{
+		{ID: 1},
+		{ID: 2},
+		{ID: 3},
+		{ID: 4, UndoOf: []int{3}},
+		{ID: 5},
+	}
+
+	var tests = []struct {
+		name    string
+		n       int
+		want    []int
+		wantErr bool
+	}{
+		{name: "latest", n: 1, want: []int{5}},
+		{name: "skips undone and undos", n: 2, want: []int{5, 2}},
+		{name: "all", n: 3, want: []int{5, 2, 1}},
+		{name: "too many", n: 4, wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := Undoable(entries, tc.n)
+			if tc.wantErr {
+				if !errors.Is(err, ErrNothingToUndo) {
+					t.Errorf("want ErrNothingToUndo, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("want %d entries, got %d", len(tc.want), len(got))
+			}
+			for i, id := range tc.want {
+				if got[i].ID != id {
+					t.Errorf("entry %d: want ID %d, got %d", i, id, got[i].ID)
+				}
+			}
+		})
+	}
+
+	if _, err := Undoable(nil, 1); !errors.Is(err, ErrNothingToUndo) {
+		t.Errorf("empty journal: want ErrNothingToUndo, got %v", err)
+	}
+}
+
+func TestUndoDays(t *testing.T) {
+	state := func(id byte) []Period {
+		return []Period{{ID: uuid.UUID{id}}}
+	}
+	// Newest first, as returned by Undoable
+	entries := []Entry{
+		{ID: 3, Days: []Day{
+			{Date: "2023-01-19", Before: state(2), After: state(3)},
+		}},
+		{ID: 2, Days: []Day{
+			{Date: "2023-01-19", Before: state(1), After: state(2)},
+			{Date: "2023-01-18", Before: state(4), After: state(5)},
+		}},
+	}
+
+	days := UndoDays(entries)
+	if len(days) != 2 {
+		t.Fatalf("want 2 days, got %d", len(days))
+	}
+
+	var tests = []struct {
+		date   string
+		before byte
+		after  byte
+	}{
+		{date: "2023-01-18", before: 4, after: 5},
+		{date: "2023-01-19", before: 1, after: 3},
+	}
+	for i, tc := range tests {
+		day := days[i]
+		if day.Date != tc.date {
+			t.Errorf("day %d: want date %s, got %s", i, tc.date, day.Date)
+		}
+		if got := day.Before[0].ID[0]; got != tc.before {
+			t.Errorf("%s: want before state %d, got %d", tc.date, tc.before, got)
+		}
+		if got := day.After[0].ID[0]; got != tc.after {
+			t.Errorf("%s: want after state %d, got %d", tc.date, tc.after, got)
+		}
+	}
+}
+
+func TestDayPlan(t *testing.T) {
+	start := time.Date(2023, 1, 18, 8, 0, 0, 0, time.UTC)
+	oldPeriod := Period{
+		ID:    uuid.New(),
+		Start: start,
+		End:   start.Add(4 * time.Hour),
+		Type:  personio.PeriodTypeWork,
+	}
+	newPeriod := oldPeriod
+	newPeriod.End = start.Add(5 * time.Hour)
+
+	var tests = []struct {
+		name    string
+		day     Day
+		want    []personio.ChangeType
+		wantDel bool
+	}{
+		{
+			name: "modified",
+			day:  Day{Date: "2023-01-18", Before: []Period{oldPeriod}, After: []Period{newPeriod}},
+			want: []personio.ChangeType{personio.ChangeModify},
+		},
+		{
+			name: "added",
+			day:  Day{Date: "2023-01-18", Before: []Period{}, After: []Period{newPeriod}},
+			want: []personio.ChangeType{personio.ChangeAdd},
+		},
+		{
+			name:    "deleted",
+			day:     Day{Date: "2023-01-18", Deleted: true, Before: []Period{oldPeriod}, After: []Period{}},
+			want:    []personio.ChangeType{personio.ChangeRemove},
+			wantDel: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			plan := tc.day.Plan(time.UTC)
+			if plan.DeleteDay != tc.wantDel {
+				t.Errorf("want delete day %t, got %t", tc.wantDel, plan.DeleteDay)
+			}
+			if len(plan.Changes) != len(tc.want) {
+				t.Fatalf("want %d changes, got %d", len(tc.want), len(plan.Changes))
+			}
+			for i, change := range plan.Changes {
+				if change.Change != tc.want[i] {
+					t.Errorf("change %d: want %s, got %s", i, tc.want[i], change.Change)
+				}
+			}
+		})
+	}
+}
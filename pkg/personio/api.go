@@ -33,6 +33,11 @@ type Timecard struct {
 
 // PersonioTime is a wrapper around time.Time
 // that marshals / unmarshals into JSON as YYYY-MM-DDTHH:MM:SS
+//
+// Personio stores the attendance as wall-clock times without an offset,
+// so the time is marshaled as the wall-clock time in its own location,
+// and is unmarshaled as UTC. The [Client] converts the times to and from
+// its location, as set via [WithLocation].
 type PersonioTime struct {
 	time.Time
 }
@@ -55,6 +60,15 @@ func (p PersonioTime) MarshalJSON() ([]byte, error) {
 	return json.Marshal(str)
 }
 
+// wallClockIn returns the time with the same wall-clock time, but in
+// another location. Times that do not exist in that location, such as
+// when the clocks are turned forward, are moved forward by the gap.
+func (p PersonioTime) wallClockIn(loc *time.Location) PersonioTime {
+	year, month, day := p.Date()
+	hour, min, sec := p.Clock()
+	return PersonioTime{time.Date(year, month, day, hour, min, sec, p.Nanosecond(), loc)}
+}
+
 type Period struct {
 	ID        uuid.UUID    `json:"id"`
 	Start     PersonioTime `json:"start"`
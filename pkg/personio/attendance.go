@@ -20,6 +20,7 @@ package personio
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -31,11 +32,49 @@ import (
 	"github.com/rs/zerolog/log"
 )
 
+// GetMyAttendanceCalendar calls [Client.GetMyAttendanceCalendarContext]
+// with [context.Background].
 func (c *Client) GetMyAttendanceCalendar(startDate, endDate time.Time) ([]Timecard, error) {
-	return c.GetAttendanceCalendar(c.EmployeeID, startDate, endDate)
+	return c.GetMyAttendanceCalendarContext(context.Background(), startDate, endDate)
 }
 
+// GetMyAttendanceCalendarContext returns the logged in employee's timecards
+// for each day between the start and end dates, inclusive.
+func (c *Client) GetMyAttendanceCalendarContext(ctx context.Context, startDate, endDate time.Time) ([]Timecard, error) {
+	return c.GetAttendanceCalendarContext(ctx, c.EmployeeID, startDate, endDate)
+}
+
+// GetMyAttendanceDay calls [Client.GetMyAttendanceDayContext]
+// with [context.Background].
+func (c *Client) GetMyAttendanceDay(date time.Time) (Timecard, error) {
+	return c.GetMyAttendanceDayContext(context.Background(), date)
+}
+
+// GetMyAttendanceDayContext returns the logged in employee's timecard
+// for a single day.
+func (c *Client) GetMyAttendanceDayContext(ctx context.Context, date time.Time) (Timecard, error) {
+	cal, err := c.GetMyAttendanceCalendarContext(ctx, date, date)
+	if err != nil {
+		return Timecard{}, err
+	}
+	dateString := date.Format(time.DateOnly)
+	for _, card := range cal {
+		if card.Date == dateString {
+			return card, nil
+		}
+	}
+	return Timecard{}, fmt.Errorf("no timecard returned for %s", dateString)
+}
+
+// GetAttendanceCalendar calls [Client.GetAttendanceCalendarContext]
+// with [context.Background].
 func (c *Client) GetAttendanceCalendar(employeeID int, startDate, endDate time.Time) ([]Timecard, error) {
+	return c.GetAttendanceCalendarContext(context.Background(), employeeID, startDate, endDate)
+}
+
+// GetAttendanceCalendarContext returns an employee's timecards for each day
+// between the start and end dates, inclusive.
+func (c *Client) GetAttendanceCalendarContext(ctx context.Context, employeeID int, startDate, endDate time.Time) ([]Timecard, error) {
 	if err := c.assertLoggedIn(); err != nil {
 		return nil, err
 	}
@@ -44,7 +83,7 @@ func (c *Client) GetAttendanceCalendar(employeeID int, startDate, endDate time.T
 	queryParams.Set("start_date", startDate.Format(time.DateOnly))
 	queryParams.Set("end_date", endDate.Format(time.DateOnly))
 
-	req, err := http.NewRequest("GET", fmt.Sprintf(
+	req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf(
 		"/svc/attendance-bff/v1/timesheet/%d?%s",
 		employeeID, queryParams.Encode()), nil)
 	if err != nil {
@@ -60,22 +99,37 @@ func (c *Client) GetAttendanceCalendar(employeeID int, startDate, endDate time.T
 	if err := json.NewDecoder(resp.Body).Decode(&timesheet); err != nil {
 		return nil, fmt.Errorf("decode response: %w", err)
 	}
+	for _, card := range timesheet.Timecards {
+		for i := range card.Periods {
+			card.Periods[i].Start = card.Periods[i].Start.wallClockIn(c.location)
+			card.Periods[i].End = card.Periods[i].End.wallClockIn(c.location)
+		}
+	}
 	return timesheet.Timecards, nil
 }
 
+// SetAttendance calls [Client.SetAttendanceContext] with [context.Background].
 func (c *Client) SetAttendance(date time.Time, periods []Period) error {
+	return c.SetAttendanceContext(context.Background(), date, periods)
+}
+
+// SetAttendanceContext replaces all of a day's attendance periods.
+//
+// The periods are sent as wall-clock times in the client's location,
+// regardless of the location of the given times.
+func (c *Client) SetAttendanceContext(ctx context.Context, date time.Time, periods []Period) error {
 	if err := c.assertLoggedIn(); err != nil {
 		return err
 	}
 
-	var requestPeriods []RequestPeriod
+	requestPeriods := make([]RequestPeriod, 0, len(periods))
 
 	for i := range periods {
 		if periods[i].ID == uuid.Nil {
 			periods[i].ID = uuid.New()
 		}
-		periods[i].Start = PersonioTime{periods[i].Start.Truncate(time.Second).UTC()}
-		periods[i].End = PersonioTime{periods[i].End.Truncate(time.Second).UTC()}
+		periods[i].Start = PersonioTime{periods[i].Start.Truncate(time.Second).In(c.location)}
+		periods[i].End = PersonioTime{periods[i].End.Truncate(time.Second).In(c.location)}
 		if periods[i].Type == "" {
 			periods[i].Type = PeriodTypeWork
 		}
@@ -91,12 +145,12 @@ func (c *Client) SetAttendance(date time.Time, periods []Period) error {
 	}
 	bodyReader := bytes.NewReader(body)
 
-	dayID, err := c.GetOrNewDayUUID(date)
+	dayID, err := c.GetOrNewDayUUIDContext(ctx, date)
 	if err != nil {
 		return err
 	}
 
-	req, err := http.NewRequest(http.MethodPut, "/svc/attendance-api/v1/days/"+dayID.String(), bodyReader)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, "/svc/attendance-api/v1/days/"+dayID.String(), bodyReader)
 	if err != nil {
 		return err
 	}
@@ -111,19 +165,24 @@ func (c *Client) SetAttendance(date time.Time, periods []Period) error {
 	return err
 }
 
-// DeleteAttendance will delete a day's attendance.
-// Note: this seems to be broken in the Personio API, it returns a 403 error (despite being used by the web UI).
+// DeleteAttendance calls [Client.DeleteAttendanceContext] with [context.Background].
 func (c *Client) DeleteAttendance(date time.Time) error {
+	return c.DeleteAttendanceContext(context.Background(), date)
+}
+
+// DeleteAttendanceContext will delete a day's attendance.
+// Note: this seems to be broken in the Personio API, it returns a 403 error (despite being used by the web UI).
+func (c *Client) DeleteAttendanceContext(ctx context.Context, date time.Time) error {
 	if err := c.assertLoggedIn(); err != nil {
 		return err
 	}
 
-	dayID, err := c.GetOrNewDayUUID(date)
+	dayID, err := c.GetOrNewDayUUIDContext(ctx, date)
 	if err != nil {
 		return err
 	}
 
-	req, err := http.NewRequest(http.MethodDelete, "/svc/attendance-api/v1/days/"+dayID.String(), nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, "/svc/attendance-api/v1/days/"+dayID.String(), nil)
 	if err != nil {
 		return err
 	}
@@ -132,13 +191,18 @@ func (c *Client) DeleteAttendance(date time.Time) error {
 	return err
 }
 
-// GetOrNewDayUUID will either lookup a day's ID (from cache or by querying
+// GetOrNewDayUUID calls [Client.GetOrNewDayUUIDContext] with [context.Background].
+func (c *Client) GetOrNewDayUUID(date time.Time) (uuid.UUID, error) {
+	return c.GetOrNewDayUUIDContext(context.Background(), date)
+}
+
+// GetOrNewDayUUIDContext will either lookup a day's ID (from cache or by querying
 // the API), or generate a new ID and store this new ID in cache.
 //
 // After the remote lookup to the API, the client caches which days in the same
 // month that has undefined IDs.
-func (c *Client) GetOrNewDayUUID(date time.Time) (uuid.UUID, error) {
-	id, err := c.GetDayUUID(date)
+func (c *Client) GetOrNewDayUUIDContext(ctx context.Context, date time.Time) (uuid.UUID, error) {
+	id, err := c.GetDayUUIDContext(ctx, date)
 	if err != nil {
 		return uuid.Nil, fmt.Errorf("get day UUID: %w", err)
 	}
@@ -153,7 +217,12 @@ func (c *Client) GetOrNewDayUUID(date time.Time) (uuid.UUID, error) {
 	return newID, nil
 }
 
-// GetDayUUID will lookup a day's ID (from cache or by querying the API),
+// GetDayUUID calls [Client.GetDayUUIDContext] with [context.Background].
+func (c *Client) GetDayUUID(date time.Time) (*uuid.UUID, error) {
+	return c.GetDayUUIDContext(context.Background(), date)
+}
+
+// GetDayUUIDContext will lookup a day's ID (from cache or by querying the API),
 // or nil if it is undefined.
 //
 // The Personio API want the client to generate the IDs, so an undefined day ID
@@ -161,14 +230,14 @@ func (c *Client) GetOrNewDayUUID(date time.Time) (uuid.UUID, error) {
 //
 // After the remote lookup to the API, the client caches which days in the same
 // month that has undefined IDs.
-func (c *Client) GetDayUUID(date time.Time) (*uuid.UUID, error) {
+func (c *Client) GetDayUUIDContext(ctx context.Context, date time.Time) (*uuid.UUID, error) {
 	dateString := date.Format(time.DateOnly)
 	// Cache contains nil values on "known to be undefined day IDs"
 	if id, ok := c.dayIDCache[dateString]; ok {
 		return id, nil
 	}
 	startDate, endDate := util.TimeFullMonth(date)
-	cal, err := c.GetMyAttendanceCalendar(startDate, endDate)
+	cal, err := c.GetMyAttendanceCalendarContext(ctx, startDate, endDate)
 	if err != nil {
 		return nil, fmt.Errorf("get days for range %s-%s: %w",
 			startDate.Format(time.DateOnly),
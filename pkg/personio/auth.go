@@ -19,37 +19,84 @@
 package personio
 
 import (
-	"bufio"
+	"context"
 	"errors"
 	"fmt"
+	"html"
 	"io"
-	"log"
 	"net/http"
 	"net/url"
-	"os"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/applejag/rootless-personio/pkg/config"
-	"golang.org/x/term"
 )
 
 var (
 	csrfTokenErrorRegex = regexp.MustCompile(`REDUX_INITIAL_STATE\.bladeState\.messages\s*=\s*{[^}]*error:\s*"((?:\\"|[^"])*)"`)
+	csrfTokenInputRegex = regexp.MustCompile(`<input[^>]*name="_token"[^>]*value="([^"]*)"`)
+	csrfTokenMetaRegex  = regexp.MustCompile(`<meta[^>]*name="csrf-token"[^>]*content="([^"]*)"`)
 )
 
+// UnlockRequiredError is returned by [Client.Login] when Personio detected a
+// login from a new device, and wants you to confirm it using the token
+// that Personio sent to your email.
+//
+// The client keeps the state needed to continue the login, which is also
+// included in [Client.Session], so the login can be finished later using
+// [Client.UnlockWithToken].
+type UnlockRequiredError struct {
+	// CSRFToken is the Cross-Site-Request-Forgery token found on the
+	// confirmation page, which is sent together with the email token.
+	CSRFToken string
+}
+
+// Error implements [error].
+func (e *UnlockRequiredError) Error() string {
+	return "unlock required: confirm the login with the token sent to your email"
+}
+
+// Unwrap returns [ErrUnlockRequired], so the error can be checked
+// with [errors.Is].
+func (e *UnlockRequiredError) Unwrap() error {
+	return ErrUnlockRequired
+}
+
+// UnlockAndLogin calls [Client.UnlockAndLoginContext] with [context.Background].
 func (c *Client) UnlockAndLogin(auth config.Auth, emailToken string) error {
-	if err := c.UnlockWithToken(emailToken); err != nil {
+	return c.UnlockAndLoginContext(context.Background(), auth, emailToken)
+}
+
+// UnlockAndLoginContext unlocks the account using the email token,
+// and then logs in again if the unlock did not already finish the login.
+func (c *Client) UnlockAndLoginContext(ctx context.Context, auth config.Auth, emailToken string) error {
+	if err := c.UnlockWithTokenContext(ctx, emailToken); err != nil {
 		return fmt.Errorf("unlock account: %w", err)
 	}
-	return c.Login(auth)
+	if c.EmployeeID != 0 {
+		return nil
+	}
+	return c.LoginContext(ctx, auth)
 }
 
+// UnlockWithToken calls [Client.UnlockWithTokenContext] with [context.Background].
 func (c *Client) UnlockWithToken(emailToken string) error {
+	return c.UnlockWithTokenContext(context.Background(), emailToken)
+}
+
+// UnlockWithTokenContext finishes a login that failed with [UnlockRequiredError],
+// by submitting the token that Personio sent to your email.
+//
+// On success, the client is logged in and its employee ID is set.
+func (c *Client) UnlockWithTokenContext(ctx context.Context, emailToken string) error {
 	params := url.Values{}
 	params.Set("token", strings.TrimSpace(emailToken))
+	if c.unlockCSRFToken != "" {
+		params.Set("_token", c.unlockCSRFToken)
+	}
 
-	req, err := http.NewRequest(http.MethodPost, "/login/token-auth", strings.NewReader(params.Encode()))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "/login/token-auth", strings.NewReader(params.Encode()))
 	if err != nil {
 		return err
 	}
@@ -62,145 +109,119 @@ func (c *Client) UnlockWithToken(emailToken string) error {
 	body, err := io.ReadAll(resp.Body)
 	resp.Body.Close()
 	if err != nil {
-		log.Fatal(err)
+		return fmt.Errorf("read body: %w", err)
 	}
 
-	if strings.HasSuffix(resp.Request.URL.Path, "/login/token-auth") {
+	if isUnlockPage(resp) {
 		errorMatch := csrfTokenErrorRegex.FindSubmatch(body)
 		if errorMatch != nil {
 			return fmt.Errorf("error from page: %s", errorMatch[1])
 		}
 		return errors.New("did not unlock account, and found no error on page")
 	}
+	c.unlockCSRFToken = ""
+	if err := c.CheckSessionContext(ctx); err != nil {
+		return fmt.Errorf("%w: %w", ErrEmployeeIDNotFound, err)
+	}
 	return nil
 }
 
-func (c *Client) Login(auth config.Auth) error {
-	email, pass, twoFactorToken, err := c.fetchCredentials(auth)
-	if err != nil {
-		return fmt.Errorf("fetch credentials: %w", err)
-	}
-	var resp *http.Response
-	startPage, err := http.NewRequest(http.MethodGet, "/", nil)
-	if err != nil {
-		return fmt.Errorf("create request: %w", err)
-	}
-	resp, err = c.Raw(startPage)
-	if err != nil {
-		return fmt.Errorf("get start page: %w", err)
-	}
-	if resp.Request.URL.Host != "login.personio.com" {
-		return fmt.Errorf("%w: want host %q, got %q", ErrUnexpectedRedirect, "login.personio.com", resp.Request.URL.Host)
-	}
-	if !strings.HasSuffix(resp.Request.URL.Path, "/u/login/identifier") {
-		return fmt.Errorf("%w: want path \"/u/login/identifier\", got %q", ErrUnexpectedRedirect, resp.Request.URL.Path)
-	}
-	state := resp.Request.URL.Query().Get("state")
+func isUnlockPage(resp *http.Response) bool {
+	return strings.HasSuffix(resp.Request.URL.Path, "/login/token-auth")
+}
 
-	enterUser, err := http.NewRequest(http.MethodPost, "https://login.personio.com/u/login/identifier", strings.NewReader(url.Values{
-		"username": []string{email},
-		"state":    []string{state},
-	}.Encode()))
-	if err != nil {
-		return fmt.Errorf("create request: %w", err)
-	}
-	resp, err = c.RawForm(enterUser)
+// newUnlockRequiredError reads the CSRF token from the login confirmation
+// page, and stores it in the client so the login can be continued.
+func (c *Client) newUnlockRequiredError(resp *http.Response) error {
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
 	if err != nil {
-		return fmt.Errorf("enter user: %w", err)
-	}
-
-	if resp.Request.URL.Host != "login.personio.com" {
-		return fmt.Errorf("%w: want host %q, got %q", ErrUnexpectedRedirect, "login.personio.com", resp.Request.URL.Host)
+		return fmt.Errorf("read body: %w", err)
 	}
-	if !strings.HasSuffix(resp.Request.URL.Path, "/u/login/password") {
-		return fmt.Errorf("%w: want path \"/u/login/password\", got %q", ErrUnexpectedRedirect, resp.Request.URL.Path)
-	}
-	state = resp.Request.URL.Query().Get("state")
-
-	params := url.Values{}
-	params.Set("email", email)
-	params.Set("password", pass)
-	params.Set("state", state)
-	req, err := http.NewRequest(http.MethodPost, "https://login.personio.com/u/login/password", strings.NewReader(params.Encode()))
-	if err != nil {
-		return err
+	var csrfToken string
+	if match := csrfTokenInputRegex.FindSubmatch(body); match != nil {
+		csrfToken = html.UnescapeString(string(match[1]))
+	} else if match := csrfTokenMetaRegex.FindSubmatch(body); match != nil {
+		csrfToken = html.UnescapeString(string(match[1]))
 	}
+	c.unlockCSRFToken = csrfToken
+	return &UnlockRequiredError{CSRFToken: csrfToken}
+}
 
-	resp, err = c.RawForm(req)
-	if err != nil {
-		return err
-	}
+// Login calls [Client.LoginContext] with [context.Background].
+func (c *Client) Login(auth config.Auth) error {
+	return c.LoginContext(context.Background(), auth)
+}
 
-	baseURL, err := url.Parse(c.BaseURL)
+// LoginContext logs in by going through Personio's login pages, using the
+// credentials from [Client.CredentialProvider] or the auth config.
+//
+// Each page is detected from its URL and form fields, and submitted by
+// its own handler, such as for the email, password and 2FA pages.
+// Returns [UnknownLoginPageError] for pages it does not know how to
+// submit, and [ErrLoginRejected] if a page shows an error, such as for
+// a wrong password.
+//
+// Returns [UnlockRequiredError] if Personio wants the login to be confirmed
+// using a token sent via email.
+func (c *Client) LoginContext(ctx context.Context, auth config.Auth) error {
+	creds, err := c.fetchCredentials(auth)
 	if err != nil {
-		return fmt.Errorf("parse base URL: %w", err)
-	}
-
-	if resp.Request.URL.Host != baseURL.Host && resp.Request.URL.Host != "login.personio.com" {
-		return fmt.Errorf("%w: want host %q, got %q", ErrUnexpectedRedirect, baseURL.Host, resp.Request.URL.Host)
+		return fmt.Errorf("fetch credentials: %w", err)
 	}
+	return c.runLoginFlow(ctx, &loginFlow{
+		auth:   auth,
+		creds:  creds,
+		prompt: promptTerminal,
+	})
+}
 
-	state = resp.Request.URL.Query().Get("state")
-
-	if strings.HasSuffix(resp.Request.URL.Path, "/u/mfa-otp-challenge") {
-		// Pass two factor token to the request
-		if twoFactorToken == "" {
-			if term.IsTerminal(int(os.Stdin.Fd())) || os.Getenv("TERM") == "dumb" {
-				fmt.Print("2 factor token: ")
-				token, err := bufio.NewReader(os.Stdin).ReadString('\n')
-				if err != nil {
-					return fmt.Errorf("read 2 factor token: %w", err)
-				}
-				twoFactorToken = strings.TrimSuffix(token, "\n")
-			} else {
-				return errors.New("2 factor token needed, but not provided")
-			}
-		}
-
-		var twoFactorParams = url.Values{}
-		twoFactorParams.Set("code", twoFactorToken)
-		twoFactorParams.Set("state", state)
-		req, err := http.NewRequest(http.MethodPost, "https://login.personio.com/u/mfa-otp-challenge", strings.NewReader(twoFactorParams.Encode()))
+func (c *Client) fetchCredentials(auth config.Auth) (Credentials, error) {
+	provider := c.CredentialProvider
+	if provider == nil {
+		var err error
+		provider, err = NewCredentialProvider(auth, c.LoginURL, c.BaseURL)
 		if err != nil {
-			return err
+			return Credentials{}, err
 		}
+	}
+	return provider.Credentials()
+}
 
-		resp, err = c.RawForm(req)
+// generateConfiguredTOTP generates a 2FA code from the TOTP secret given by
+// the credential provider or the config, or returns an empty string if no
+// secret is configured.
+//
+// The auth.totpSecret config is used with all credential sources, as most
+// providers do not store the secret themselves.
+func generateConfiguredTOTP(creds Credentials, auth config.Auth) (string, error) {
+	secret := creds.TOTPSecret
+	if secret == "" {
+		secret = auth.TOTPSecret
+	}
+	if secret == "" && auth.TOTPSecretCommand != "" {
+		var err error
+		secret, err = runShellCommand(auth.TOTPSecretCommand)
 		if err != nil {
-			return err
+			return "", fmt.Errorf("get TOTP secret: %w", err)
 		}
 	}
-
-	if strings.TrimPrefix(resp.Request.URL.Path, "/") != "" {
-		return fmt.Errorf("%w: want path \"/\", got %q", ErrUnexpectedRedirect, resp.Request.URL.Path)
-	}
-
-	userActivity, err := c.getUserActivity()
-	if err != nil {
-		return fmt.Errorf("%w: %s", ErrEmployeeIDNotFound, err)
-	}
-	c.EmployeeID = userActivity.User.ID
-	return nil
-}
-
-func (c *Client) fetchCredentials(auth config.Auth) (string, string, string, error) {
-	if !auth.Keepass {
-		return auth.Email, auth.Password, "", nil
+	if secret == "" {
+		return "", nil
 	}
-	user, pw, tfa, err := fetchKeepassCredentials("https://login.personio.com/")
+	code, err := GenerateTOTP(secret, time.Now())
 	if err != nil {
-		// Fall back to the old method
-		user, pw, tfa, err = fetchKeepassCredentials(c.BaseURL)
+		return "", fmt.Errorf("generate 2 factor token: %w", err)
 	}
-	return user, pw, tfa, err
+	return code, nil
 }
 
 // getUserActivity seems to get info about the currently logged in user.
 //
 // Don't know for certain what this endpoint is, so keeping the function as
 // private in the meantime.
-func (c *Client) getUserActivity() (*navigationContext, error) {
-	req, err := http.NewRequest(http.MethodGet, "/api/v1/navigation/context", nil)
+func (c *Client) getUserActivity(ctx context.Context) (*navigationContext, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "/api/v1/navigation/context", nil)
 	if err != nil {
 		return nil, fmt.Errorf("create request: %w", err)
 	}
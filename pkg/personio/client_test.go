@@ -0,0 +1,900 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package personio_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/config"
+	"github.com/applejag/rootless-personio/pkg/personio"
+	"github.com/applejag/rootless-personio/pkg/personio/personiotest"
+	"github.com/google/uuid"
+)
+
+func newLoggedInClient(t *testing.T, server *personiotest.Server, opts ...personio.Option) *personio.Client {
+	t.Helper()
+	client, err := server.NewClient(opts...)
+	if err != nil {
+		t.Fatalf("new client: %s", err)
+	}
+	if err := client.Login(server.Auth()); err != nil {
+		t.Fatalf("login: %s", err)
+	}
+	return client
+}
+
+func TestLogin(t *testing.T) {
+	server := personiotest.NewServer()
+	defer server.Close()
+
+	client := newLoggedInClient(t, server)
+	if client.EmployeeID != personiotest.DefaultEmployeeID {
+		t.Errorf("want employee ID %d, got %d", personiotest.DefaultEmployeeID, client.EmployeeID)
+	}
+}
+
+func TestLoginBehindProxy(t *testing.T) {
+	server := personiotest.NewServer()
+	defer server.Close()
+	server.BaseURL = "https://personio.example.com/some/reverse/proxy"
+	server.LoginHost = "login.example.com"
+
+	client := newLoggedInClient(t, server)
+	if _, err := client.GetMyEmployeeData(); err != nil {
+		t.Fatalf("get employee: %s", err)
+	}
+	for _, r := range server.Requests() {
+		if strings.HasSuffix(r.Host, ".personio.com") {
+			t.Errorf("request was sent to %s%s, want only the proxy hosts", r.Host, r.Path)
+		}
+	}
+}
+
+func TestLoginWrongPassword(t *testing.T) {
+	server := personiotest.NewServer()
+	defer server.Close()
+
+	client, err := server.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth := server.Auth()
+	auth.Password = "wrong"
+	err = client.Login(auth)
+	if !errors.Is(err, personio.ErrLoginRejected) {
+		t.Errorf("want ErrLoginRejected, got %v", err)
+	} else if !strings.Contains(err.Error(), "Wrong email or password") {
+		t.Errorf("want page error message in %q", err)
+	}
+	if client.EmployeeID != 0 {
+		t.Errorf("want no employee ID, got %d", client.EmployeeID)
+	}
+}
+
+func TestLoginTOTP(t *testing.T) {
+	server := personiotest.NewServer()
+	defer server.Close()
+	server.TOTPSecret = "JBSWY3DPEHPK3PXP"
+
+	// Env file without a TOTP secret, so it must come from the config
+	envFile := filepath.Join(t.TempDir(), "personio.env")
+	content := fmt.Sprintf("PERSONIO_AUTH_EMAIL=%s\nPERSONIO_AUTH_PASSWORD=%s\n", server.Email, server.Password)
+	if err := os.WriteFile(envFile, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	var tests = []struct {
+		name string
+		auth config.Auth
+	}{
+		{
+			name: "config source",
+			auth: server.Auth(),
+		},
+		{
+			name: "env file source",
+			auth: config.Auth{
+				Source:     config.CredentialSourceEnvFile,
+				EnvFile:    envFile,
+				TOTPSecret: server.TOTPSecret,
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			client, err := server.NewClient()
+			if err != nil {
+				t.Fatal(err)
+			}
+			// Fails instead of prompting, as the tests are not interactive
+			if err := client.Login(tc.auth); err != nil {
+				t.Fatalf("login: %s", err)
+			}
+			if client.EmployeeID != personiotest.DefaultEmployeeID {
+				t.Errorf("want employee ID %d, got %d", personiotest.DefaultEmployeeID, client.EmployeeID)
+			}
+		})
+	}
+}
+
+func TestLoginUnlock(t *testing.T) {
+	server := personiotest.NewServer()
+	defer server.Close()
+	server.EmailToken = "email-token-1234"
+
+	client, err := server.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = client.Login(server.Auth())
+	var unlockErr *personio.UnlockRequiredError
+	if !errors.As(err, &unlockErr) {
+		t.Fatalf("want UnlockRequiredError, got %v", err)
+	}
+	if unlockErr.CSRFToken == "" {
+		t.Error("want CSRF token in error, got empty")
+	}
+
+	// Continue from a new client, as done by the "unlock" command
+	resumed, err := server.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := resumed.RestoreSession(client.Session()); err != nil {
+		t.Fatalf("restore session: %s", err)
+	}
+	if err := resumed.UnlockWithToken("wrong-token"); err == nil {
+		t.Fatal("want error when using wrong token, got nil")
+	}
+	if err := resumed.UnlockWithToken(server.EmailToken); err != nil {
+		t.Fatalf("unlock: %s", err)
+	}
+	if resumed.EmployeeID != personiotest.DefaultEmployeeID {
+		t.Errorf("want employee ID %d, got %d", personiotest.DefaultEmployeeID, resumed.EmployeeID)
+	}
+}
+
+func TestRestoreSession(t *testing.T) {
+	server := personiotest.NewServer()
+	defer server.Close()
+
+	session := newLoggedInClient(t, server).Session()
+
+	client, err := server.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.RestoreSession(session); err != nil {
+		t.Fatalf("restore session: %s", err)
+	}
+	if err := client.CheckSession(); err != nil {
+		t.Fatalf("check session: %s", err)
+	}
+	if client.EmployeeID != personiotest.DefaultEmployeeID {
+		t.Errorf("want employee ID %d, got %d", personiotest.DefaultEmployeeID, client.EmployeeID)
+	}
+
+	// Fresh client without the cookies
+	other, err := server.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := other.CheckSession(); !errors.Is(err, personio.ErrNotLoggedIn) {
+		t.Errorf("want ErrNotLoggedIn, got %v", err)
+	}
+}
+
+func TestImportCookies(t *testing.T) {
+	server := personiotest.NewServer()
+	defer server.Close()
+
+	// Pretend the logged in client is the browser
+	var cookies []personio.BrowserCookie
+	for _, c := range newLoggedInClient(t, server).Session().Cookies {
+		u, err := url.Parse(c.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cookies = append(cookies, personio.BrowserCookie{
+			Domain:   u.Hostname(),
+			HostOnly: true,
+			Path:     "/",
+			Name:     c.Name,
+			Value:    c.Value,
+			Secure:   true,
+		})
+	}
+	cookies = append(cookies,
+		personio.BrowserCookie{Domain: ".example.com", Name: "other", Value: "ignored"},
+		personio.BrowserCookie{Domain: "example.personio.de", Name: "expired", Value: "ignored",
+			Expires: time.Now().Add(-time.Hour)},
+	)
+
+	client, err := server.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := client.ImportCookies(cookies)
+	if err != nil {
+		t.Fatalf("import cookies: %s", err)
+	}
+	if want := len(cookies) - 2; n != want {
+		t.Errorf("want %d imported cookies, got %d", want, n)
+	}
+	if err := client.CheckSession(); err != nil {
+		t.Fatalf("check session: %s", err)
+	}
+	if client.EmployeeID != personiotest.DefaultEmployeeID {
+		t.Errorf("want employee ID %d, got %d", personiotest.DefaultEmployeeID, client.EmployeeID)
+	}
+	if session := client.Session(); session.XSRFToken == "" {
+		t.Error("want XSRF token in session, got none")
+	}
+
+	other, err := server.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := other.ImportCookies(cookies[len(cookies)-2:]); !errors.Is(err, personio.ErrNoCookiesImported) {
+		t.Errorf("want ErrNoCookiesImported, got %v", err)
+	}
+}
+
+func TestImportCookiesSaveAndRestore(t *testing.T) {
+	server := personiotest.NewServer()
+	defer server.Close()
+
+	// Pretend the browser got the session cookie for the parent domain,
+	// and only for the API paths
+	var cookies []personio.BrowserCookie
+	for _, c := range newLoggedInClient(t, server).Session().Cookies {
+		u, err := url.Parse(c.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cookie := personio.BrowserCookie{
+			Domain:   u.Hostname(),
+			HostOnly: true,
+			Path:     "/",
+			Name:     c.Name,
+			Value:    c.Value,
+			Secure:   true,
+			Expires:  time.Now().Add(time.Hour).Truncate(time.Second),
+		}
+		if u.Hostname() == "example.personio.de" && !strings.Contains(c.Name, "XSRF") {
+			cookie.Domain = ".personio.de"
+			cookie.HostOnly = false
+			cookie.Path = "/api"
+		}
+		cookies = append(cookies, cookie)
+	}
+
+	client, err := server.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.ImportCookies(cookies); err != nil {
+		t.Fatalf("import cookies: %s", err)
+	}
+	if err := client.CheckSession(); err != nil {
+		t.Fatalf("check imported session: %s", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "session.json")
+	if err := client.Session().SaveFile(path); err != nil {
+		t.Fatalf("save session: %s", err)
+	}
+	session, err := personio.LoadSessionFile(path)
+	if err != nil {
+		t.Fatalf("load session: %s", err)
+	}
+	var found bool
+	for _, c := range session.Cookies {
+		if c.Domain == "personio.de" && c.Path == "/api" {
+			found = true
+			if !c.Secure || c.Expires.IsZero() {
+				t.Errorf("want secure cookie with expiry, got %+v", c)
+			}
+		}
+	}
+	if !found {
+		t.Errorf("want domain cookie for personio.de with path /api, got %+v", session.Cookies)
+	}
+
+	restored, err := server.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := restored.RestoreSession(session); err != nil {
+		t.Fatalf("restore session: %s", err)
+	}
+	if err := restored.CheckSession(); err != nil {
+		t.Fatalf("check restored session: %s", err)
+	}
+	if restored.EmployeeID != personiotest.DefaultEmployeeID {
+		t.Errorf("want employee ID %d, got %d", personiotest.DefaultEmployeeID, restored.EmployeeID)
+	}
+}
+
+func TestSetAttendance(t *testing.T) {
+	server := personiotest.NewServer()
+	defer server.Close()
+	client := newLoggedInClient(t, server)
+
+	date := time.Date(2023, 1, 18, 0, 0, 0, 0, time.UTC)
+	projectID, err := client.GetProjectID("Project X")
+	if err != nil {
+		t.Fatalf("get project ID: %s", err)
+	}
+	periods := []personio.Period{
+		{
+			Start:     personio.PersonioTime{Time: date.Add(8 * time.Hour)},
+			End:       personio.PersonioTime{Time: date.Add(12 * time.Hour)},
+			ProjectID: &projectID,
+		},
+		{
+			Start: personio.PersonioTime{Time: date.Add(12 * time.Hour)},
+			End:   personio.PersonioTime{Time: date.Add(13 * time.Hour)},
+			Type:  personio.PeriodTypeBreak,
+		},
+	}
+	if err := client.SetAttendance(date, periods); err != nil {
+		t.Fatalf("set attendance: %s", err)
+	}
+
+	stored := server.Periods("2023-01-18")
+	if len(stored) != 2 {
+		t.Fatalf("want 2 stored periods, got %d", len(stored))
+	}
+	if stored[0].GetProjectID() != projectID {
+		t.Errorf("want project ID %d, got %d", projectID, stored[0].GetProjectID())
+	}
+
+	cal, err := client.GetMyAttendanceCalendar(date, date)
+	if err != nil {
+		t.Fatalf("get calendar: %s", err)
+	}
+	if len(cal) != 1 || len(cal[0].Periods) != 2 {
+		t.Fatalf("want 1 day with 2 periods, got %+v", cal)
+	}
+	if cal[0].DayID == nil {
+		t.Fatal("want day ID, got nil")
+	}
+	if got := cal[0].Periods[1].Type; got != personio.PeriodTypeBreak {
+		t.Errorf("want second period type %q, got %q", personio.PeriodTypeBreak, got)
+	}
+}
+
+func TestSetAttendanceTimezone(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tests = []struct {
+		name        string
+		start       time.Time
+		end         time.Time
+		wantStart   string
+		wantEnd     string
+		wantTracked time.Duration
+	}{
+		{
+			name:        "winter time",
+			start:       time.Date(2023, 1, 18, 8, 0, 0, 0, berlin),
+			end:         time.Date(2023, 1, 18, 16, 0, 0, 0, berlin),
+			wantStart:   "2023-01-18 08:00:00",
+			wantEnd:     "2023-01-18 16:00:00",
+			wantTracked: 8 * time.Hour,
+		},
+		{
+			name:        "summer time",
+			start:       time.Date(2023, 7, 3, 8, 0, 0, 0, berlin),
+			end:         time.Date(2023, 7, 3, 16, 0, 0, 0, berlin),
+			wantStart:   "2023-07-03 08:00:00",
+			wantEnd:     "2023-07-03 16:00:00",
+			wantTracked: 8 * time.Hour,
+		},
+		{
+			name:        "converts from UTC",
+			start:       time.Date(2023, 7, 3, 6, 0, 0, 0, time.UTC),
+			end:         time.Date(2023, 7, 3, 14, 0, 0, 0, time.UTC),
+			wantStart:   "2023-07-03 08:00:00",
+			wantEnd:     "2023-07-03 16:00:00",
+			wantTracked: 8 * time.Hour,
+		},
+		{
+			name:        "clocks turned forward",
+			start:       time.Date(2023, 3, 26, 1, 0, 0, 0, berlin),
+			end:         time.Date(2023, 3, 26, 4, 0, 0, 0, berlin),
+			wantStart:   "2023-03-26 01:00:00",
+			wantEnd:     "2023-03-26 04:00:00",
+			wantTracked: 2 * time.Hour,
+		},
+		{
+			name:        "clocks turned back",
+			start:       time.Date(2023, 10, 29, 1, 0, 0, 0, berlin),
+			end:         time.Date(2023, 10, 29, 4, 0, 0, 0, berlin),
+			wantStart:   "2023-10-29 01:00:00",
+			wantEnd:     "2023-10-29 04:00:00",
+			wantTracked: 4 * time.Hour,
+		},
+		{
+			name:        "day after clocks turned back",
+			start:       time.Date(2023, 10, 30, 8, 0, 0, 0, berlin),
+			end:         time.Date(2023, 10, 30, 16, 0, 0, 0, berlin),
+			wantStart:   "2023-10-30 08:00:00",
+			wantEnd:     "2023-10-30 16:00:00",
+			wantTracked: 8 * time.Hour,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			server := personiotest.NewServer()
+			defer server.Close()
+			client := newLoggedInClient(t, server, personio.WithLocation(berlin))
+
+			date := tc.start.In(berlin)
+			periods := []personio.Period{{
+				Start: personio.PersonioTime{Time: tc.start},
+				End:   personio.PersonioTime{Time: tc.end},
+			}}
+			if err := client.SetAttendance(date, periods); err != nil {
+				t.Fatalf("set attendance: %s", err)
+			}
+
+			stored := server.Periods(date.Format(time.DateOnly))
+			if len(stored) != 1 {
+				t.Fatalf("want 1 stored period, got %d", len(stored))
+			}
+			if got := stored[0].Start.Format(time.DateTime); got != tc.wantStart {
+				t.Errorf("want stored start %q, got %q", tc.wantStart, got)
+			}
+			if got := stored[0].End.Format(time.DateTime); got != tc.wantEnd {
+				t.Errorf("want stored end %q, got %q", tc.wantEnd, got)
+			}
+
+			cal, err := client.GetMyAttendanceCalendar(date, date)
+			if err != nil {
+				t.Fatalf("get calendar: %s", err)
+			}
+			if len(cal) != 1 || len(cal[0].Periods) != 1 {
+				t.Fatalf("want 1 day with 1 period, got %+v", cal)
+			}
+			got := cal[0].Periods[0]
+			if !got.Start.Equal(tc.start) {
+				t.Errorf("want start %s, got %s", tc.start, got.Start.Time)
+			}
+			if !got.End.Equal(tc.end) {
+				t.Errorf("want end %s, got %s", tc.end, got.End.Time)
+			}
+			if got.Start.Location() != berlin {
+				t.Errorf("want start in location %s, got %s", berlin, got.Start.Location())
+			}
+			if tracked := cal[0].TrackedDuration(); tracked != tc.wantTracked {
+				t.Errorf("want tracked %s, got %s", tc.wantTracked, tracked)
+			}
+		})
+	}
+}
+
+func TestEditSinglePeriod(t *testing.T) {
+	server := personiotest.NewServer()
+	defer server.Close()
+
+	date := time.Date(2023, 1, 18, 0, 0, 0, 0, time.UTC)
+	server.SetPeriods("2023-01-18", []personio.Period{
+		{
+			Start: personio.PersonioTime{Time: date.Add(8 * time.Hour)},
+			End:   personio.PersonioTime{Time: date.Add(12 * time.Hour)},
+			Type:  personio.PeriodTypeWork,
+		},
+		{
+			Start: personio.PersonioTime{Time: date.Add(12 * time.Hour)},
+			End:   personio.PersonioTime{Time: date.Add(13 * time.Hour)},
+			Type:  personio.PeriodTypeBreak,
+		},
+		{
+			Start: personio.PersonioTime{Time: date.Add(13 * time.Hour)},
+			End:   personio.PersonioTime{Time: date.Add(17 * time.Hour)},
+			Type:  personio.PeriodTypeWork,
+		},
+	})
+	client := newLoggedInClient(t, server, personio.WithLocation(time.UTC))
+
+	card, err := client.GetMyAttendanceDay(date)
+	if err != nil {
+		t.Fatalf("get day: %s", err)
+	}
+	wantIDs := []uuid.UUID{card.Periods[0].ID, card.Periods[2].ID}
+	index, err := card.FindPeriod("3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	card.Periods[index].End.Time = date.Add(18 * time.Hour)
+	periods := slices.Delete(card.Periods, 1, 2)
+	if err := client.SetAttendance(date, periods); err != nil {
+		t.Fatalf("set attendance: %s", err)
+	}
+
+	stored := server.Periods("2023-01-18")
+	if len(stored) != 2 {
+		t.Fatalf("want 2 stored periods, got %d", len(stored))
+	}
+	for i, want := range wantIDs {
+		if stored[i].ID != want {
+			t.Errorf("period %d: want ID %s, got %s", i, want, stored[i].ID)
+		}
+	}
+	if got, want := stored[1].End.Format(time.DateTime), "2023-01-18 18:00:00"; got != want {
+		t.Errorf("want edited end %q, got %q", want, got)
+	}
+}
+
+func TestDeleteAttendance(t *testing.T) {
+	server := personiotest.NewServer()
+	defer server.Close()
+
+	date := time.Date(2023, 1, 18, 0, 0, 0, 0, time.UTC)
+	server.SetPeriods("2023-01-18", []personio.Period{{
+		Start: personio.PersonioTime{Time: date.Add(8 * time.Hour)},
+		End:   personio.PersonioTime{Time: date.Add(16 * time.Hour)},
+		Type:  personio.PeriodTypeWork,
+	}})
+	client := newLoggedInClient(t, server)
+
+	if err := client.DeleteAttendance(date); err != nil {
+		t.Fatalf("delete attendance: %s", err)
+	}
+	if _, ok := server.DayID("2023-01-18"); ok {
+		t.Error("want day to be deleted, but it still exists")
+	}
+}
+
+func TestAttendanceRequiresLogin(t *testing.T) {
+	server := personiotest.NewServer()
+	defer server.Close()
+	client, err := server.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.GetMyAttendanceCalendar(time.Now(), time.Now())
+	if !errors.Is(err, personio.ErrNotLoggedIn) {
+		t.Errorf("want ErrNotLoggedIn, got %v", err)
+	}
+}
+
+func TestGetProjectName(t *testing.T) {
+	server := personiotest.NewServer()
+	defer server.Close()
+	client := newLoggedInClient(t, server)
+
+	name, err := client.GetProjectName(2)
+	if err != nil {
+		t.Fatalf("get project name: %s", err)
+	}
+	if name != "Project Y" {
+		t.Errorf("want %q, got %q", "Project Y", name)
+	}
+	if _, err := client.GetProjectID("Nonexistent"); !errors.Is(err, personio.ErrProjectNotFound) {
+		t.Errorf("want %v for unknown project, got %v", personio.ErrProjectNotFound, err)
+	}
+	if _, err := client.GetProjectName(999); !errors.Is(err, personio.ErrProjectNotFound) {
+		t.Errorf("want %v for unknown project ID, got %v", personio.ErrProjectNotFound, err)
+	}
+}
+
+func TestSummarizeDays(t *testing.T) {
+	server := personiotest.NewServer()
+	defer server.Close()
+
+	date := time.Date(2023, 1, 18, 0, 0, 0, 0, time.UTC)
+	comment := "Lunch"
+	projectID := 2
+	server.SetPeriods("2023-01-18", []personio.Period{
+		{
+			Start:     personio.PersonioTime{Time: date.Add(8 * time.Hour)},
+			End:       personio.PersonioTime{Time: date.Add(12 * time.Hour)},
+			Type:      personio.PeriodTypeWork,
+			ProjectID: &projectID,
+		},
+		{
+			Start:   personio.PersonioTime{Time: date.Add(12 * time.Hour)},
+			End:     personio.PersonioTime{Time: date.Add(12*time.Hour + 30*time.Minute)},
+			Type:    personio.PeriodTypeBreak,
+			Comment: &comment,
+		},
+		{
+			Start: personio.PersonioTime{Time: date.Add(12*time.Hour + 30*time.Minute)},
+			End:   personio.PersonioTime{Time: date.Add(16 * time.Hour)},
+			Type:  personio.PeriodTypeWork,
+		},
+	})
+	client := newLoggedInClient(t, server)
+
+	cal, err := client.GetMyAttendanceCalendar(date, date.AddDate(0, 0, 1))
+	if err != nil {
+		t.Fatalf("get calendar: %s", err)
+	}
+	days, err := client.SummarizeDays(cal)
+	if err != nil {
+		t.Fatalf("summarize: %s", err)
+	}
+	if len(days) != 2 {
+		t.Fatalf("want 2 days, got %d", len(days))
+	}
+
+	day := days[0]
+	if len(day.Periods) != 3 {
+		t.Fatalf("want 3 periods, got %d", len(day.Periods))
+	}
+	if got := day.Periods[0].Project; got != "Project Y" {
+		t.Errorf("want first period project %q, got %q", "Project Y", got)
+	}
+	if got := day.Periods[1].Comment; got != comment {
+		t.Errorf("want second period comment %q, got %q", comment, got)
+	}
+	if got := day.Periods[1].DurationMinutes; got != 30 {
+		t.Errorf("want second period duration 30, got %d", got)
+	}
+	if len(day.Projects) != 2 {
+		t.Fatalf("want 2 projects, got %+v", day.Projects)
+	}
+
+	var tests = []struct {
+		name string
+		got  int
+		want int
+	}{
+		{"tracked", day.TrackedMinutes, 7*60 + 30},
+		{"breaks", day.BreakMinutes, 30},
+		{"target", day.TargetMinutes, 8 * 60},
+		{"difference", day.DifferenceMinutes, -30},
+		{"project Y", day.Projects[0].TrackedMinutes, 4 * 60},
+		{"no project", day.Projects[1].TrackedMinutes, 3*60 + 30},
+		{"next day periods", len(days[1].Periods), 0},
+	}
+	for _, tc := range tests {
+		if tc.got != tc.want {
+			t.Errorf("%s: want %d, got %d", tc.name, tc.want, tc.got)
+		}
+	}
+	if got := day.Projects[1].Project; got != "" {
+		t.Errorf("want no project name for periods without project, got %q", got)
+	}
+}
+
+func TestSummarizeDaysMissingProject(t *testing.T) {
+	server := personiotest.NewServer()
+	defer server.Close()
+
+	date := time.Date(2023, 1, 18, 0, 0, 0, 0, time.UTC)
+	// Such as an archived project, which is not listed by Personio
+	projectID := 999
+	server.SetPeriods("2023-01-18", []personio.Period{
+		{
+			Start:     personio.PersonioTime{Time: date.Add(8 * time.Hour)},
+			End:       personio.PersonioTime{Time: date.Add(12 * time.Hour)},
+			Type:      personio.PeriodTypeWork,
+			ProjectID: &projectID,
+		},
+	})
+	client := newLoggedInClient(t, server)
+
+	cal, err := client.GetMyAttendanceCalendar(date, date)
+	if err != nil {
+		t.Fatalf("get calendar: %s", err)
+	}
+	days, err := client.SummarizeDays(cal)
+	if err != nil {
+		t.Fatalf("summarize: %s", err)
+	}
+	if len(days) != 1 || len(days[0].Periods) != 1 || len(days[0].Projects) != 1 {
+		t.Fatalf("want 1 day with 1 period and project, got %+v", days)
+	}
+	if got, want := days[0].Periods[0].Project, "#999"; got != want {
+		t.Errorf("period: want project %q, got %q", want, got)
+	}
+	if got, want := days[0].Projects[0].Project, "#999"; got != want {
+		t.Errorf("projects: want project %q, got %q", want, got)
+	}
+}
+
+func TestGetMyEmployeeData(t *testing.T) {
+	server := personiotest.NewServer()
+	defer server.Close()
+	client := newLoggedInClient(t, server)
+
+	employee, err := client.GetMyEmployeeData()
+	if err != nil {
+		t.Fatalf("get employee: %s", err)
+	}
+	if employee.FirstName != server.Employee.FirstName || employee.ID != server.Employee.ID {
+		t.Errorf("want %+v, got %+v", server.Employee, employee)
+	}
+}
+
+func TestRawRejectsOtherHosts(t *testing.T) {
+	server := personiotest.NewServer()
+	defer server.Close()
+	client := newLoggedInClient(t, server)
+
+	req, err := http.NewRequest(http.MethodGet, "https://evil.example.com/steal", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Raw(req); !errors.Is(err, personio.ErrHostNotAllowed) {
+		t.Errorf("want ErrHostNotAllowed, got %v", err)
+	}
+	for _, r := range server.Requests() {
+		if r.Host == "evil.example.com" {
+			t.Error("request was sent to non-Personio host")
+		}
+	}
+}
+
+func TestContextCanceled(t *testing.T) {
+	server := personiotest.NewServer()
+	defer server.Close()
+	client := newLoggedInClient(t, server)
+	requestsBefore := len(server.Requests())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	date := time.Date(2023, 1, 18, 0, 0, 0, 0, time.UTC)
+	if _, err := client.GetMyAttendanceCalendarContext(ctx, date, date); !errors.Is(err, context.Canceled) {
+		t.Errorf("get calendar: want context.Canceled, got %v", err)
+	}
+	if err := client.SetAttendanceContext(ctx, date, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("set attendance: want context.Canceled, got %v", err)
+	}
+	if _, err := client.GetProjectIDContext(ctx, "Project X"); !errors.Is(err, context.Canceled) {
+		t.Errorf("get project ID: want context.Canceled, got %v", err)
+	}
+	if err := client.CheckSessionContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("check session: want context.Canceled, got %v", err)
+	} else if !errors.Is(err, personio.ErrNotLoggedIn) {
+		t.Errorf("check session: want ErrNotLoggedIn, got %v", err)
+	}
+
+	other, err := server.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := other.LoginContext(ctx, server.Auth()); !errors.Is(err, context.Canceled) {
+		t.Errorf("login: want context.Canceled, got %v", err)
+	}
+
+	if got := len(server.Requests()); got != requestsBefore {
+		t.Errorf("want no requests sent after cancel, got %d", got-requestsBefore)
+	}
+}
+
+func TestDiagnose(t *testing.T) {
+	var tests = []struct {
+		name       string
+		setup      func(server *personiotest.Server, auth *config.Auth)
+		wantOK     bool
+		wantStatus map[string]personio.DiagnosticStatus
+	}{
+		{
+			name:   "ok",
+			wantOK: true,
+			wantStatus: map[string]personio.DiagnosticStatus{
+				personio.DiagnoseStepCredentials:   personio.DiagnosticOK,
+				personio.DiagnoseStepBaseURL:       personio.DiagnosticOK,
+				personio.DiagnoseStepLoginRedirect: personio.DiagnosticOK,
+				personio.DiagnoseStepIdentifier:    personio.DiagnosticOK,
+				personio.DiagnoseStepPassword:      personio.DiagnosticOK,
+				personio.DiagnoseStepMFA:           personio.DiagnosticOK,
+				personio.DiagnoseStepOtherPages:    personio.DiagnosticOK,
+				personio.DiagnoseStepEmployeeID:    personio.DiagnosticOK,
+				personio.DiagnoseStepXSRFCookies:   personio.DiagnosticOK,
+			},
+		},
+		{
+			name: "unknown email",
+			setup: func(server *personiotest.Server, auth *config.Auth) {
+				auth.Email = "john.doe@example.com"
+			},
+			wantStatus: map[string]personio.DiagnosticStatus{
+				personio.DiagnoseStepIdentifier: personio.DiagnosticFailed,
+				personio.DiagnoseStepPassword:   personio.DiagnosticSkipped,
+				personio.DiagnoseStepEmployeeID: personio.DiagnosticSkipped,
+			},
+		},
+		{
+			name: "wrong password",
+			setup: func(server *personiotest.Server, auth *config.Auth) {
+				auth.Password = "wrong"
+			},
+			wantStatus: map[string]personio.DiagnosticStatus{
+				personio.DiagnoseStepIdentifier:  personio.DiagnosticOK,
+				personio.DiagnoseStepPassword:    personio.DiagnosticFailed,
+				personio.DiagnoseStepMFA:         personio.DiagnosticSkipped,
+				personio.DiagnoseStepXSRFCookies: personio.DiagnosticSkipped,
+			},
+		},
+		{
+			name: "TOTP",
+			setup: func(server *personiotest.Server, auth *config.Auth) {
+				server.TOTPSecret = "JBSWY3DPEHPK3PXP"
+				auth.TOTPSecret = server.TOTPSecret
+			},
+			wantOK: true,
+			wantStatus: map[string]personio.DiagnosticStatus{
+				personio.DiagnoseStepMFA:        personio.DiagnosticOK,
+				personio.DiagnoseStepEmployeeID: personio.DiagnosticOK,
+			},
+		},
+		{
+			name: "unlock required",
+			setup: func(server *personiotest.Server, auth *config.Auth) {
+				server.EmailToken = "123456"
+			},
+			wantStatus: map[string]personio.DiagnosticStatus{
+				personio.DiagnoseStepPassword:   personio.DiagnosticOK,
+				personio.DiagnoseStepMFA:        personio.DiagnosticFailed,
+				personio.DiagnoseStepEmployeeID: personio.DiagnosticSkipped,
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			server := personiotest.NewServer()
+			defer server.Close()
+			auth := server.Auth()
+			if tc.setup != nil {
+				tc.setup(server, &auth)
+			}
+			client, err := server.NewClient()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			diagnosis := client.Diagnose(auth)
+			if diagnosis.OK != tc.wantOK {
+				t.Errorf("want OK %t, got %t: %+v", tc.wantOK, diagnosis.OK, diagnosis.Steps)
+			}
+			for name, want := range tc.wantStatus {
+				step, ok := diagnosis.Step(name)
+				if !ok {
+					t.Errorf("%s: step not found", name)
+				} else if step.Status != want {
+					t.Errorf("%s: want %s, got %s: %s", name, want, step.Status, step.Message)
+				}
+			}
+			if tc.wantOK && client.EmployeeID != personiotest.DefaultEmployeeID {
+				t.Errorf("want employee ID %d, got %d", personiotest.DefaultEmployeeID, client.EmployeeID)
+			}
+		})
+	}
+}
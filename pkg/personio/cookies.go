@@ -0,0 +1,259 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package personio
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	ErrNoCookiesImported = errors.New("no cookies for Personio found")
+)
+
+// BrowserCookie is a cookie exported from a web browser.
+type BrowserCookie struct {
+	Domain string `json:"domain"`
+	// HostOnly is true if the cookie is only sent to the exact domain,
+	// and not to its subdomains.
+	HostOnly bool      `json:"hostOnly"`
+	Path     string    `json:"path"`
+	Name     string    `json:"name"`
+	Value    string    `json:"value"`
+	Secure   bool      `json:"secure"`
+	HTTPOnly bool      `json:"httpOnly"`
+	Expires  time.Time `json:"expires,omitempty"`
+}
+
+// ParseCookies reads cookies exported from a web browser, either in the
+// Netscape cookies.txt format, or as a JSON array of cookies as written
+// by browser extensions such as Cookie-Editor. A JSON object with a
+// "cookies" array, as written by e.g Playwright, is also accepted.
+func ParseCookies(r io.Reader) ([]BrowserCookie, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	trimmed := bytes.TrimSpace(b)
+	if len(trimmed) > 0 && (trimmed[0] == '[' || trimmed[0] == '{') {
+		return ParseJSONCookies(bytes.NewReader(trimmed))
+	}
+	return ParseNetscapeCookies(bytes.NewReader(b))
+}
+
+// ParseNetscapeCookies reads cookies in the Netscape cookies.txt format,
+// as written by curl and wget, with one tab-separated cookie per line:
+//
+//	domain  includeSubdomains  path  secure  expires  name  value
+func ParseNetscapeCookies(r io.Reader) ([]BrowserCookie, error) {
+	var cookies []BrowserCookie
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimRight(scanner.Text(), "\r")
+		httpOnly := false
+		if rest, ok := strings.CutPrefix(line, "#HttpOnly_"); ok {
+			line = rest
+			httpOnly = true
+		}
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) == 6 {
+			// Cookie without a value
+			fields = append(fields, "")
+		}
+		if len(fields) != 7 {
+			return nil, fmt.Errorf("line %d: want 7 tab-separated fields, got %d", lineNum, len(fields))
+		}
+		expires, err := strconv.ParseInt(fields[4], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: parse expiry: %w", lineNum, err)
+		}
+		cookie := BrowserCookie{
+			Domain:   fields[0],
+			HostOnly: !strings.EqualFold(fields[1], "TRUE"),
+			Path:     fields[2],
+			Secure:   strings.EqualFold(fields[3], "TRUE"),
+			Name:     fields[5],
+			Value:    fields[6],
+			HTTPOnly: httpOnly,
+		}
+		if expires > 0 {
+			cookie.Expires = time.Unix(expires, 0)
+		}
+		cookies = append(cookies, cookie)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return cookies, nil
+}
+
+type jsonCookie struct {
+	Domain   string `json:"domain"`
+	Path     string `json:"path"`
+	Name     string `json:"name"`
+	Value    string `json:"value"`
+	Secure   bool   `json:"secure"`
+	HTTPOnly bool   `json:"httpOnly"`
+	HostOnly bool   `json:"hostOnly"`
+	// ExpirationDate is used by Cookie-Editor and EditThisCookie.
+	ExpirationDate float64 `json:"expirationDate"`
+	// Expires is used by Playwright and Puppeteer, where -1 means
+	// a session cookie.
+	Expires float64 `json:"expires"`
+	Session bool    `json:"session"`
+}
+
+// ParseJSONCookies reads a JSON array of cookies, or a JSON object with
+// a "cookies" array, as exported by browser extensions and automation tools.
+func ParseJSONCookies(r io.Reader) ([]BrowserCookie, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var jsonCookies []jsonCookie
+	if trimmed := bytes.TrimSpace(b); len(trimmed) > 0 && trimmed[0] == '{' {
+		var wrapper struct {
+			Cookies []jsonCookie `json:"cookies"`
+		}
+		if err := json.Unmarshal(trimmed, &wrapper); err != nil {
+			return nil, fmt.Errorf("parse JSON cookies: %w", err)
+		}
+		jsonCookies = wrapper.Cookies
+	} else if err := json.Unmarshal(trimmed, &jsonCookies); err != nil {
+		return nil, fmt.Errorf("parse JSON cookies: %w", err)
+	}
+
+	cookies := make([]BrowserCookie, 0, len(jsonCookies))
+	for i, jc := range jsonCookies {
+		if jc.Name == "" || jc.Domain == "" {
+			return nil, fmt.Errorf("cookie #%d: missing name or domain", i+1)
+		}
+		cookie := BrowserCookie{
+			Domain:   jc.Domain,
+			HostOnly: jc.HostOnly,
+			Path:     jc.Path,
+			Name:     jc.Name,
+			Value:    jc.Value,
+			Secure:   jc.Secure,
+			HTTPOnly: jc.HTTPOnly,
+		}
+		expires := jc.ExpirationDate
+		if expires == 0 {
+			expires = jc.Expires
+		}
+		if !jc.Session && expires > 0 {
+			sec, frac := math.Modf(expires)
+			cookie.Expires = time.Unix(int64(sec), int64(frac*1e9))
+		}
+		cookies = append(cookies, cookie)
+	}
+	return cookies, nil
+}
+
+// ImportCookies loads cookies exported from a web browser into the client,
+// such as from [ParseCookies], to reuse a session from the browser instead
+// of logging in. Cookies for hosts that the client does not send requests
+// to are ignored, as are expired cookies.
+//
+// Returns the number of imported cookies, or [ErrNoCookiesImported] if
+// none were imported. The cookies are not validated. Use
+// [Client.CheckSession] to verify them, which also resolves the
+// client's employee ID.
+func (c *Client) ImportCookies(cookies []BrowserCookie) (int, error) {
+	baseURL, err := url.Parse(c.BaseURL)
+	if err != nil {
+		return 0, fmt.Errorf("parse base URL: %w", err)
+	}
+	now := time.Now()
+	imported := 0
+	for _, cookie := range cookies {
+		host := strings.TrimPrefix(cookie.Domain, ".")
+		if !c.isAllowedCookieHost(baseURL, host) {
+			log.Trace().Str("domain", host).Str("name", cookie.Name).
+				Msg("Ignoring cookie for other host.")
+			continue
+		}
+		if !cookie.Expires.IsZero() && cookie.Expires.Before(now) {
+			log.Debug().Str("domain", host).Str("name", cookie.Name).
+				Msg("Ignoring expired cookie.")
+			continue
+		}
+		httpCookie := &http.Cookie{
+			Name:     cookie.Name,
+			Value:    cookie.Value,
+			Path:     cookie.Path,
+			Domain:   host,
+			Expires:  cookie.Expires,
+			Secure:   cookie.Secure,
+			HttpOnly: cookie.HTTPOnly,
+		}
+		if cookie.HostOnly {
+			httpCookie.Domain = ""
+		}
+		if httpCookie.Path == "" {
+			httpCookie.Path = "/"
+		}
+		u := &url.URL{Scheme: baseURL.Scheme, Host: host, Path: httpCookie.Path}
+		c.http.Jar.SetCookies(u, []*http.Cookie{httpCookie})
+		imported++
+	}
+	if imported == 0 {
+		return 0, ErrNoCookiesImported
+	}
+	return imported, nil
+}
+
+// isAllowedCookieHost returns true if a cookie for the domain would be sent
+// to any of the hosts that the client accesses. The port of the base URL
+// is ignored, as cookies do not have ports.
+func (c *Client) isAllowedCookieHost(baseURL *url.URL, domain string) bool {
+	if domain == "" {
+		return false
+	}
+	if c.isAllowedHost(baseURL, domain) {
+		return true
+	}
+	for _, rawURL := range []string{c.BaseURL, c.LoginURL} {
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			continue
+		}
+		hostname := u.Hostname()
+		if hostname == domain || strings.HasSuffix(hostname, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,173 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package personio
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseCookies(t *testing.T) {
+	var tests = []struct {
+		name  string
+		input string
+		want  []BrowserCookie
+	}{
+		{
+			name: "netscape",
+			input: "# Netscape HTTP Cookie File\n" +
+				"# https://curl.se/docs/http-cookies.html\n" +
+				"\n" +
+				"#HttpOnly_example.personio.de\tFALSE\t/\tTRUE\t1700000000\tpersonio_session\tabc123\n" +
+				".personio.de\tTRUE\t/\tTRUE\t0\tXSRF-TOKEN\txsrf%3D\n" +
+				"example.personio.de\tFALSE\t/api\tFALSE\t1700000000\tempty\t\n",
+			want: []BrowserCookie{
+				{
+					Domain:   "example.personio.de",
+					HostOnly: true,
+					Path:     "/",
+					Name:     "personio_session",
+					Value:    "abc123",
+					Secure:   true,
+					HTTPOnly: true,
+					Expires:  time.Unix(1700000000, 0),
+				},
+				{
+					Domain: ".personio.de",
+					Path:   "/",
+					Name:   "XSRF-TOKEN",
+					Value:  "xsrf%3D",
+					Secure: true,
+				},
+				{
+					Domain:   "example.personio.de",
+					HostOnly: true,
+					Path:     "/api",
+					Name:     "empty",
+					Expires:  time.Unix(1700000000, 0),
+				},
+			},
+		},
+		{
+			name: "JSON array",
+			input: `[
+				{
+					"domain": "example.personio.de",
+					"expirationDate": 1700000000.5,
+					"hostOnly": true,
+					"httpOnly": true,
+					"name": "personio_session",
+					"path": "/",
+					"secure": true,
+					"session": false,
+					"value": "abc123"
+				},
+				{
+					"domain": ".personio.de",
+					"hostOnly": false,
+					"name": "XSRF-TOKEN",
+					"path": "/",
+					"session": true,
+					"value": "xsrf"
+				}
+			]`,
+			want: []BrowserCookie{
+				{
+					Domain:   "example.personio.de",
+					HostOnly: true,
+					Path:     "/",
+					Name:     "personio_session",
+					Value:    "abc123",
+					Secure:   true,
+					HTTPOnly: true,
+					Expires:  time.Unix(1700000000, 5e8),
+				},
+				{
+					Domain: ".personio.de",
+					Path:   "/",
+					Name:   "XSRF-TOKEN",
+					Value:  "xsrf",
+				},
+			},
+		},
+		{
+			name: "JSON object",
+			input: `{"cookies": [
+				{"name": "personio_session", "value": "abc123", "domain": "example.personio.de", "path": "/", "expires": -1}
+			]}`,
+			want: []BrowserCookie{
+				{
+					Domain: "example.personio.de",
+					Path:   "/",
+					Name:   "personio_session",
+					Value:  "abc123",
+				},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ParseCookies(strings.NewReader(tc.input))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(tc.want, got) {
+				t.Errorf("want:\n%+v\ngot:\n%+v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestParseCookiesInvalid(t *testing.T) {
+	var tests = []struct {
+		name    string
+		input   string
+		wantErr string
+	}{
+		{
+			name:    "too few fields",
+			input:   "example.personio.de\tFALSE\t/\n",
+			wantErr: "line 1: want 7 tab-separated fields, got 3",
+		},
+		{
+			name:    "invalid expiry",
+			input:   "# comment\nexample.personio.de\tFALSE\t/\tTRUE\tnever\tname\tvalue\n",
+			wantErr: "line 2: parse expiry",
+		},
+		{
+			name:    "JSON without domain",
+			input:   `[{"name": "personio_session", "value": "abc123"}]`,
+			wantErr: "cookie #1: missing name or domain",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ParseCookies(strings.NewReader(tc.input))
+			if err == nil {
+				t.Fatalf("want error %q, got nil", tc.wantErr)
+			}
+			if !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("want error %q, got %q", tc.wantErr, err)
+			}
+		})
+	}
+}
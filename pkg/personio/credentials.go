@@ -0,0 +1,273 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package personio
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/applejag/rootless-personio/pkg/config"
+	"golang.org/x/term"
+)
+
+var (
+	ErrMissingCredentials = errors.New("missing credentials")
+)
+
+// PassphraseEnv is the environment variable read by
+// [EncryptedFileCredentials] before prompting for the passphrase.
+const PassphraseEnv = "PERSONIO_CREDENTIALS_PASSPHRASE"
+
+// Credentials are the values used when logging in.
+type Credentials struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+	// TOTP is a 2FA code, for providers that can generate them.
+	TOTP string `json:"-"`
+	// TOTPSecret is used to generate 2FA codes, for providers that store it.
+	TOTPSecret string `json:"totpSecret,omitempty"`
+}
+
+// CredentialProvider is a source of login credentials.
+type CredentialProvider interface {
+	Credentials() (Credentials, error)
+}
+
+// NewCredentialProvider returns the [CredentialProvider] selected in the
+// config. The login and base URLs are used to look up the KeePassXC entry.
+func NewCredentialProvider(auth config.Auth, loginURL, baseURL string) (CredentialProvider, error) {
+	source := auth.Source
+	if auth.Keepass {
+		source = config.CredentialSourceKeepass
+	}
+	switch source {
+	case config.CredentialSourceConfig, "":
+		return StaticCredentials{
+			Email:      auth.Email,
+			Password:   auth.Password,
+			TOTPSecret: auth.TOTPSecret,
+		}, nil
+	case config.CredentialSourceKeepass:
+		return KeepassCredentials{
+			URLs: []string{loginURL + "/", baseURL},
+		}, nil
+	case config.CredentialSourceCommand:
+		return CommandCredentials{
+			Email:           auth.Email,
+			PasswordCommand: auth.PasswordCommand,
+		}, nil
+	case config.CredentialSourceEnvFile:
+		return EnvFileCredentials{Path: auth.EnvFile}, nil
+	case config.CredentialSourceEncryptedFile:
+		return EncryptedFileCredentials{Path: auth.EncryptedFile}, nil
+	default:
+		return nil, fmt.Errorf("unknown credential source: %q", source)
+	}
+}
+
+// StaticCredentials provides the credentials as-is, such as when they are
+// written in plain text in the config file.
+type StaticCredentials Credentials
+
+// Credentials implements [CredentialProvider].
+func (s StaticCredentials) Credentials() (Credentials, error) {
+	var missing []string
+	if s.Email == "" {
+		missing = append(missing, "email (auth.email config or PERSONIO_AUTH_EMAIL env var)")
+	}
+	if s.Password == "" {
+		missing = append(missing, "password (auth.password config or PERSONIO_AUTH_PASSWORD env var)")
+	}
+	if len(missing) > 0 {
+		return Credentials{}, fmt.Errorf("%w: %s", ErrMissingCredentials, strings.Join(missing, ", "))
+	}
+	return Credentials(s), nil
+}
+
+// KeepassCredentials fetches the credentials and 2FA code from a running
+// KeePassXC instance, using the first of the URLs that has a matching entry.
+type KeepassCredentials struct {
+	URLs []string
+}
+
+// Credentials implements [CredentialProvider].
+func (k KeepassCredentials) Credentials() (Credentials, error) {
+	var errs []error
+	for _, url := range k.URLs {
+		user, pw, tfa, err := fetchKeepassCredentials(url)
+		if err == nil {
+			return Credentials{Email: user, Password: pw, TOTP: tfa}, nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", url, err))
+	}
+	return Credentials{}, fmt.Errorf("KeePassXC: %w", errors.Join(errs...))
+}
+
+// CommandCredentials runs a shell command to obtain the password,
+// such as "pass show personio". Only the first line of the output is used.
+type CommandCredentials struct {
+	Email           string
+	PasswordCommand string
+}
+
+// Credentials implements [CredentialProvider].
+func (c CommandCredentials) Credentials() (Credentials, error) {
+	if c.Email == "" {
+		return Credentials{}, fmt.Errorf("%w: email (auth.email config or PERSONIO_AUTH_EMAIL env var)", ErrMissingCredentials)
+	}
+	if c.PasswordCommand == "" {
+		return Credentials{}, fmt.Errorf("%w: password command (auth.passwordCommand config or PERSONIO_AUTH_PASSWORDCOMMAND env var)", ErrMissingCredentials)
+	}
+	password, err := runShellCommand(c.PasswordCommand)
+	if err != nil {
+		return Credentials{}, fmt.Errorf("get password: %w", err)
+	}
+	if password == "" {
+		return Credentials{}, fmt.Errorf("%w: password command printed nothing", ErrMissingCredentials)
+	}
+	return Credentials{Email: c.Email, Password: password}, nil
+}
+
+// EnvFileCredentials reads the credentials from a file of KEY=VALUE lines,
+// using the same variable names as the environment variables:
+//
+//	PERSONIO_AUTH_EMAIL=firstname.lastname@example.com
+//	PERSONIO_AUTH_PASSWORD=SuperSecretPassword1234
+//	PERSONIO_AUTH_TOTPSECRET=JBSWY3DPEHPK3PXP
+//
+// The path can also be "fd:N" to read from an inherited file descriptor,
+// e.g "fd:3" together with "3< <(pass show personio-env)" in Bash.
+type EnvFileCredentials struct {
+	Path string
+}
+
+// Credentials implements [CredentialProvider].
+func (e EnvFileCredentials) Credentials() (Credentials, error) {
+	if e.Path == "" {
+		return Credentials{}, fmt.Errorf("%w: env file path (auth.envFile config or PERSONIO_AUTH_ENVFILE env var)", ErrMissingCredentials)
+	}
+	r, err := openEnvFile(e.Path)
+	if err != nil {
+		return Credentials{}, err
+	}
+	defer r.Close()
+	values, err := parseEnvFile(r)
+	if err != nil {
+		return Credentials{}, fmt.Errorf("parse env file: %w", err)
+	}
+	return StaticCredentials{
+		Email:      values["PERSONIO_AUTH_EMAIL"],
+		Password:   values["PERSONIO_AUTH_PASSWORD"],
+		TOTPSecret: values["PERSONIO_AUTH_TOTPSECRET"],
+	}.Credentials()
+}
+
+func openEnvFile(path string) (io.ReadCloser, error) {
+	if fdStr, ok := strings.CutPrefix(path, "fd:"); ok {
+		fd, err := strconv.ParseUint(fdStr, 10, 0)
+		if err != nil {
+			return nil, fmt.Errorf("parse file descriptor: %w", err)
+		}
+		f := os.NewFile(uintptr(fd), path)
+		if f == nil {
+			return nil, fmt.Errorf("invalid file descriptor: %d", fd)
+		}
+		return f, nil
+	}
+	return os.Open(path)
+}
+
+func parseEnvFile(r io.Reader) (map[string]string, error) {
+	values := map[string]string{}
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected KEY=VALUE", lineNum)
+		}
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		values[strings.TrimSpace(key)] = value
+	}
+	return values, scanner.Err()
+}
+
+// EncryptedFileCredentials reads the credentials from a file encrypted with
+// a passphrase, as written by [EncryptCredentials].
+//
+// If Passphrase is nil, then the passphrase is read from the
+// PERSONIO_CREDENTIALS_PASSPHRASE environment variable, or prompted for
+// when running in a terminal.
+type EncryptedFileCredentials struct {
+	Path       string
+	Passphrase func() (string, error)
+}
+
+// Credentials implements [CredentialProvider].
+func (e EncryptedFileCredentials) Credentials() (Credentials, error) {
+	if e.Path == "" {
+		return Credentials{}, fmt.Errorf("%w: encrypted file path (auth.encryptedFile config or PERSONIO_AUTH_ENCRYPTEDFILE env var)", ErrMissingCredentials)
+	}
+	data, err := os.ReadFile(e.Path)
+	if err != nil {
+		return Credentials{}, err
+	}
+	getPassphrase := e.Passphrase
+	if getPassphrase == nil {
+		getPassphrase = promptPassphrase
+	}
+	passphrase, err := getPassphrase()
+	if err != nil {
+		return Credentials{}, fmt.Errorf("get passphrase: %w", err)
+	}
+	creds, err := DecryptCredentials(data, passphrase)
+	if err != nil {
+		return Credentials{}, err
+	}
+	return StaticCredentials(creds).Credentials()
+}
+
+func promptPassphrase() (string, error) {
+	if passphrase := os.Getenv(PassphraseEnv); passphrase != "" {
+		return passphrase, nil
+	}
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return "", fmt.Errorf("not running in a terminal, and %s is not set", PassphraseEnv)
+	}
+	fmt.Fprint(os.Stderr, "Credentials passphrase: ")
+	b, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
@@ -0,0 +1,148 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package personio
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+
+	"github.com/applejag/rootless-personio/pkg/config"
+)
+
+func TestStaticCredentialsMissing(t *testing.T) {
+	_, err := StaticCredentials{Email: "me@example.com"}.Credentials()
+	if !errors.Is(err, ErrMissingCredentials) {
+		t.Errorf("want ErrMissingCredentials, got %v", err)
+	}
+}
+
+func TestEnvFileCredentials(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "personio.env")
+	content := `# Personio login
+PERSONIO_AUTH_EMAIL=me@example.com
+export PERSONIO_AUTH_PASSWORD="hunter2 with spaces"
+PERSONIO_AUTH_TOTPSECRET='JBSWY3DPEHPK3PXP'
+`
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := EnvFileCredentials{Path: path}.Credentials()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	want := Credentials{
+		Email:      "me@example.com",
+		Password:   "hunter2 with spaces",
+		TOTPSecret: "JBSWY3DPEHPK3PXP",
+	}
+	if got != want {
+		t.Errorf("want %+v, got %+v", want, got)
+	}
+}
+
+func TestCommandCredentials(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test uses a POSIX shell command")
+	}
+	got, err := CommandCredentials{
+		Email:           "me@example.com",
+		PasswordCommand: "printf 'hunter2\\nsome: metadata\\n'",
+	}.Credentials()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if got.Password != "hunter2" {
+		t.Errorf("want password %q, got %q", "hunter2", got.Password)
+	}
+}
+
+func TestEncryptedFileCredentials(t *testing.T) {
+	want := Credentials{
+		Email:      "me@example.com",
+		Password:   "hunter2",
+		TOTPSecret: "JBSWY3DPEHPK3PXP",
+	}
+	data, err := EncryptCredentials(want, "correct horse")
+	if err != nil {
+		t.Fatalf("encrypt: %s", err)
+	}
+	path := filepath.Join(t.TempDir(), "credentials.json")
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := EncryptedFileCredentials{
+		Path:       path,
+		Passphrase: func() (string, error) { return "correct horse", nil },
+	}.Credentials()
+	if err != nil {
+		t.Fatalf("decrypt: %s", err)
+	}
+	if got != want {
+		t.Errorf("want %+v, got %+v", want, got)
+	}
+
+	_, err = EncryptedFileCredentials{
+		Path:       path,
+		Passphrase: func() (string, error) { return "wrong horse", nil },
+	}.Credentials()
+	if !errors.Is(err, ErrWrongPassphrase) {
+		t.Errorf("want ErrWrongPassphrase, got %v", err)
+	}
+}
+
+func TestNewCredentialProvider(t *testing.T) {
+	var tests = []struct {
+		name string
+		auth config.Auth
+		want CredentialProvider
+	}{
+		{
+			name: "default is config",
+			auth: config.Auth{Email: "me@example.com", Password: "hunter2"},
+			want: StaticCredentials{Email: "me@example.com", Password: "hunter2"},
+		},
+		{
+			name: "legacy keepass bool",
+			auth: config.Auth{Keepass: true},
+			want: KeepassCredentials{URLs: []string{"https://login.personio.com/", "https://example.personio.de"}},
+		},
+		{
+			name: "env file",
+			auth: config.Auth{Source: config.CredentialSourceEnvFile, EnvFile: "fd:3"},
+			want: EnvFileCredentials{Path: "fd:3"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := NewCredentialProvider(tc.auth, DefaultLoginURL, "https://example.personio.de")
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("want %+v, got %+v", tc.want, got)
+			}
+		})
+	}
+}
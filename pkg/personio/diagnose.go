@@ -0,0 +1,279 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package personio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/applejag/rootless-personio/pkg/config"
+)
+
+// DiagnosticStatus is the result of a [DiagnosticStep].
+type DiagnosticStatus string
+
+// Available [DiagnosticStatus] values.
+const (
+	DiagnosticOK      DiagnosticStatus = "ok"
+	DiagnosticWarning DiagnosticStatus = "warning"
+	DiagnosticFailed  DiagnosticStatus = "failed"
+	DiagnosticSkipped DiagnosticStatus = "skipped"
+)
+
+// Names of the steps in a [Diagnosis], in the order they are run.
+const (
+	DiagnoseStepCredentials   = "credentials"
+	DiagnoseStepBaseURL       = "base URL"
+	DiagnoseStepLoginRedirect = "login redirect"
+	DiagnoseStepIdentifier    = "identifier"
+	DiagnoseStepPassword      = "password"
+	DiagnoseStepMFA           = "MFA"
+	DiagnoseStepOtherPages    = "other login pages"
+	DiagnoseStepEmployeeID    = "employee ID"
+	DiagnoseStepXSRFCookies   = "XSRF cookies"
+)
+
+var diagnoseSteps = []string{
+	DiagnoseStepCredentials,
+	DiagnoseStepBaseURL,
+	DiagnoseStepLoginRedirect,
+	DiagnoseStepIdentifier,
+	DiagnoseStepPassword,
+	DiagnoseStepMFA,
+	DiagnoseStepOtherPages,
+	DiagnoseStepEmployeeID,
+	DiagnoseStepXSRFCookies,
+}
+
+// DiagnosticStep is the result of one step of the login.
+type DiagnosticStep struct {
+	Name    string           `json:"name"`
+	Status  DiagnosticStatus `json:"status"`
+	Message string           `json:"message,omitempty"`
+}
+
+// Diagnosis is the result of [Client.Diagnose].
+//
+// It does not contain any credentials, so it can be attached to bug reports.
+type Diagnosis struct {
+	// OK is true if no step failed.
+	OK    bool             `json:"ok"`
+	Steps []DiagnosticStep `json:"steps"`
+}
+
+// Step returns the step with the given name, or false if there is none.
+func (d *Diagnosis) Step(name string) (DiagnosticStep, bool) {
+	i := slices.IndexFunc(d.Steps, func(s DiagnosticStep) bool { return s.Name == name })
+	if i == -1 {
+		return DiagnosticStep{}, false
+	}
+	return d.Steps[i], true
+}
+
+func (d *Diagnosis) add(name string, status DiagnosticStatus, format string, args ...any) {
+	d.Steps = append(d.Steps, DiagnosticStep{
+		Name:    name,
+		Status:  status,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+func (d *Diagnosis) failed() bool {
+	return slices.ContainsFunc(d.Steps, func(s DiagnosticStep) bool {
+		return s.Status == DiagnosticFailed
+	})
+}
+
+// finish skips all steps that were not run, and sets the OK field.
+func (d *Diagnosis) finish() *Diagnosis {
+	for _, name := range diagnoseSteps {
+		if _, ok := d.Step(name); !ok {
+			d.add(name, DiagnosticSkipped, "not reached")
+		}
+	}
+	d.OK = !d.failed()
+	return d
+}
+
+// Diagnose calls [Client.DiagnoseContext] with [context.Background].
+func (c *Client) Diagnose(auth config.Auth) *Diagnosis {
+	return c.DiagnoseContext(context.Background(), auth)
+}
+
+// DiagnoseContext logs in like [Client.LoginContext], but reports the
+// result of each step of the login instead of only the first error,
+// to help finding out why the login fails.
+//
+// The client is logged in afterwards if the diagnosis is OK.
+func (c *Client) DiagnoseContext(ctx context.Context, auth config.Auth) *Diagnosis {
+	d := &Diagnosis{}
+	creds, err := c.fetchCredentials(auth)
+	if err != nil {
+		d.add(DiagnoseStepCredentials, DiagnosticFailed, "%s", err)
+		return d.finish()
+	}
+	switch {
+	case creds.Email == "":
+		d.add(DiagnoseStepCredentials, DiagnosticWarning, "email is empty")
+	case creds.Password == "":
+		d.add(DiagnoseStepCredentials, DiagnosticWarning, "password is empty")
+	case creds.TOTP != "" || creds.TOTPSecret != "" || auth.TOTPSecretCommand != "":
+		d.add(DiagnoseStepCredentials, DiagnosticOK, "found email, password and 2FA")
+	default:
+		d.add(DiagnoseStepCredentials, DiagnosticOK, "found email and password")
+	}
+
+	var (
+		startResp *http.Response
+		startErr  error
+		submitted []string
+	)
+	loginErr := c.runLoginFlow(ctx, &loginFlow{
+		auth:   auth,
+		creds:  creds,
+		prompt: promptTerminal,
+		onStartPage: func(resp *http.Response, err error) {
+			startResp, startErr = resp, err
+		},
+		onSubmit: func(handler string, _ *loginPage) {
+			submitted = append(submitted, handler)
+		},
+	})
+
+	if startResp == nil || startErr != nil {
+		if startErr == nil {
+			startErr = loginErr
+		}
+		d.add(DiagnoseStepBaseURL, DiagnosticFailed, "%s", startErr)
+		return d.finish()
+	}
+	d.add(DiagnoseStepBaseURL, DiagnosticOK, "responded with %s", startResp.Status)
+	c.diagnoseLoginRedirect(d, startResp)
+
+	reachedApp := loginErr == nil || errors.Is(loginErr, ErrEmployeeIDNotFound)
+	var unknownPage *UnknownLoginPageError
+	// failedPage is the last submitted page, if submitting it failed
+	var failedPage string
+	if !reachedApp && !errors.As(loginErr, &unknownPage) &&
+		!errors.Is(loginErr, ErrUnlockRequired) && len(submitted) > 0 {
+		failedPage = submitted[len(submitted)-1]
+	}
+
+	diagnosePage := func(name, handler string) {
+		switch {
+		case handler == failedPage:
+			d.add(name, DiagnosticFailed, "%s", loginErr)
+		case slices.Contains(submitted, handler):
+			d.add(name, DiagnosticOK, "accepted")
+		case reachedApp:
+			d.add(name, DiagnosticSkipped, "page was not shown")
+		}
+	}
+	diagnosePage(DiagnoseStepIdentifier, identifierPageHandler.name)
+	diagnosePage(DiagnoseStepPassword, passwordPageHandler.name)
+
+	mfaPages := slices.DeleteFunc(slices.Clone(submitted), func(handler string) bool {
+		return handler != otpPageHandler.name && handler != emailCodePageHandler.name
+	})
+	switch {
+	case failedPage == otpPageHandler.name || failedPage == emailCodePageHandler.name:
+		d.add(DiagnoseStepMFA, DiagnosticFailed, "%s", loginErr)
+	case errors.Is(loginErr, ErrUnlockRequired):
+		d.add(DiagnoseStepMFA, DiagnosticFailed, "%s", loginErr)
+	case len(mfaPages) > 0:
+		d.add(DiagnoseStepMFA, DiagnosticOK, "required: %s", strings.Join(slices.Compact(mfaPages), ", "))
+	case reachedApp:
+		d.add(DiagnoseStepMFA, DiagnosticOK, "not required")
+	}
+
+	otherPages := slices.DeleteFunc(slices.Clone(submitted), func(handler string) bool {
+		return handler == identifierPageHandler.name || handler == passwordPageHandler.name ||
+			handler == otpPageHandler.name || handler == emailCodePageHandler.name
+	})
+	switch {
+	case unknownPage != nil:
+		d.add(DiagnoseStepOtherPages, DiagnosticFailed, "%s", unknownPage)
+	case failedPage != "" && slices.Contains(otherPages, failedPage):
+		d.add(DiagnoseStepOtherPages, DiagnosticFailed, "%s", loginErr)
+	case !reachedApp && !d.failed():
+		// Failed without being caught by any of the steps above
+		d.add(DiagnoseStepOtherPages, DiagnosticFailed, "%s", loginErr)
+	case len(otherPages) > 0:
+		d.add(DiagnoseStepOtherPages, DiagnosticOK, "submitted: %s", strings.Join(slices.Compact(otherPages), ", "))
+	case reachedApp:
+		d.add(DiagnoseStepOtherPages, DiagnosticOK, "none")
+	}
+
+	if !reachedApp {
+		return d.finish()
+	}
+	if loginErr != nil {
+		d.add(DiagnoseStepEmployeeID, DiagnosticFailed, "%s", loginErr)
+	} else {
+		d.add(DiagnoseStepEmployeeID, DiagnosticOK, "%d", c.EmployeeID)
+	}
+	c.diagnoseXSRFCookies(d)
+	return d.finish()
+}
+
+func (c *Client) diagnoseLoginRedirect(d *Diagnosis, startResp *http.Response) {
+	if startResp.Request == nil {
+		d.add(DiagnoseStepLoginRedirect, DiagnosticWarning, "response has no request URL")
+		return
+	}
+	landed := startResp.Request.URL
+	loginURL, _ := url.Parse(c.LoginURL)
+	baseURL, _ := url.Parse(c.BaseURL)
+	switch landed.Host {
+	case loginURL.Host:
+		d.add(DiagnoseStepLoginRedirect, DiagnosticOK, "redirected to %s%s", landed.Host, landed.Path)
+	case baseURL.Host:
+		d.add(DiagnoseStepLoginRedirect, DiagnosticWarning, "not redirected to %s, stayed on %s%s",
+			loginURL.Host, landed.Host, landed.Path)
+	default:
+		d.add(DiagnoseStepLoginRedirect, DiagnosticWarning, "redirected to %s%s instead of %s",
+			landed.Host, landed.Path, loginURL.Host)
+	}
+}
+
+// diagnoseXSRFCookies checks the cookies used by [Client.setCsrfTokens],
+// which are needed when changing the attendance.
+func (c *Client) diagnoseXSRFCookies(d *Diagnosis) {
+	baseURL, err := url.Parse(c.BaseURL)
+	if err != nil {
+		d.add(DiagnoseStepXSRFCookies, DiagnosticFailed, "parse base URL: %s", err)
+		return
+	}
+	names := []string{"XSRF-TOKEN", "ATHENA-XSRF-TOKEN"}
+	var missing []string
+	for _, name := range names {
+		if _, ok := c.findCookie(baseURL, name); !ok {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		d.add(DiagnoseStepXSRFCookies, DiagnosticFailed, "missing %s", strings.Join(missing, ", "))
+		return
+	}
+	d.add(DiagnoseStepXSRFCookies, DiagnosticOK, "found %s", strings.Join(names, ", "))
+}
@@ -18,6 +18,7 @@
 package personio
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
@@ -48,15 +49,27 @@ type EmployeeTab struct {
 	IsActive bool   `json:"isActive"`
 }
 
+// GetMyEmployeeData calls [Client.GetMyEmployeeDataContext] with [context.Background].
 func (c *Client) GetMyEmployeeData() (*Employee, error) {
+	return c.GetMyEmployeeDataContext(context.Background())
+}
+
+// GetMyEmployeeDataContext returns the logged in employee's data.
+func (c *Client) GetMyEmployeeDataContext(ctx context.Context) (*Employee, error) {
 	if c.EmployeeID == 0 {
 		return nil, errors.New("no employee ID stored in client")
 	}
-	return c.GetEmployeeData(c.EmployeeID)
+	return c.GetEmployeeDataContext(ctx, c.EmployeeID)
 }
 
+// GetEmployeeData calls [Client.GetEmployeeDataContext] with [context.Background].
 func (c *Client) GetEmployeeData(id int) (*Employee, error) {
-	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/employee-header-bff/%d", id), nil)
+	return c.GetEmployeeDataContext(context.Background(), id)
+}
+
+// GetEmployeeDataContext returns an employee's data.
+func (c *Client) GetEmployeeDataContext(ctx context.Context, id int) (*Employee, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("/employee-header-bff/%d", id), nil)
 	if err != nil {
 		return nil, fmt.Errorf("create request: %w", err)
 	}
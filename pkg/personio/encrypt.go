@@ -0,0 +1,118 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package personio
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/argon2"
+)
+
+var (
+	ErrWrongPassphrase = errors.New("wrong passphrase or corrupted file")
+)
+
+const encryptedCredentialsVersion = 1
+
+// encryptedCredentials is the file format of an encrypted credentials file.
+// The key is derived from the passphrase using Argon2id, and the
+// credentials are encrypted with AES-256-GCM.
+type encryptedCredentials struct {
+	Version    int    `json:"version"`
+	KDF        string `json:"kdf"`
+	Time       uint32 `json:"time"`
+	Memory     uint32 `json:"memory"`
+	Threads    uint8  `json:"threads"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// EncryptCredentials encrypts the credentials with a passphrase, for use
+// with [EncryptedFileCredentials].
+func EncryptCredentials(creds Credentials, passphrase string) ([]byte, error) {
+	if passphrase == "" {
+		return nil, errors.New("passphrase must not be empty")
+	}
+	plaintext, err := json.Marshal(creds)
+	if err != nil {
+		return nil, err
+	}
+	file := encryptedCredentials{
+		Version: encryptedCredentialsVersion,
+		KDF:     "argon2id",
+		Time:    3,
+		Memory:  64 * 1024,
+		Threads: 4,
+		Salt:    make([]byte, 16),
+	}
+	if _, err := rand.Read(file.Salt); err != nil {
+		return nil, err
+	}
+	aead, err := file.aead(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	file.Nonce = make([]byte, aead.NonceSize())
+	if _, err := rand.Read(file.Nonce); err != nil {
+		return nil, err
+	}
+	file.Ciphertext = aead.Seal(nil, file.Nonce, plaintext, nil)
+	return json.MarshalIndent(file, "", "  ")
+}
+
+// DecryptCredentials decrypts credentials encrypted by [EncryptCredentials].
+func DecryptCredentials(data []byte, passphrase string) (Credentials, error) {
+	var file encryptedCredentials
+	if err := json.Unmarshal(data, &file); err != nil {
+		return Credentials{}, fmt.Errorf("parse encrypted credentials: %w", err)
+	}
+	if file.Version != encryptedCredentialsVersion || file.KDF != "argon2id" {
+		return Credentials{}, fmt.Errorf("unsupported encrypted credentials: version %d, kdf %q", file.Version, file.KDF)
+	}
+	aead, err := file.aead(passphrase)
+	if err != nil {
+		return Credentials{}, err
+	}
+	if len(file.Nonce) != aead.NonceSize() {
+		return Credentials{}, ErrWrongPassphrase
+	}
+	plaintext, err := aead.Open(nil, file.Nonce, file.Ciphertext, nil)
+	if err != nil {
+		return Credentials{}, ErrWrongPassphrase
+	}
+	var creds Credentials
+	if err := json.Unmarshal(plaintext, &creds); err != nil {
+		return Credentials{}, fmt.Errorf("parse decrypted credentials: %w", err)
+	}
+	return creds, nil
+}
+
+func (f encryptedCredentials) aead(passphrase string) (cipher.AEAD, error) {
+	key := argon2.IDKey([]byte(passphrase), f.Salt, f.Time, f.Memory, f.Threads, 32)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,435 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package personio
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/applejag/rootless-personio/pkg/config"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/term"
+)
+
+var (
+	ErrUnknownLoginPage = errors.New("unknown login page")
+	ErrLoginRejected    = errors.New("login rejected")
+	ErrNotInteractive   = errors.New("not running in a terminal")
+)
+
+// maxLoginSteps is the maximum number of pages submitted during a login,
+// to not end up in an endless loop.
+const maxLoginSteps = 20
+
+// UnknownLoginPageError is returned by [Client.Login] when it lands on
+// a page it does not know how to submit, such as when Personio has added
+// a new step to their login flow.
+type UnknownLoginPageError struct {
+	URL   string
+	Title string
+	// Forms contains a description of each form's fields.
+	Forms []string
+}
+
+// Error implements [error].
+func (e *UnknownLoginPageError) Error() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "unknown login page %s", e.URL)
+	if e.Title != "" {
+		fmt.Fprintf(&sb, " (title %q)", e.Title)
+	}
+	switch len(e.Forms) {
+	case 0:
+		sb.WriteString(": page has no forms")
+	case 1:
+		fmt.Fprintf(&sb, ": form fields: %s", e.Forms[0])
+	default:
+		for i, form := range e.Forms {
+			fmt.Fprintf(&sb, "; form #%d fields: %s", i+1, form)
+		}
+	}
+	return sb.String()
+}
+
+// Unwrap returns [ErrUnknownLoginPage], so the error can be checked
+// with [errors.Is].
+func (e *UnknownLoginPageError) Unwrap() error {
+	return ErrUnknownLoginPage
+}
+
+func newUnknownLoginPageError(page *loginPage) *UnknownLoginPageError {
+	u := *page.URL
+	u.RawQuery = ""
+	err := &UnknownLoginPageError{URL: u.String(), Title: page.Title}
+	for _, form := range page.Forms {
+		err.Forms = append(err.Forms, form.describe())
+	}
+	return err
+}
+
+// loginFlow is the state of an ongoing login.
+type loginFlow struct {
+	auth  config.Auth
+	creds Credentials
+	// prompt asks the user for a value, such as a 2FA code.
+	prompt func(label string) (string, error)
+	// onStartPage is called with the response of the first request,
+	// when set. Used by [Client.Diagnose].
+	onStartPage func(resp *http.Response, err error)
+	// onSubmit is called before a page is submitted, when set.
+	// Used by [Client.Diagnose].
+	onSubmit func(handler string, page *loginPage)
+}
+
+// loginPageHandler submits one type of page in the login flow.
+type loginPageHandler struct {
+	name string
+	// match returns the form to submit, or nil if the page is of another type.
+	match func(page *loginPage) *loginForm
+	// fill sets the values to submit in the form.
+	fill func(flow *loginFlow, form *loginForm) error
+}
+
+// loginPageHandlers are tried in order, and the first match is used.
+var loginPageHandlers = []loginPageHandler{
+	identifierPageHandler,
+	passwordPageHandler,
+	emailCodePageHandler,
+	otpPageHandler,
+	consentPageHandler,
+	rememberDevicePageHandler,
+}
+
+var identifierPageHandler = loginPageHandler{
+	name: "identifier",
+	match: func(page *loginPage) *loginForm {
+		return page.findForm(func(f *loginForm) bool {
+			if _, ok := f.inputOfType("password"); ok {
+				return false
+			}
+			in, ok := f.input("username", "email")
+			return ok && in.Type != "hidden" &&
+				(page.pathHasSuffix("/u/login/identifier") || in.Type == "text" || in.Type == "email")
+		})
+	},
+	fill: func(flow *loginFlow, form *loginForm) error {
+		in, _ := form.input("username", "email")
+		form.set(in.Name, flow.creds.Email)
+		return nil
+	},
+}
+
+var passwordPageHandler = loginPageHandler{
+	name: "password",
+	match: func(page *loginPage) *loginForm {
+		return page.findForm(func(f *loginForm) bool {
+			_, ok := f.inputOfType("password")
+			return ok
+		})
+	},
+	fill: func(flow *loginFlow, form *loginForm) error {
+		in, _ := form.inputOfType("password")
+		form.set(in.Name, flow.creds.Password)
+		if in, ok := form.input("username", "email"); ok {
+			form.set(in.Name, flow.creds.Email)
+		}
+		return nil
+	},
+}
+
+var emailCodePageHandler = loginPageHandler{
+	name: "email code",
+	match: func(page *loginPage) *loginForm {
+		if !page.pathContains("email") {
+			return nil
+		}
+		return page.findForm(func(f *loginForm) bool {
+			_, ok := f.input("code", "otp", "verification-code")
+			return ok
+		})
+	},
+	fill: func(flow *loginFlow, form *loginForm) error {
+		code, err := flow.prompt("Verification code from email")
+		if err != nil {
+			return fmt.Errorf("email verification code needed: %w", err)
+		}
+		in, _ := form.input("code", "otp", "verification-code")
+		form.set(in.Name, code)
+		return nil
+	},
+}
+
+var otpPageHandler = loginPageHandler{
+	name: "2FA",
+	match: func(page *loginPage) *loginForm {
+		return page.findForm(func(f *loginForm) bool {
+			if in, ok := f.input("code", "otp"); ok && in.Type != "hidden" {
+				return true
+			}
+			for _, in := range f.Inputs {
+				if in.Autocomplete == "one-time-code" {
+					return true
+				}
+			}
+			return false
+		})
+	},
+	fill: func(flow *loginFlow, form *loginForm) error {
+		code := flow.creds.TOTP
+		if code == "" {
+			var err error
+			code, err = generateConfiguredTOTP(flow.creds, flow.auth)
+			if err != nil {
+				return err
+			}
+		}
+		if code == "" {
+			var err error
+			code, err = flow.prompt("2 factor token")
+			if err != nil {
+				return fmt.Errorf("2 factor token needed, but not provided: %w", err)
+			}
+		}
+		in, ok := form.input("code", "otp")
+		if !ok {
+			for _, i := range form.Inputs {
+				if i.Autocomplete == "one-time-code" {
+					in = i
+				}
+			}
+		}
+		form.set(in.Name, code)
+		return nil
+	},
+}
+
+var consentPageHandler = loginPageHandler{
+	name: "consent",
+	match: func(page *loginPage) *loginForm {
+		return page.findForm(func(f *loginForm) bool {
+			if f.hasVisibleInputs() {
+				return false
+			}
+			_, ok := f.buttonWithValue("accept")
+			return ok || (page.pathContains("consent") && len(f.Buttons) > 0)
+		})
+	},
+	fill: func(flow *loginFlow, form *loginForm) error {
+		if b, ok := form.buttonWithValue("accept"); ok {
+			form.button = &b
+		}
+		return nil
+	},
+}
+
+var rememberDevicePageHandler = loginPageHandler{
+	name: "remember device",
+	match: func(page *loginPage) *loginForm {
+		return page.findForm(func(f *loginForm) bool {
+			if f.hasVisibleInputs() {
+				return false
+			}
+			if page.pathContains("remember") {
+				return true
+			}
+			_, ok := rememberInput(f)
+			return ok
+		})
+	},
+	fill: func(flow *loginFlow, form *loginForm) error {
+		if in, ok := rememberInput(form); ok {
+			form.set(in.Name, valueOr(in.Value, "true"))
+		}
+		if b, ok := form.buttonWithValue("remember", "accept", "yes", "trust"); ok {
+			form.button = &b
+		}
+		return nil
+	},
+}
+
+// rememberInput returns the checkbox or hidden field used to remember
+// the device, e.g Auth0's "rememberBrowser".
+func rememberInput(f *loginForm) (formInput, bool) {
+	for _, in := range f.Inputs {
+		if strings.Contains(strings.ToLower(in.Name), "remember") {
+			return in, true
+		}
+	}
+	return formInput{}, false
+}
+
+func (p *loginPage) findForm(match func(f *loginForm) bool) *loginForm {
+	for _, f := range p.Forms {
+		if match(f) {
+			return f
+		}
+	}
+	return nil
+}
+
+func (p *loginPage) pathHasSuffix(suffix string) bool {
+	return strings.HasSuffix(p.URL.Path, suffix)
+}
+
+func (p *loginPage) pathContains(s string) bool {
+	return strings.Contains(strings.ToLower(p.URL.Path), s)
+}
+
+// matchLoginPage returns the handler and form to submit for the page,
+// or nil if no handler matches.
+func matchLoginPage(page *loginPage) (*loginPageHandler, *loginForm) {
+	for i := range loginPageHandlers {
+		if form := loginPageHandlers[i].match(page); form != nil {
+			return &loginPageHandlers[i], form
+		}
+	}
+	return nil, nil
+}
+
+func (c *Client) runLoginFlow(ctx context.Context, flow *loginFlow) error {
+	baseURL, err := url.Parse(c.BaseURL)
+	if err != nil {
+		return fmt.Errorf("parse base URL: %w", err)
+	}
+	startPage, err := http.NewRequestWithContext(ctx, http.MethodGet, "/", nil)
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	resp, err := c.Raw(startPage)
+	if flow.onStartPage != nil {
+		flow.onStartPage(resp, err)
+	}
+	if err != nil {
+		return fmt.Errorf("get start page: %w", err)
+	}
+
+	submitted := map[string]int{}
+	finished := false
+	for step := 0; step < maxLoginSteps; step++ {
+		if isUnlockPage(resp) {
+			return c.newUnlockRequiredError(resp)
+		}
+		if !isHTML(resp) {
+			resp.Body.Close()
+			finished = true
+			break
+		}
+		page, err := parseLoginPage(resp)
+		if err != nil {
+			return err
+		}
+		handler, form := matchLoginPage(page)
+		if handler == nil {
+			if page.URL.Host == baseURL.Host {
+				// Landed in the app itself
+				finished = true
+				break
+			}
+			return newUnknownLoginPageError(page)
+		}
+
+		if submitted[handler.name] > 0 && len(page.Errors) > 0 {
+			// Shown again with an error, such as for a wrong password
+			return newLoginRejectedError(handler.name, page, "")
+		}
+		submitted[handler.name]++
+		if submitted[handler.name] > 2 {
+			return newLoginRejectedError(handler.name, page,
+				"page was shown again after submitting it")
+		}
+		log.Debug().Str("page", handler.name).Str("url", page.URL.Redacted()).
+			Msg("Submitting login page.")
+		if flow.onSubmit != nil {
+			flow.onSubmit(handler.name, page)
+		}
+		if err := handler.fill(flow, form); err != nil {
+			return fmt.Errorf("%s page: %w", handler.name, err)
+		}
+		resp, err = c.submitLoginForm(ctx, form)
+		if err != nil {
+			if resp != nil && isHTML(resp) && resp.StatusCode >= 400 && resp.StatusCode < 500 {
+				if page, parseErr := parseLoginPage(resp); parseErr == nil && len(page.Errors) > 0 {
+					return newLoginRejectedError(handler.name, page, "")
+				}
+			}
+			return fmt.Errorf("%s page: %w", handler.name, err)
+		}
+	}
+	if !finished {
+		resp.Body.Close()
+		return fmt.Errorf("login did not finish after %d steps", maxLoginSteps)
+	}
+
+	userActivity, err := c.getUserActivity(ctx)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrEmployeeIDNotFound, err)
+	}
+	c.EmployeeID = userActivity.User.ID
+	return nil
+}
+
+func newLoginRejectedError(pageName string, page *loginPage, fallback string) error {
+	reason := strings.Join(page.Errors, "; ")
+	if reason == "" {
+		reason = fallback
+	}
+	return fmt.Errorf("%s page: %w: %s", pageName, ErrLoginRejected, reason)
+}
+
+func (c *Client) submitLoginForm(ctx context.Context, form *loginForm) (*http.Response, error) {
+	values := form.encode()
+	if form.Method == http.MethodGet {
+		u := *form.Action
+		u.RawQuery = values.Encode()
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+		if err != nil {
+			return nil, err
+		}
+		return c.Raw(req)
+	}
+	req, err := http.NewRequestWithContext(ctx, form.Method, form.Action.String(), strings.NewReader(values.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	return c.RawForm(req)
+}
+
+func isHTML(resp *http.Response) bool {
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	return mediaType == "text/html"
+}
+
+// promptTerminal asks the user for a value via the terminal.
+func promptTerminal(label string) (string, error) {
+	if !term.IsTerminal(int(os.Stdin.Fd())) && os.Getenv("TERM") != "dumb" {
+		return "", ErrNotInteractive
+	}
+	fmt.Print(label + ": ")
+	value, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return "", fmt.Errorf("read %s: %w", label, err)
+	}
+	return strings.TrimSpace(value), nil
+}
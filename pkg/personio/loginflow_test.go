@@ -0,0 +1,301 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package personio
+
+import (
+	"errors"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoginPageHandlers(t *testing.T) {
+	var tests = []struct {
+		name        string
+		url         string
+		html        string
+		creds       Credentials
+		prompt      string
+		wantHandler string
+		wantAction  string
+		wantValues  url.Values
+	}{
+		{
+			name: "identifier",
+			url:  "https://login.personio.com/u/login/identifier?state=abc",
+			html: `<form method="POST" action="">
+				<input type="hidden" name="state" value="abc">
+				<input type="text" name="username" value="">
+				<button type="submit" name="action" value="default">Continue</button>
+			</form>`,
+			creds:       Credentials{Email: "jane@example.com"},
+			wantHandler: "identifier",
+			wantAction:  "https://login.personio.com/u/login/identifier?state=abc",
+			wantValues: url.Values{
+				"state":    {"abc"},
+				"username": {"jane@example.com"},
+				"action":   {"default"},
+			},
+		},
+		{
+			name: "identifier detected from fields",
+			url:  "https://login.personio.com/u/something-new",
+			html: `<form method="post" action="/u/something-new/submit">
+				<input type="email" name="email">
+				<input type="submit" value="Next">
+			</form>`,
+			creds:       Credentials{Email: "jane@example.com"},
+			wantHandler: "identifier",
+			wantAction:  "https://login.personio.com/u/something-new/submit",
+			wantValues:  url.Values{"email": {"jane@example.com"}},
+		},
+		{
+			name: "password",
+			url:  "https://login.personio.com/u/login/password?state=abc",
+			html: `<form method="POST">
+				<input type="hidden" name="state" value="abc">
+				<input type="hidden" name="username" value="jane@example.com">
+				<input type="password" name="password">
+				<button type="submit" name="action" value="default">Continue</button>
+			</form>`,
+			creds:       Credentials{Email: "jane@example.com", Password: "hunter2"},
+			wantHandler: "password",
+			wantAction:  "https://login.personio.com/u/login/password?state=abc",
+			wantValues: url.Values{
+				"state":    {"abc"},
+				"username": {"jane@example.com"},
+				"password": {"hunter2"},
+				"action":   {"default"},
+			},
+		},
+		{
+			name: "2FA from credentials",
+			url:  "https://login.personio.com/u/mfa-otp-challenge?state=abc",
+			html: `<form method="POST">
+				<input type="hidden" name="state" value="abc">
+				<input type="text" name="code" autocomplete="one-time-code">
+				<input type="checkbox" name="rememberBrowser" value="true">
+				<button type="submit" name="action" value="default">Continue</button>
+			</form>`,
+			creds:       Credentials{TOTP: "123456"},
+			wantHandler: "2FA",
+			wantAction:  "https://login.personio.com/u/mfa-otp-challenge?state=abc",
+			wantValues: url.Values{
+				"state":  {"abc"},
+				"code":   {"123456"},
+				"action": {"default"},
+			},
+		},
+		{
+			name: "2FA from prompt",
+			url:  "https://login.personio.com/u/mfa-otp-challenge",
+			html: `<form method="POST">
+				<input type="text" name="otp-code" autocomplete="one-time-code">
+			</form>`,
+			prompt:      "654321",
+			wantHandler: "2FA",
+			wantAction:  "https://login.personio.com/u/mfa-otp-challenge",
+			wantValues:  url.Values{"otp-code": {"654321"}},
+		},
+		{
+			name: "email code",
+			url:  "https://login.personio.com/u/mfa-email-challenge?state=abc",
+			html: `<form method="POST">
+				<input type="hidden" name="state" value="abc">
+				<input type="text" name="code">
+				<button type="submit" name="action" value="default">Continue</button>
+			</form>`,
+			creds:       Credentials{TOTP: "not-used"},
+			prompt:      "987654",
+			wantHandler: "email code",
+			wantAction:  "https://login.personio.com/u/mfa-email-challenge?state=abc",
+			wantValues: url.Values{
+				"state":  {"abc"},
+				"code":   {"987654"},
+				"action": {"default"},
+			},
+		},
+		{
+			name: "consent",
+			url:  "https://login.personio.com/u/consent?state=abc",
+			html: `<form method="POST">
+				<input type="hidden" name="state" value="abc">
+				<button type="submit" name="action" value="deny">Decline</button>
+				<button type="submit" name="action" value="accept">Accept</button>
+			</form>`,
+			wantHandler: "consent",
+			wantAction:  "https://login.personio.com/u/consent?state=abc",
+			wantValues: url.Values{
+				"state":  {"abc"},
+				"action": {"accept"},
+			},
+		},
+		{
+			name: "remember device",
+			url:  "https://login.personio.com/u/remember-device?state=abc",
+			html: `<form method="POST">
+				<input type="hidden" name="state" value="abc">
+				<button type="submit" name="action" value="skip">Not now</button>
+				<button type="submit" name="action" value="remember">Remember this device</button>
+			</form>`,
+			wantHandler: "remember device",
+			wantAction:  "https://login.personio.com/u/remember-device?state=abc",
+			wantValues: url.Values{
+				"state":  {"abc"},
+				"action": {"remember"},
+			},
+		},
+		{
+			name: "remember device detected from fields",
+			url:  "https://login.personio.com/u/trust",
+			html: `<form method="POST">
+				<input type="checkbox" name="rememberBrowser" value="true">
+				<button type="submit">Continue</button>
+			</form>`,
+			wantHandler: "remember device",
+			wantAction:  "https://login.personio.com/u/trust",
+			wantValues:  url.Values{"rememberBrowser": {"true"}},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			pageURL, err := url.Parse(tc.url)
+			if err != nil {
+				t.Fatal(err)
+			}
+			page, err := parseLoginPageHTML(pageURL, "<html><body>"+tc.html+"</body></html>")
+			if err != nil {
+				t.Fatalf("parse page: %s", err)
+			}
+			handler, form := matchLoginPage(page)
+			if handler == nil {
+				t.Fatalf("want handler %q, got none: %s", tc.wantHandler, newUnknownLoginPageError(page))
+			}
+			if handler.name != tc.wantHandler {
+				t.Fatalf("want handler %q, got %q", tc.wantHandler, handler.name)
+			}
+			flow := &loginFlow{
+				creds: tc.creds,
+				prompt: func(label string) (string, error) {
+					if tc.prompt == "" {
+						t.Errorf("unexpected prompt for %q", label)
+					}
+					return tc.prompt, nil
+				},
+			}
+			if err := handler.fill(flow, form); err != nil {
+				t.Fatalf("fill: %s", err)
+			}
+			if got := form.Action.String(); got != tc.wantAction {
+				t.Errorf("want action %q, got %q", tc.wantAction, got)
+			}
+			if got := form.encode(); !reflect.DeepEqual(got, tc.wantValues) {
+				t.Errorf("want values %v, got %v", tc.wantValues, got)
+			}
+		})
+	}
+}
+
+func TestLoginPageOTPNotInteractive(t *testing.T) {
+	pageURL, _ := url.Parse("https://login.personio.com/u/mfa-otp-challenge")
+	page, err := parseLoginPageHTML(pageURL, `<form method="POST"><input name="code"></form>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler, form := matchLoginPage(page)
+	if handler == nil || handler.name != "2FA" {
+		t.Fatalf("want 2FA handler, got %v", handler)
+	}
+	flow := &loginFlow{
+		prompt: func(string) (string, error) { return "", ErrNotInteractive },
+	}
+	if err := handler.fill(flow, form); !errors.Is(err, ErrNotInteractive) {
+		t.Errorf("want ErrNotInteractive, got %v", err)
+	}
+}
+
+func TestUnknownLoginPageError(t *testing.T) {
+	pageURL, _ := url.Parse("https://login.personio.com/u/captcha?state=secret")
+	page, err := parseLoginPageHTML(pageURL, `<html>
+		<head><title>Are you a robot?</title></head>
+		<body><form method="POST">
+			<input type="hidden" name="state" value="secret">
+			<input type="checkbox" name="not-a-robot">
+			<button type="submit" name="action" value="default">Continue</button>
+		</form></body></html>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if handler, _ := matchLoginPage(page); handler != nil {
+		t.Fatalf("want no handler, got %q", handler.name)
+	}
+	err = newUnknownLoginPageError(page)
+	if !errors.Is(err, ErrUnknownLoginPage) {
+		t.Errorf("want ErrUnknownLoginPage, got %v", err)
+	}
+	want := `unknown login page https://login.personio.com/u/captcha (title "Are you a robot?"): ` +
+		`form fields: state (hidden), not-a-robot (checkbox), action=default (button)`
+	if got := err.Error(); got != want {
+		t.Errorf("want:\n%s\ngot:\n%s", want, got)
+	}
+	if strings.Contains(err.Error(), "secret") {
+		t.Error("error contains the state value")
+	}
+}
+
+func TestParseLoginPageErrors(t *testing.T) {
+	pageURL, _ := url.Parse("https://login.personio.com/u/login/password")
+	page, err := parseLoginPageHTML(pageURL, `<form method="POST">
+		<input type="password" name="password">
+		<span class="ulp-input-error-message" id="error-element-password">
+			Wrong email or password
+		</span>
+		<div role="alert">Too many attempts</div>
+	</form>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"Wrong email or password", "Too many attempts"}
+	if !reflect.DeepEqual(page.Errors, want) {
+		t.Errorf("want errors %q, got %q", want, page.Errors)
+	}
+
+	// Auth0 marks the field's wrapper as an error, with the input inside
+	page, err = parseLoginPageHTML(pageURL, `<form method="POST">
+		<input type="hidden" name="state" value="abc">
+		<div class="ulp-field ulp-error">
+			<label for="password">Password</label>
+			<input type="password" name="password" id="password">
+			<span class="ulp-input-error-message">Wrong password</span>
+		</div>
+		<button type="submit" name="action" value="default">Continue</button>
+	</form>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []string{"Wrong password"}
+	if !reflect.DeepEqual(page.Errors, want) {
+		t.Errorf("nested: want errors %q, got %q", want, page.Errors)
+	}
+	handler, _ := matchLoginPage(page)
+	if handler == nil || handler.name != "password" {
+		t.Errorf("nested: want password page, got %v", handler)
+	}
+}
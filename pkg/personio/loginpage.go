@@ -0,0 +1,328 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package personio
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// loginPage is a parsed HTML page from the login flow.
+type loginPage struct {
+	URL    *url.URL
+	Title  string
+	Forms  []*loginForm
+	Errors []string
+}
+
+// loginForm is an HTML form, with its inputs and submit buttons.
+type loginForm struct {
+	// Action is the absolute URL the form is submitted to.
+	Action  *url.URL
+	Method  string
+	Inputs  []formInput
+	Buttons []formInput
+
+	// values are set by the page handlers, and override the inputs.
+	values url.Values
+	// button is the submit button to press, or the first one if nil.
+	button *formInput
+}
+
+// formInput is an <input> or <button> element.
+type formInput struct {
+	Name         string
+	Type         string
+	Value        string
+	Checked      bool
+	Autocomplete string
+}
+
+func parseLoginPage(resp *http.Response) (*loginPage, error) {
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("read body: %w", err)
+	}
+	return parseLoginPageHTML(resp.Request.URL, string(body))
+}
+
+func parseLoginPageHTML(pageURL *url.URL, body string) (*loginPage, error) {
+	doc, err := html.Parse(strings.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("parse HTML: %w", err)
+	}
+	page := &loginPage{URL: pageURL}
+	var form *loginForm
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch n.DataAtom {
+			case atom.Title:
+				page.Title = strings.TrimSpace(textContent(n))
+			case atom.Form:
+				action := pageURL
+				if u, err := pageURL.Parse(attr(n, "action")); err == nil {
+					action = u
+				}
+				form = &loginForm{
+					Action: action,
+					Method: strings.ToUpper(attr(n, "method")),
+				}
+				if form.Method == "" {
+					form.Method = http.MethodGet
+				}
+				page.Forms = append(page.Forms, form)
+				for c := n.FirstChild; c != nil; c = c.NextSibling {
+					walk(c)
+				}
+				form = nil
+				return
+			case atom.Input:
+				if form != nil {
+					_, checked := attrOK(n, "checked")
+					form.Inputs = append(form.Inputs, formInput{
+						Name:         attr(n, "name"),
+						Type:         strings.ToLower(attrDefault(n, "type", "text")),
+						Value:        attr(n, "value"),
+						Checked:      checked,
+						Autocomplete: attr(n, "autocomplete"),
+					})
+				}
+			case atom.Button:
+				if form != nil {
+					buttonType := strings.ToLower(attrDefault(n, "type", "submit"))
+					if buttonType == "submit" {
+						form.Buttons = append(form.Buttons, formInput{
+							Name:  attr(n, "name"),
+							Type:  buttonType,
+							Value: attr(n, "value"),
+						})
+					}
+				}
+			}
+			// Auth0 also marks the wrapper of the field as an error, e.g via
+			// "ulp-error", so only the innermost message is recorded, and the
+			// inputs inside the wrapper are still walked.
+			if isErrorElement(n) && !hasErrorDescendant(n) {
+				if text := strings.Join(strings.Fields(textContent(n)), " "); text != "" {
+					page.Errors = append(page.Errors, text)
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+	return page, nil
+}
+
+// isErrorElement reports if the element is an error message,
+// based on its class, such as Auth0's "ulp-input-error-message".
+func isErrorElement(n *html.Node) bool {
+	if n.DataAtom == atom.Script || n.DataAtom == atom.Style {
+		return false
+	}
+	for _, class := range strings.Fields(attr(n, "class")) {
+		if strings.Contains(strings.ToLower(class), "error") {
+			return true
+		}
+	}
+	return attr(n, "role") == "alert"
+}
+
+func hasErrorDescendant(n *html.Node) bool {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && (isErrorElement(c) || hasErrorDescendant(c)) {
+			return true
+		}
+	}
+	return false
+}
+
+func attr(n *html.Node, key string) string {
+	value, _ := attrOK(n, key)
+	return value
+}
+
+func attrDefault(n *html.Node, key, fallback string) string {
+	if value, ok := attrOK(n, key); ok && value != "" {
+		return value
+	}
+	return fallback
+}
+
+func attrOK(n *html.Node, key string) (string, bool) {
+	for _, a := range n.Attr {
+		if a.Namespace == "" && a.Key == key {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
+
+func textContent(n *html.Node) string {
+	var sb strings.Builder
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			sb.WriteString(n.Data)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return sb.String()
+}
+
+// input returns the first input with one of the names.
+func (f *loginForm) input(names ...string) (formInput, bool) {
+	for _, in := range f.Inputs {
+		for _, name := range names {
+			if strings.EqualFold(in.Name, name) {
+				return in, true
+			}
+		}
+	}
+	return formInput{}, false
+}
+
+// inputOfType returns the first input of one of the types.
+func (f *loginForm) inputOfType(types ...string) (formInput, bool) {
+	for _, in := range f.Inputs {
+		for _, t := range types {
+			if in.Type == t && in.Name != "" {
+				return in, true
+			}
+		}
+	}
+	return formInput{}, false
+}
+
+// hasVisibleInputs reports if the form has any inputs the user would type in.
+func (f *loginForm) hasVisibleInputs() bool {
+	for _, in := range f.Inputs {
+		switch in.Type {
+		case "hidden", "checkbox", "radio", "submit", "button":
+		default:
+			return true
+		}
+	}
+	return false
+}
+
+// buttonWithValue returns the first submit button with one of the values.
+func (f *loginForm) buttonWithValue(values ...string) (formInput, bool) {
+	for _, b := range f.Buttons {
+		for _, v := range values {
+			if strings.EqualFold(b.Value, v) {
+				return b, true
+			}
+		}
+	}
+	for _, in := range f.Inputs {
+		if in.Type != "submit" {
+			continue
+		}
+		for _, v := range values {
+			if strings.EqualFold(in.Value, v) {
+				return in, true
+			}
+		}
+	}
+	return formInput{}, false
+}
+
+// set sets the value to submit for a field.
+func (f *loginForm) set(name, value string) {
+	if f.values == nil {
+		f.values = url.Values{}
+	}
+	f.values.Set(name, value)
+}
+
+// encode returns the values to submit, the same way a browser would:
+// the inputs' values, only the checked checkboxes and radio buttons,
+// and the pressed submit button.
+func (f *loginForm) encode() url.Values {
+	values := url.Values{}
+	for _, in := range f.Inputs {
+		if in.Name == "" {
+			continue
+		}
+		switch in.Type {
+		case "checkbox", "radio":
+			if !in.Checked {
+				continue
+			}
+			values.Set(in.Name, valueOr(in.Value, "on"))
+		case "submit", "button", "image", "reset":
+			continue
+		default:
+			values.Set(in.Name, in.Value)
+		}
+	}
+	button := f.button
+	if button == nil && len(f.Buttons) > 0 {
+		button = &f.Buttons[0]
+	}
+	if button != nil && button.Name != "" {
+		values.Set(button.Name, button.Value)
+	}
+	for name, vals := range f.values {
+		values[name] = vals
+	}
+	return values
+}
+
+func valueOr(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
+// describe lists the form fields, for diagnostics.
+func (f *loginForm) describe() string {
+	var fields []string
+	for _, in := range f.Inputs {
+		if in.Name == "" {
+			continue
+		}
+		fields = append(fields, fmt.Sprintf("%s (%s)", in.Name, in.Type))
+	}
+	for _, b := range f.Buttons {
+		if b.Name == "" {
+			fields = append(fields, "button")
+		} else {
+			fields = append(fields, fmt.Sprintf("%s=%s (button)", b.Name, b.Value))
+		}
+	}
+	if len(fields) == 0 {
+		return "no fields"
+	}
+	return strings.Join(fields, ", ")
+}
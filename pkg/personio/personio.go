@@ -28,10 +28,11 @@ import (
 	"fmt"
 	"io"
 	"mime"
+	"net"
 	"net/http"
-	"net/http/cookiejar"
 	"net/url"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/rs/zerolog"
@@ -42,6 +43,10 @@ var (
 	UserAgent = "Rootless-Personio-bot/0.1 (+https://github.com/applejag/rootless-personio)"
 )
 
+// DefaultLoginURL is the URL of Personio's login pages, used unless
+// changed via [WithLoginURL].
+const DefaultLoginURL = "https://login.personio.com"
+
 var (
 	ErrUnexpectedRedirect = errors.New("unexpected redirect")
 	ErrEmployeeIDNotFound = errors.New("employee ID not found")
@@ -49,30 +54,110 @@ var (
 	ErrNotLoggedIn        = errors.New("not logged in")
 	ErrNon2xxStatusCode   = errors.New("non-2xx status code")
 	ErrUnlockRequired     = errors.New("unlock required")
+	ErrHostNotAllowed     = errors.New("host not allowed")
 )
 
 type Client struct {
-	BaseURL      string
-	http         *http.Client
-	EmployeeID   int
-	dayIDCache   map[string]*uuid.UUID
-	projectCache []Project
+	BaseURL string
+	// LoginURL is the URL of the login pages that Personio redirects to.
+	LoginURL string
+	// CredentialProvider is used by [Client.Login] to get the credentials.
+	// When nil, the provider is selected from the auth config passed
+	// to [Client.Login], via [NewCredentialProvider].
+	CredentialProvider CredentialProvider
+	http               *http.Client
+	jar                *sessionJar
+	EmployeeID         int
+	dayIDCache         map[string]*uuid.UUID
+	projectCache       []Project
+	unlockCSRFToken    string
+	allowedHosts       []string
+	retryPolicy        RetryPolicy
+	rateLimiter        *rateLimiter
+	location           *time.Location
+}
+
+// Option is used to configure a [Client] in [New].
+type Option func(*Client)
+
+// WithTransport sets the [http.RoundTripper] used for all HTTP requests,
+// instead of [http.DefaultTransport]. Retries and rate limiting
+// are still handled by the client.
+func WithTransport(rt http.RoundTripper) Option {
+	return func(c *Client) {
+		c.http.Transport = rt
+	}
+}
+
+// WithLoginURL sets the URL of the login pages, instead of
+// [DefaultLoginURL]. Its host is allowed in [Client.Raw].
+func WithLoginURL(loginURL string) Option {
+	return func(c *Client) {
+		c.LoginURL = loginURL
+	}
 }
 
-func New(baseURL string) (*Client, error) {
+// WithAllowedHosts adds hosts that [Client.Raw] may send requests to,
+// in addition to the hosts of the base URL, the login URL,
+// and *.personio.com.
+//
+// A host may contain a port, and may start with "*." to allow all its
+// subdomains, e.g "*.example.com".
+func WithAllowedHosts(hosts ...string) Option {
+	return func(c *Client) {
+		c.allowedHosts = append(c.allowedHosts, hosts...)
+	}
+}
+
+// WithLocation sets the timezone that the attendance is tracked in,
+// instead of [time.Local]. Personio stores the attendance periods as
+// wall-clock times, which the client interprets in this location.
+func WithLocation(loc *time.Location) Option {
+	return func(c *Client) {
+		c.location = loc
+	}
+}
+
+func New(baseURL string, opts ...Option) (*Client, error) {
 	normalURL, err := NormalizeBaseURL(baseURL)
 	if err != nil {
 		return nil, err
 	}
-	jar, err := cookiejar.New(&cookiejar.Options{})
+	jar, err := newSessionJar()
 	if err != nil {
 		return nil, err
 	}
-	return &Client{
-		http:       &http.Client{Jar: jar},
-		BaseURL:    normalURL,
-		dayIDCache: make(map[string]*uuid.UUID),
-	}, nil
+	c := &Client{
+		http:        &http.Client{Jar: jar},
+		jar:         jar,
+		BaseURL:     normalURL,
+		LoginURL:    DefaultLoginURL,
+		dayIDCache:  make(map[string]*uuid.UUID),
+		retryPolicy: DefaultRetryPolicy,
+		location:    time.Local,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	transport := c.http.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	c.http.Transport = &retryTransport{
+		next:    transport,
+		policy:  c.retryPolicy,
+		limiter: c.rateLimiter,
+	}
+	c.LoginURL, err = NormalizeBaseURL(c.LoginURL)
+	if err != nil {
+		return nil, fmt.Errorf("login URL: %w", err)
+	}
+	return c, nil
+}
+
+// Location returns the timezone that the attendance is tracked in.
+func (c *Client) Location() *time.Location {
+	return c.location
 }
 
 func (c *Client) RawJSON(req *http.Request) (*http.Response, error) {
@@ -86,6 +171,11 @@ func (c *Client) RawForm(req *http.Request) (*http.Response, error) {
 	return c.Raw(req)
 }
 
+// Raw sends a request to Personio, using the client's cookies and CSRF tokens.
+// Relative URLs are resolved against the base URL.
+//
+// The request is canceled when the request's context is done,
+// so use [http.NewRequestWithContext] to control its lifetime.
 func (c *Client) Raw(req *http.Request) (*http.Response, error) {
 	u, err := url.Parse(c.BaseURL)
 	if err != nil {
@@ -93,18 +183,20 @@ func (c *Client) Raw(req *http.Request) (*http.Response, error) {
 	}
 
 	if req.URL.Host != "" {
-		if !strings.HasSuffix(req.URL.Host, ".personio.com") {
-			return nil, fmt.Errorf("invalid host %q, expected *.personio.com", req.URL.Host)
+		if !c.isAllowedHost(u, req.URL.Host) {
+			return nil, fmt.Errorf("%w: %q", ErrHostNotAllowed, req.URL.Host)
 		}
-		u.Host = req.URL.Host
+		// Absolute URLs are used as-is, as the base URL's path
+		// does not apply to other hosts, such as the login pages.
+	} else {
+		u.Fragment = req.URL.Fragment
+		u.RawFragment = req.URL.RawFragment
+		u.RawQuery = req.URL.RawQuery
+		u.ForceQuery = req.URL.ForceQuery
+		u.Path += req.URL.Path
+		req.URL = u
 	}
-	u.Fragment = req.URL.Fragment
-	u.RawFragment = req.URL.RawFragment
-	u.RawQuery = req.URL.RawQuery
-	u.ForceQuery = req.URL.ForceQuery
-	u.Path += req.URL.Path
 
-	req.URL = u
 	c.setCsrfTokens(req)
 	setHeaderDefault(req.Header, "Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
 
@@ -163,6 +255,29 @@ func setHeaderDefault(headers http.Header, key, value string) {
 	}
 }
 
+func (c *Client) isAllowedHost(baseURL *url.URL, host string) bool {
+	hostname := host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		hostname = h
+	}
+	if host == baseURL.Host || strings.HasSuffix(hostname, ".personio.com") {
+		return true
+	}
+	if loginURL, err := url.Parse(c.LoginURL); err == nil && host == loginURL.Host {
+		return true
+	}
+	for _, allowed := range c.allowedHosts {
+		if allowed == host || allowed == hostname {
+			return true
+		}
+		if suffix, ok := strings.CutPrefix(allowed, "*"); ok && strings.HasPrefix(suffix, ".") &&
+			(strings.HasSuffix(host, suffix) || strings.HasSuffix(hostname, suffix)) {
+			return true
+		}
+	}
+	return false
+}
+
 func (c *Client) assertLoggedIn() error {
 	if c.EmployeeID == 0 {
 		return ErrNotLoggedIn
@@ -17,7 +17,10 @@
 
 package personio
 
-import "testing"
+import (
+	"net/url"
+	"testing"
+)
 
 func TestNormalizeBaseURL(t *testing.T) {
 	var tests = []struct {
@@ -59,3 +62,86 @@ func TestNormalizeBaseURL(t *testing.T) {
 		})
 	}
 }
+
+func TestIsAllowedHost(t *testing.T) {
+	var tests = []struct {
+		name    string
+		host    string
+		allowed []string
+		want    bool
+	}{
+		{
+			name: "base URL host",
+			host: "personio.example.com",
+			want: true,
+		},
+		{
+			name: "login host",
+			host: "login.example.com",
+			want: true,
+		},
+		{
+			name: "personio.com subdomain",
+			host: "login.personio.com",
+			want: true,
+		},
+		{
+			name: "other host",
+			host: "evil.example.com",
+			want: false,
+		},
+		{
+			name: "personio.com lookalike",
+			host: "evilpersonio.com",
+			want: false,
+		},
+		{
+			name:    "allowed host",
+			host:    "localhost:8080",
+			allowed: []string{"localhost:8080"},
+			want:    true,
+		},
+		{
+			name:    "allowed host without port",
+			host:    "localhost:8080",
+			allowed: []string{"localhost"},
+			want:    true,
+		},
+		{
+			name:    "allowed host with other port",
+			host:    "localhost:8080",
+			allowed: []string{"localhost:9090"},
+			want:    false,
+		},
+		{
+			name:    "allowed wildcard",
+			host:    "sso.corp.example",
+			allowed: []string{"*.corp.example"},
+			want:    true,
+		},
+		{
+			name:    "wildcard does not match parent",
+			host:    "corp.example",
+			allowed: []string{"*.corp.example"},
+			want:    false,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := New("https://personio.example.com/proxy",
+				WithLoginURL("https://login.example.com"),
+				WithAllowedHosts(tc.allowed...))
+			if err != nil {
+				t.Fatal(err)
+			}
+			baseURL, err := url.Parse(c.BaseURL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := c.isAllowedHost(baseURL, tc.host); got != tc.want {
+				t.Errorf("want %t, got %t", tc.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,388 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package personiotest
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/personio"
+	"github.com/google/uuid"
+)
+
+const sessionCookieName = "personio_session"
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests = append(s.requests, Request{
+		Method: r.Method,
+		Host:   r.Host,
+		Path:   r.URL.Path,
+		Query:  r.URL.RawQuery,
+	})
+
+	if r.Host == s.LoginHost {
+		s.serveLogin(w, r)
+		return
+	}
+	s.serveApp(w, r)
+}
+
+func (s *Server) serveLogin(w http.ResponseWriter, r *http.Request) {
+	stateID := r.URL.Query().Get("state")
+	if r.Method == http.MethodPost {
+		r.ParseForm()
+		if formState := r.PostForm.Get("state"); formState != "" {
+			stateID = formState
+		}
+	}
+	state, ok := s.loginStates[stateID]
+	if !ok {
+		http.Error(w, "invalid state", http.StatusBadRequest)
+		return
+	}
+
+	switch {
+	case r.URL.Path == "/u/login/identifier" && r.Method == http.MethodGet:
+		writeForm(w, r.URL.Path, stateID, `<input type="text" name="username" autocomplete="email" autofocus>`)
+	case r.URL.Path == "/u/login/identifier" && r.Method == http.MethodPost:
+		if r.PostForm.Get("username") != s.Email {
+			w.WriteHeader(http.StatusBadRequest)
+			writeForm(w, r.URL.Path, stateID, `<span class="error">Unknown email</span><input type="text" name="username">`)
+			return
+		}
+		state.email = s.Email
+		s.redirectLogin(w, r, "/u/login/password", stateID)
+	case r.URL.Path == "/u/login/password" && r.Method == http.MethodGet:
+		writeForm(w, r.URL.Path, stateID, fmt.Sprintf(
+			`<input type="hidden" name="username" value="%s"><input type="password" name="password">`,
+			html.EscapeString(state.email)))
+	case r.URL.Path == "/u/login/password" && r.Method == http.MethodPost:
+		if state.email == "" || r.PostForm.Get("password") != s.Password {
+			w.WriteHeader(http.StatusBadRequest)
+			writeForm(w, r.URL.Path, stateID, `<span class="error">Wrong email or password</span><input type="password" name="password">`)
+			return
+		}
+		state.passwordOK = true
+		if s.TOTPSecret != "" {
+			s.redirectLogin(w, r, "/u/mfa-otp-challenge", stateID)
+			return
+		}
+		s.finishLogin(w, r, stateID)
+	case r.URL.Path == "/u/mfa-otp-challenge" && r.Method == http.MethodGet:
+		writeForm(w, r.URL.Path, stateID, `<input type="text" name="code" inputmode="numeric" autocomplete="one-time-code">`)
+	case r.URL.Path == "/u/mfa-otp-challenge" && r.Method == http.MethodPost:
+		if !state.passwordOK || !s.validTOTP(r.PostForm.Get("code")) {
+			w.WriteHeader(http.StatusBadRequest)
+			writeForm(w, r.URL.Path, stateID, `<span class="error">Invalid code</span><input type="text" name="code">`)
+			return
+		}
+		s.finishLogin(w, r, stateID)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) validTOTP(code string) bool {
+	now := time.Now()
+	for _, t := range []time.Time{now, now.Add(-30 * time.Second), now.Add(30 * time.Second)} {
+		want, err := personio.GenerateTOTP(s.TOTPSecret, t)
+		if err == nil && code == want {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) redirectLogin(w http.ResponseWriter, r *http.Request, path, stateID string) {
+	http.Redirect(w, r, path+"?state="+url.QueryEscape(stateID), http.StatusFound)
+}
+
+func (s *Server) finishLogin(w http.ResponseWriter, r *http.Request, stateID string) {
+	delete(s.loginStates, stateID)
+	http.Redirect(w, r, s.BaseURL+"/auth/callback?code="+url.QueryEscape(uuid.NewString()), http.StatusFound)
+}
+
+func writeForm(w http.ResponseWriter, action, stateID, inputs string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprintf(w, `<!DOCTYPE html>
+<html>
+<body>
+<form method="POST" action="%s?state=%s">
+<input type="hidden" name="state" value="%s">
+%s
+<button type="submit" name="action" value="default">Continue</button>
+</form>
+</body>
+</html>
+`, action, url.QueryEscape(stateID), html.EscapeString(stateID), inputs)
+}
+
+func (s *Server) isLoggedIn(r *http.Request) bool {
+	cookie, err := r.Cookie(sessionCookieName)
+	return err == nil && s.sessions[cookie.Value]
+}
+
+func (s *Server) serveApp(w http.ResponseWriter, r *http.Request) {
+	// Serve the app below the base URL's path, like behind a reverse proxy
+	if baseURL, err := url.Parse(s.BaseURL); err == nil && baseURL.Path != "" {
+		path, ok := strings.CutPrefix(r.URL.Path, baseURL.Path)
+		if !ok {
+			writeJSONError(w, http.StatusNotFound, "not found")
+			return
+		}
+		r.URL.Path = path
+	}
+
+	switch r.URL.Path {
+	case "/auth/callback":
+		s.serveCallback(w, r)
+		return
+	case "/login/token-auth":
+		s.serveTokenAuth(w, r)
+		return
+	}
+
+	if !s.isLoggedIn(r) {
+		stateID := uuid.NewString()
+		s.loginStates[stateID] = &loginState{}
+		http.Redirect(w, r, "https://"+s.LoginHost+"/u/login/identifier?state="+url.QueryEscape(stateID), http.StatusFound)
+		return
+	}
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead && !s.validXSRF(r) {
+		writeJSONError(w, http.StatusForbidden, "CSRF token mismatch")
+		return
+	}
+
+	switch {
+	case r.URL.Path == "/" && r.Method == http.MethodGet:
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprintln(w, "<!DOCTYPE html><html><body>Personio</body></html>")
+	case r.URL.Path == "/api/v1/navigation/context" && r.Method == http.MethodGet:
+		s.serveNavigationContext(w)
+	case r.URL.Path == "/api/v1/projects" && r.Method == http.MethodGet:
+		writeJSONData(w, s.Projects)
+	case strings.HasPrefix(r.URL.Path, "/employee-header-bff/") && r.Method == http.MethodGet:
+		s.serveEmployee(w, strings.TrimPrefix(r.URL.Path, "/employee-header-bff/"))
+	case strings.HasPrefix(r.URL.Path, "/svc/attendance-bff/v1/timesheet/") && r.Method == http.MethodGet:
+		s.serveTimesheet(w, r, strings.TrimPrefix(r.URL.Path, "/svc/attendance-bff/v1/timesheet/"))
+	case strings.HasPrefix(r.URL.Path, "/svc/attendance-api/v1/days/") && r.Method == http.MethodPut:
+		s.servePutDay(w, r, strings.TrimPrefix(r.URL.Path, "/svc/attendance-api/v1/days/"))
+	case strings.HasPrefix(r.URL.Path, "/svc/attendance-api/v1/days/") && r.Method == http.MethodDelete:
+		s.serveDeleteDay(w, strings.TrimPrefix(r.URL.Path, "/svc/attendance-api/v1/days/"))
+	default:
+		writeJSONError(w, http.StatusNotFound, "not found")
+	}
+}
+
+func (s *Server) validXSRF(r *http.Request) bool {
+	token := r.Header.Get("X-XSRF-TOKEN")
+	if token == "" {
+		token = r.Header.Get("X-CSRF-Token")
+	}
+	return token == s.xsrfToken
+}
+
+func (s *Server) serveCallback(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("code") == "" {
+		http.Error(w, "missing code", http.StatusBadRequest)
+		return
+	}
+	sessionID := uuid.NewString()
+	s.setSessionCookies(w, sessionID)
+	if s.EmailToken != "" {
+		// Session is only valid after the email token has been entered
+		s.sessions[sessionID] = false
+		http.Redirect(w, r, s.BaseURL+"/login/token-auth", http.StatusFound)
+		return
+	}
+	s.sessions[sessionID] = true
+	http.Redirect(w, r, s.BaseURL+"/", http.StatusFound)
+}
+
+func (s *Server) setSessionCookies(w http.ResponseWriter, sessionID string) {
+	http.SetCookie(w, &http.Cookie{Name: sessionCookieName, Value: sessionID, Path: "/", HttpOnly: true})
+	http.SetCookie(w, &http.Cookie{Name: "XSRF-TOKEN", Value: s.xsrfToken, Path: "/"})
+	http.SetCookie(w, &http.Cookie{Name: "ATHENA-XSRF-TOKEN", Value: s.athenaToken, Path: "/"})
+}
+
+func (s *Server) serveTokenAuth(w http.ResponseWriter, r *http.Request) {
+	cookie, err := r.Cookie(sessionCookieName)
+	if err != nil {
+		http.Error(w, "missing session", http.StatusUnauthorized)
+		return
+	}
+	if _, ok := s.sessions[cookie.Value]; !ok {
+		http.Error(w, "unknown session", http.StatusUnauthorized)
+		return
+	}
+	if r.Method == http.MethodPost {
+		r.ParseForm()
+		if r.PostForm.Get("_token") == s.xsrfToken && r.PostForm.Get("token") == s.EmailToken {
+			s.sessions[cookie.Value] = true
+			http.Redirect(w, r, s.BaseURL+"/", http.StatusFound)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprintf(w, `<!DOCTYPE html><html><head><meta name="csrf-token" content="%s"></head><body>
+<script>REDUX_INITIAL_STATE.bladeState.messages = {error: "The token is invalid"};</script>
+</body></html>
+`, s.xsrfToken)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprintf(w, `<!DOCTYPE html>
+<html>
+<body>
+<form method="POST" action="%s/login/token-auth">
+<input type="hidden" name="_token" value="%s">
+<input type="text" name="token">
+<button type="submit">Confirm login</button>
+</form>
+</body>
+</html>
+`, html.EscapeString(s.BaseURL), s.xsrfToken)
+}
+
+func (s *Server) serveNavigationContext(w http.ResponseWriter) {
+	writeJSONData(w, map[string]any{
+		"user": map[string]any{
+			"id":            s.Employee.ID,
+			"type":          "employee",
+			"isAdmin":       false,
+			"fullName":      s.Employee.FirstName + " " + s.Employee.LastName,
+			"position":      s.Employee.Position,
+			"impersonated":  false,
+			"context":       "client",
+			"preferredName": s.Employee.FirstName,
+			"firstName":     s.Employee.FirstName,
+			"lastName":      s.Employee.LastName,
+			"email":         s.Email,
+		},
+	})
+}
+
+func (s *Server) serveEmployee(w http.ResponseWriter, idStr string) {
+	id, err := strconv.Atoi(idStr)
+	if err != nil || id != s.Employee.ID {
+		writeJSONError(w, http.StatusNotFound, "employee not found")
+		return
+	}
+	writeJSONData(w, s.Employee)
+}
+
+func (s *Server) serveTimesheet(w http.ResponseWriter, r *http.Request, idStr string) {
+	id, err := strconv.Atoi(idStr)
+	if err != nil || id != s.Employee.ID {
+		writeJSONError(w, http.StatusForbidden, "not allowed to view timesheet")
+		return
+	}
+	start, err := time.Parse(time.DateOnly, r.URL.Query().Get("start_date"))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "invalid start_date")
+		return
+	}
+	end, err := time.Parse(time.DateOnly, r.URL.Query().Get("end_date"))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "invalid end_date")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(personio.TimecardResponse{
+		Timecards: s.timecards(start, end),
+	})
+}
+
+func (s *Server) servePutDay(w http.ResponseWriter, r *http.Request, idStr string) {
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "invalid day ID")
+		return
+	}
+	var body personio.SetAttendanceDayRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "invalid body: "+err.Error())
+		return
+	}
+	if body.EmployeeID != s.Employee.ID {
+		writeJSONError(w, http.StatusForbidden, "not allowed to edit attendance")
+		return
+	}
+	d, ok := s.days[id]
+	if !ok {
+		if len(body.Periods) == 0 {
+			writeJSONError(w, http.StatusUnprocessableEntity, "no periods")
+			return
+		}
+		date := body.Periods[0].Start.Format(time.DateOnly)
+		if _, _, exists := s.findDayByDate(date); exists {
+			writeJSONError(w, http.StatusConflict, "day already exists with different ID")
+			return
+		}
+		d = &day{date: date}
+		s.days[id] = d
+	}
+	d.periods = nil
+	for _, p := range body.Periods {
+		d.periods = append(d.periods, personio.Period(p))
+	}
+	writeJSONData(w, map[string]any{"id": id})
+}
+
+func (s *Server) serveDeleteDay(w http.ResponseWriter, idStr string) {
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "invalid day ID")
+		return
+	}
+	if _, ok := s.days[id]; !ok {
+		writeJSONError(w, http.StatusNotFound, "day not found")
+		return
+	}
+	delete(s.days, id)
+	writeJSONData(w, map[string]any{"id": id})
+}
+
+func writeJSONData(w http.ResponseWriter, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"success": true,
+		"data":    data,
+	})
+}
+
+func writeJSONError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]any{
+		"success": false,
+		"error": map[string]any{
+			"code":    status,
+			"message": message,
+		},
+	})
+}
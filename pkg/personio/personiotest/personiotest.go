@@ -0,0 +1,284 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package personiotest provides a fake Personio server for testing code that
+// uses the [personio.Client] without accessing the real Personio.
+//
+// The fake server keeps all its state in memory, which can be inspected and
+// modified by the tests. Use [Server.NewClient] to get a client that sends
+// all its requests to the fake server, including the requests to the
+// login pages.
+package personiotest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/applejag/rootless-personio/pkg/config"
+	"github.com/applejag/rootless-personio/pkg/personio"
+	"github.com/google/uuid"
+)
+
+// Default values used by [NewServer].
+const (
+	DefaultBaseURL    = "https://example.personio.de"
+	DefaultLoginHost  = "login.personio.com"
+	DefaultEmail      = "jane.doe@example.com"
+	DefaultPassword   = "SuperSecretPassword1234"
+	DefaultEmployeeID = 1234
+)
+
+// Server is a fake Personio server.
+//
+// The exported fields can be changed to configure the server, but must not be
+// changed while a request is being served.
+type Server struct {
+	// BaseURL is the URL of the fake Personio instance, as passed to
+	// [personio.New]. The host does not need to exist, as requests are
+	// routed to the fake server by [Server.Transport]. It may contain a
+	// path, to act like Personio behind a reverse proxy.
+	BaseURL string
+	// LoginHost is the host of the fake login pages, as passed to
+	// [personio.WithLoginURL].
+	LoginHost string
+
+	// Email and Password are the only accepted credentials.
+	Email    string
+	Password string
+	// TOTPSecret enables the 2FA step of the login when set, and only
+	// accepts codes generated from this secret.
+	TOTPSecret string
+	// EmailToken enables the "confirm login from new device" step of the
+	// login when set, and only accepts this token.
+	EmailToken string
+
+	Employee personio.Employee
+	Projects []personio.Project
+	// TargetMinutes is the contractual work duration used for all
+	// weekdays. Weekends are always off days.
+	TargetMinutes int
+
+	server *httptest.Server
+
+	mu          sync.Mutex
+	loginStates map[string]*loginState
+	sessions    map[string]bool
+	xsrfToken   string
+	athenaToken string
+	days        map[uuid.UUID]*day
+	requests    []Request
+}
+
+// Request is a request received by the fake server.
+type Request struct {
+	Method string
+	Host   string
+	Path   string
+	Query  string
+}
+
+type loginState struct {
+	email      string
+	passwordOK bool
+}
+
+type day struct {
+	date    string
+	periods []personio.Period
+}
+
+// NewServer starts and returns a new fake Personio server with default
+// values. The caller should call Close when finished, to shut it down.
+func NewServer() *Server {
+	s := &Server{
+		BaseURL:   DefaultBaseURL,
+		LoginHost: DefaultLoginHost,
+		Email:     DefaultEmail,
+		Password:  DefaultPassword,
+		Employee: personio.Employee{
+			ID:         DefaultEmployeeID,
+			FirstName:  "Jane",
+			LastName:   "Doe",
+			Position:   "DevOps Engineer",
+			Department: "Engineering",
+			Office:     "Berlin",
+			Team:       "Platform",
+		},
+		Projects: []personio.Project{
+			newProject(1, "Project X", true),
+			newProject(2, "Project Y", true),
+			newProject(3, "Old project", false),
+		},
+		TargetMinutes: 8 * 60,
+		loginStates:   map[string]*loginState{},
+		sessions:      map[string]bool{},
+		days:          map[uuid.UUID]*day{},
+		xsrfToken:     uuid.NewString(),
+		athenaToken:   uuid.NewString(),
+	}
+	s.server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+func newProject(id int, name string, active bool) personio.Project {
+	p := personio.Project{ID: id}
+	p.Attributes.Name = name
+	p.Attributes.Active = active
+	return p
+}
+
+// Close shuts down the server.
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+// Auth returns an auth config with the credentials accepted by the server.
+func (s *Server) Auth() config.Auth {
+	return config.Auth{
+		Email:      s.Email,
+		Password:   s.Password,
+		TOTPSecret: s.TOTPSecret,
+	}
+}
+
+// Transport returns a [http.RoundTripper] that sends all requests to the
+// fake server, regardless of their host.
+func (s *Server) Transport() http.RoundTripper {
+	return transport{
+		addr: s.server.Listener.Addr().String(),
+		rt:   s.server.Client().Transport,
+	}
+}
+
+// NewClient returns a new client that is not yet logged in,
+// which sends all its requests to the fake server.
+func (s *Server) NewClient(opts ...personio.Option) (*personio.Client, error) {
+	return personio.New(s.BaseURL, append([]personio.Option{
+		personio.WithTransport(s.Transport()),
+		personio.WithLoginURL("https://" + s.LoginHost),
+	}, opts...)...)
+}
+
+// Requests returns all requests received by the server so far.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.requests)
+}
+
+// Periods returns the attendance periods stored for a date,
+// formatted as YYYY-MM-DD.
+func (s *Server) Periods(date string) []personio.Period {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if d, _, ok := s.findDayByDate(date); ok {
+		return slices.Clone(d.periods)
+	}
+	return nil
+}
+
+// SetPeriods replaces the attendance periods stored for a date,
+// formatted as YYYY-MM-DD, and returns the day's ID.
+func (s *Server) SetPeriods(date string, periods []personio.Period) uuid.UUID {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d, id, ok := s.findDayByDate(date)
+	if !ok {
+		id = uuid.New()
+		d = &day{date: date}
+		s.days[id] = d
+	}
+	d.periods = slices.Clone(periods)
+	for i := range d.periods {
+		if d.periods[i].ID == uuid.Nil {
+			d.periods[i].ID = uuid.New()
+		}
+	}
+	return id
+}
+
+// DayID returns the ID of the day for a date, formatted as YYYY-MM-DD,
+// or false if no attendance has been stored for that date.
+func (s *Server) DayID(date string) (uuid.UUID, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, id, ok := s.findDayByDate(date)
+	return id, ok
+}
+
+func (s *Server) findDayByDate(date string) (*day, uuid.UUID, bool) {
+	for id, d := range s.days {
+		if d.date == date {
+			return d, id, true
+		}
+	}
+	return nil, uuid.Nil, false
+}
+
+func (s *Server) timecards(start, end time.Time) []personio.Timecard {
+	var cards []personio.Timecard
+	for date := start; !date.After(end); date = date.AddDate(0, 0, 1) {
+		dateStr := date.Format(time.DateOnly)
+		card := personio.Timecard{
+			Date:    dateStr,
+			State:   "empty",
+			Periods: []personio.Period{},
+		}
+		if weekday := date.Weekday(); weekday == time.Saturday || weekday == time.Sunday {
+			card.IsOffDay = true
+		} else {
+			card.TargetHours.ContractualWorkDurationMinutes = s.TargetMinutes
+			card.TargetHours.EffectiveWorkDurationMinutes = s.TargetMinutes
+		}
+		if d, id, ok := s.findDayByDate(dateStr); ok {
+			card.DayID = &id
+			card.Periods = slices.Clone(d.periods)
+			sort.Slice(card.Periods, func(i, j int) bool {
+				return card.Periods[i].Start.Before(card.Periods[j].Start.Time)
+			})
+			if len(card.Periods) > 0 {
+				card.State = "trackable"
+			}
+		}
+		cards = append(cards, card)
+	}
+	return cards
+}
+
+type transport struct {
+	addr string
+	rt   http.RoundTripper
+}
+
+// RoundTrip implements [http.RoundTripper].
+func (t transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	clone := req.Clone(req.Context())
+	clone.URL.Scheme = "http"
+	clone.URL.Host = t.addr
+	clone.Host = req.URL.Host
+	resp, err := t.rt.RoundTrip(clone)
+	if err != nil {
+		return nil, err
+	}
+	// Pretend that the response came from the original URL
+	resp.Request = req
+	return resp, nil
+}
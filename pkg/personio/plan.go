@@ -0,0 +1,152 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package personio
+
+import (
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ChangeType is the kind of a [PeriodChange].
+type ChangeType string
+
+// Available [ChangeType] values.
+const (
+	ChangeAdd    ChangeType = "add"
+	ChangeRemove ChangeType = "remove"
+	ChangeModify ChangeType = "modify"
+)
+
+// PeriodChange is a change of a single attendance period.
+type PeriodChange struct {
+	Change ChangeType `json:"change"`
+	// Old is nil for added periods.
+	Old *Period `json:"old"`
+	// New is nil for removed periods.
+	New *Period `json:"new"`
+}
+
+// DayPlan is the set of changes that will be made to a day's attendance.
+// See [DiffPeriods].
+type DayPlan struct {
+	Date string `json:"date"`
+	// DeleteDay is true when the whole day is deleted via
+	// [Client.DeleteAttendance], instead of replacing its periods.
+	DeleteDay bool           `json:"delete_day"`
+	Changes   []PeriodChange `json:"changes"`
+}
+
+// HasChanges returns true if the plan changes any periods.
+func (p DayPlan) HasChanges() bool {
+	return p.DeleteDay || len(p.Changes) > 0
+}
+
+// DiffPeriods returns the changes needed to replace the old periods of
+// a day with the new periods, as done by [Client.SetAttendance].
+//
+// Periods are matched by their ID, so new periods without an ID are always
+// added, and old periods missing from the new periods are removed.
+// See [ReusePeriodIDs] to match them by their start time instead.
+// The periods are compared the same way as [Client.SetAttendance] sends
+// them, e.g with the times truncated to whole seconds.
+func DiffPeriods(date string, oldPeriods, newPeriods []Period) DayPlan {
+	plan := DayPlan{Date: date, Changes: []PeriodChange{}}
+	for i := range newPeriods {
+		newPeriod := normalizePeriod(newPeriods[i])
+		j := slices.IndexFunc(oldPeriods, func(p Period) bool {
+			return newPeriod.ID != uuid.Nil && p.ID == newPeriod.ID
+		})
+		if j == -1 {
+			plan.Changes = append(plan.Changes, PeriodChange{Change: ChangeAdd, New: &newPeriod})
+			continue
+		}
+		oldPeriod := oldPeriods[j]
+		if !periodsEqual(oldPeriod, newPeriod) {
+			plan.Changes = append(plan.Changes, PeriodChange{Change: ChangeModify, Old: &oldPeriod, New: &newPeriod})
+		}
+	}
+	for i := range oldPeriods {
+		oldPeriod := oldPeriods[i]
+		found := slices.ContainsFunc(newPeriods, func(p Period) bool {
+			return p.ID == oldPeriod.ID
+		})
+		if !found {
+			plan.Changes = append(plan.Changes, PeriodChange{Change: ChangeRemove, Old: &oldPeriod})
+		}
+	}
+	return plan
+}
+
+// ReusePeriodIDs returns a copy of the new periods, where the periods
+// without an ID get the ID of the old period with the same start time.
+// This makes [Client.SetAttendance] update the old periods instead of
+// replacing them, and [DiffPeriods] show them as unchanged or modified,
+// such as when importing the same periods again.
+//
+// Each old period's ID is only used once, and never when a new period
+// already has it.
+func ReusePeriodIDs(oldPeriods, newPeriods []Period) []Period {
+	newPeriods = slices.Clone(newPeriods)
+	used := map[uuid.UUID]bool{}
+	for _, p := range newPeriods {
+		if p.ID != uuid.Nil {
+			used[p.ID] = true
+		}
+	}
+	for i := range newPeriods {
+		if newPeriods[i].ID != uuid.Nil {
+			continue
+		}
+		start := newPeriods[i].Start.Truncate(time.Second)
+		j := slices.IndexFunc(oldPeriods, func(p Period) bool {
+			return !used[p.ID] && p.Start.Truncate(time.Second).Equal(start)
+		})
+		if j != -1 {
+			newPeriods[i].ID = oldPeriods[j].ID
+			used[oldPeriods[j].ID] = true
+		}
+	}
+	return newPeriods
+}
+
+// DiffDelete returns the changes of deleting all periods of a day,
+// as done by [Client.DeleteAttendance].
+func DiffDelete(date string, oldPeriods []Period) DayPlan {
+	plan := DiffPeriods(date, oldPeriods, nil)
+	plan.DeleteDay = true
+	return plan
+}
+
+func normalizePeriod(p Period) Period {
+	p.Start = PersonioTime{p.Start.Truncate(time.Second)}
+	p.End = PersonioTime{p.End.Truncate(time.Second)}
+	if p.Type == "" {
+		p.Type = PeriodTypeWork
+	}
+	return p
+}
+
+func periodsEqual(a, b Period) bool {
+	return a.Start.Equal(b.Start.Time) &&
+		a.End.Equal(b.End.Time) &&
+		a.Type == b.Type &&
+		a.GetProjectID() == b.GetProjectID() &&
+		a.GetComment() == b.GetComment()
+}
@@ -0,0 +1,208 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package personio
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestDiffPeriods(t *testing.T) {
+	start := time.Date(2023, 1, 18, 8, 0, 0, 0, time.UTC)
+	morning := Period{
+		ID:    uuid.New(),
+		Start: PersonioTime{start},
+		End:   PersonioTime{start.Add(4 * time.Hour)},
+		Type:  PeriodTypeWork,
+	}
+	lunch := Period{
+		ID:    uuid.New(),
+		Start: PersonioTime{start.Add(4 * time.Hour)},
+		End:   PersonioTime{start.Add(5 * time.Hour)},
+		Type:  PeriodTypeBreak,
+	}
+	longerLunch := lunch
+	longerLunch.End = PersonioTime{start.Add(5*time.Hour + 30*time.Minute)}
+	comment := "Lunch"
+	commentedLunch := lunch
+	commentedLunch.Comment = &comment
+	sameLunch := lunch
+	sameLunch.Start = PersonioTime{lunch.Start.Add(300 * time.Millisecond)}
+	afternoon := Period{
+		Start: PersonioTime{start.Add(5 * time.Hour)},
+		End:   PersonioTime{start.Add(9 * time.Hour)},
+	}
+
+	var tests = []struct {
+		name string
+		old  []Period
+		new  []Period
+		want []ChangeType
+	}{
+		{
+			name: "unchanged",
+			old:  []Period{morning, lunch},
+			new:  []Period{morning, sameLunch},
+			want: nil,
+		},
+		{
+			name: "added without ID",
+			old:  []Period{morning, lunch},
+			new:  []Period{morning, lunch, afternoon},
+			want: []ChangeType{ChangeAdd},
+		},
+		{
+			name: "removed",
+			old:  []Period{morning, lunch},
+			new:  []Period{morning},
+			want: []ChangeType{ChangeRemove},
+		},
+		{
+			name: "modified time",
+			old:  []Period{morning, lunch},
+			new:  []Period{morning, longerLunch},
+			want: []ChangeType{ChangeModify},
+		},
+		{
+			name: "modified comment",
+			old:  []Period{morning, lunch},
+			new:  []Period{morning, commentedLunch},
+			want: []ChangeType{ChangeModify},
+		},
+		{
+			name: "replaced",
+			old:  []Period{morning},
+			new:  []Period{afternoon},
+			want: []ChangeType{ChangeAdd, ChangeRemove},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			plan := DiffPeriods("2023-01-18", tc.old, tc.new)
+			var got []ChangeType
+			for _, change := range plan.Changes {
+				got = append(got, change.Change)
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("want changes %v, got %v", tc.want, got)
+			}
+			for i := range got {
+				if got[i] != tc.want[i] {
+					t.Errorf("change %d: want %q, got %q", i, tc.want[i], got[i])
+				}
+			}
+			if plan.HasChanges() != (len(tc.want) > 0) {
+				t.Errorf("want HasChanges %t, got %t", len(tc.want) > 0, plan.HasChanges())
+			}
+		})
+	}
+}
+
+func TestReusePeriodIDs(t *testing.T) {
+	start := time.Date(2023, 1, 18, 8, 0, 0, 0, time.UTC)
+	old := []Period{
+		{
+			ID:    uuid.New(),
+			Start: PersonioTime{start},
+			End:   PersonioTime{start.Add(4 * time.Hour)},
+			Type:  PeriodTypeWork,
+		},
+		{
+			ID:    uuid.New(),
+			Start: PersonioTime{start.Add(4 * time.Hour)},
+			End:   PersonioTime{start.Add(5 * time.Hour)},
+			Type:  PeriodTypeBreak,
+		},
+		{
+			ID:    uuid.New(),
+			Start: PersonioTime{start.Add(5 * time.Hour)},
+			End:   PersonioTime{start.Add(9 * time.Hour)},
+			Type:  PeriodTypeWork,
+		},
+	}
+	withoutID := func(p Period) Period {
+		p.ID = uuid.Nil
+		return p
+	}
+	// Same periods as imported again, where the import has no IDs
+	imported := []Period{withoutID(old[0]), withoutID(old[1]), withoutID(old[2])}
+	imported[1].Start = PersonioTime{imported[1].Start.Add(300 * time.Millisecond)}
+	// Longer afternoon
+	imported[2].End = PersonioTime{imported[2].End.Add(time.Hour)}
+	// New evening period
+	imported = append(imported, Period{
+		Start: PersonioTime{start.Add(11 * time.Hour)},
+		End:   PersonioTime{start.Add(12 * time.Hour)},
+	})
+
+	got := ReusePeriodIDs(old, imported)
+	for i, want := range []uuid.UUID{old[0].ID, old[1].ID, old[2].ID, uuid.Nil} {
+		if got[i].ID != want {
+			t.Errorf("period %d: want ID %s, got %s", i, want, got[i].ID)
+		}
+	}
+	if imported[0].ID != uuid.Nil {
+		t.Error("want new periods unchanged")
+	}
+
+	plan := DiffPeriods("2023-01-18", old, got)
+	var changes []ChangeType
+	for _, change := range plan.Changes {
+		changes = append(changes, change.Change)
+	}
+	if want := []ChangeType{ChangeModify, ChangeAdd}; !slices.Equal(changes, want) {
+		t.Errorf("want changes %v, got %v", want, changes)
+	}
+	if plan := DiffPeriods("2023-01-18", old, ReusePeriodIDs(old, imported[:2])); len(plan.Changes) != 1 ||
+		plan.Changes[0].Change != ChangeRemove {
+		t.Errorf("want only the afternoon removed, got %+v", plan.Changes)
+	}
+
+	// IDs are only used once
+	twice := ReusePeriodIDs(old, []Period{{ID: old[0].ID, Start: old[1].Start}, withoutID(old[1])})
+	if twice[1].ID != old[1].ID {
+		t.Errorf("want second period to reuse ID %s, got %s", old[1].ID, twice[1].ID)
+	}
+	twice = ReusePeriodIDs(old, []Period{withoutID(old[0]), withoutID(old[0])})
+	if twice[0].ID != old[0].ID || twice[1].ID != uuid.Nil {
+		t.Errorf("want only first period to reuse the ID, got %s and %s", twice[0].ID, twice[1].ID)
+	}
+}
+
+func TestDiffDelete(t *testing.T) {
+	start := time.Date(2023, 1, 18, 8, 0, 0, 0, time.UTC)
+	old := []Period{{
+		ID:    uuid.New(),
+		Start: PersonioTime{start},
+		End:   PersonioTime{start.Add(8 * time.Hour)},
+		Type:  PeriodTypeWork,
+	}}
+	plan := DiffDelete("2023-01-18", old)
+	if !plan.DeleteDay {
+		t.Error("want DeleteDay, got false")
+	}
+	if len(plan.Changes) != 1 || plan.Changes[0].Change != ChangeRemove {
+		t.Errorf("want 1 removed period, got %+v", plan.Changes)
+	}
+	if !DiffDelete("2023-01-19", nil).HasChanges() {
+		t.Error("want deleting an empty day to still be a change")
+	}
+}
@@ -1,10 +1,16 @@
 package personio
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
 )
 
+var (
+	ErrProjectNotFound = errors.New("project not found")
+)
+
 type Project struct {
 	ID         int `json:"id"`
 	Attributes struct {
@@ -13,8 +19,14 @@ type Project struct {
 	} `json:"attributes"`
 }
 
+// GetProjectID calls [Client.GetProjectIDContext] with [context.Background].
 func (client *Client) GetProjectID(name string) (int, error) {
-	err := client.cacheProjects()
+	return client.GetProjectIDContext(context.Background(), name)
+}
+
+// GetProjectIDContext returns the ID of the project with the given name.
+func (client *Client) GetProjectIDContext(ctx context.Context, name string) (int, error) {
+	err := client.cacheProjects(ctx)
 	if err != nil {
 		return 0, err
 	}
@@ -23,11 +35,17 @@ func (client *Client) GetProjectID(name string) (int, error) {
 			return project.ID, nil
 		}
 	}
-	return 0, fmt.Errorf("project %s not found", name)
+	return 0, fmt.Errorf("%w: %q", ErrProjectNotFound, name)
 }
 
+// GetProjectName calls [Client.GetProjectNameContext] with [context.Background].
 func (client *Client) GetProjectName(id int) (string, error) {
-	err := client.cacheProjects()
+	return client.GetProjectNameContext(context.Background(), id)
+}
+
+// GetProjectNameContext returns the name of the project with the given ID.
+func (client *Client) GetProjectNameContext(ctx context.Context, id int) (string, error) {
+	err := client.cacheProjects(ctx)
 	if err != nil {
 		return "", err
 	}
@@ -36,14 +54,14 @@ func (client *Client) GetProjectName(id int) (string, error) {
 			return project.Attributes.Name, nil
 		}
 	}
-	return "", fmt.Errorf("project %d not found", id)
+	return "", fmt.Errorf("%w: %d", ErrProjectNotFound, id)
 }
 
-func (client *Client) cacheProjects() error {
+func (client *Client) cacheProjects(ctx context.Context) error {
 	if client.projectCache != nil {
 		return nil
 	}
-	request, err := http.NewRequest("GET", "/api/v1/projects", nil)
+	request, err := http.NewRequestWithContext(ctx, "GET", "/api/v1/projects", nil)
 	if err != nil {
 		return err
 	}
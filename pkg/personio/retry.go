@@ -0,0 +1,228 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package personio
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// RetryPolicy controls how failed HTTP requests are retried.
+//
+// A request is retried on transport errors and on the status codes
+// 429 Too Many Requests, 502 Bad Gateway, 503 Service Unavailable and
+// 504 Gateway Timeout, but only if it is safe to send again:
+// the method must be idempotent (GET, HEAD, OPTIONS, TRACE, PUT, DELETE),
+// or the server must have responded with 429, meaning the request was
+// never processed. Requests with a body are only retried if the body can
+// be replayed, i.e if [http.Request.GetBody] is set.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of times a request is sent,
+	// including the first attempt. A value of 1 or less disables retries.
+	MaxAttempts int
+	// MinBackoff is the wait before the first retry. The wait is doubled
+	// for every following retry, with some random jitter added.
+	MinBackoff time.Duration
+	// MaxBackoff is the longest wait between two attempts. If the server
+	// asks us to wait longer than this via the Retry-After header,
+	// the request is not retried.
+	MaxBackoff time.Duration
+}
+
+// DefaultRetryPolicy is the retry policy used unless changed via
+// [WithRetryPolicy].
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 4,
+	MinBackoff:  500 * time.Millisecond,
+	MaxBackoff:  30 * time.Second,
+}
+
+// WithRetryPolicy sets how failed HTTP requests are retried,
+// instead of [DefaultRetryPolicy].
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(c *Client) {
+		c.retryPolicy = policy
+	}
+}
+
+// WithRateLimit limits how many HTTP requests the client sends, using a
+// token bucket that is refilled with the given number of requests per second,
+// and can hold at most burst requests. A rate of 0 or less disables the limit.
+func WithRateLimit(requestsPerSecond float64, burst int) Option {
+	return func(c *Client) {
+		c.rateLimiter = newRateLimiter(requestsPerSecond, burst)
+	}
+}
+
+// retryTransport is a [http.RoundTripper] that waits for the rate limiter
+// before each attempt, and retries failed requests according to the
+// [RetryPolicy].
+type retryTransport struct {
+	next    http.RoundTripper
+	policy  RetryPolicy
+	limiter *rateLimiter
+}
+
+// RoundTrip implements [http.RoundTripper].
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	for attempt := 1; ; attempt++ {
+		if err := t.limiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+		attemptReq, err := rewindRequest(req, attempt)
+		if err != nil {
+			return nil, err
+		}
+
+		log.Debug().
+			Str("method", req.Method).
+			Str("url", req.URL.Redacted()).
+			Int("attempt", attempt).
+			Msg("Sending HTTP request.")
+		resp, err := t.next.RoundTrip(attemptReq)
+
+		if attempt >= t.policy.MaxAttempts || !canRetry(req, resp, err) {
+			return resp, err
+		}
+		wait, ok := t.backoff(attempt, resp)
+		if !ok {
+			return resp, err
+		}
+
+		event := log.Warn().
+			Str("method", req.Method).
+			Str("url", req.URL.Redacted()).
+			Int("attempt", attempt).
+			Dur("wait", wait)
+		if err != nil {
+			event = event.Err(err)
+		} else {
+			event = event.Int("status", resp.StatusCode)
+			// Drain the body, so the connection can be reused
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		event.Msg("HTTP request failed, retrying.")
+
+		if err := sleepContext(ctx, wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// rewindRequest returns the request to send for an attempt, with a fresh
+// copy of the body for all attempts after the first one.
+func rewindRequest(req *http.Request, attempt int) (*http.Request, error) {
+	if attempt == 1 || req.Body == nil || req.Body == http.NoBody {
+		return req, nil
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	clone := req.Clone(req.Context())
+	clone.Body = body
+	return clone, nil
+}
+
+func canRetry(req *http.Request, resp *http.Response, err error) bool {
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return false
+		}
+		return isIdempotent(req.Method)
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests:
+		return true
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return isIdempotent(req.Method)
+	default:
+		return false
+	}
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace,
+		http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// backoff returns how long to wait before the next attempt, or false if
+// the server asked us to wait longer than the policy allows.
+func (t *retryTransport) backoff(attempt int, resp *http.Response) (time.Duration, bool) {
+	if resp != nil {
+		if wait, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+			return wait, wait <= t.policy.MaxBackoff
+		}
+	}
+	wait := t.policy.MinBackoff << (attempt - 1)
+	if wait <= 0 || wait > t.policy.MaxBackoff {
+		wait = t.policy.MaxBackoff
+	}
+	// "Equal jitter", to not have all clients retry at the same time
+	half := wait / 2
+	if half > 0 {
+		wait = half + rand.N(half)
+	}
+	return wait, true
+}
+
+// parseRetryAfter parses the Retry-After header, which is either a number
+// of seconds or an HTTP date.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(date.Sub(now), 0), true
+	}
+	return 0, false
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,286 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package personio
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeRoundTripper struct {
+	responses []fakeResponse
+	bodies    []string
+}
+
+type fakeResponse struct {
+	status     int
+	retryAfter string
+	err        error
+}
+
+func (f *fakeRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body string
+	if req.Body != nil {
+		b, _ := io.ReadAll(req.Body)
+		body = string(b)
+	}
+	f.bodies = append(f.bodies, body)
+	next := f.responses[0]
+	if len(f.responses) > 1 {
+		f.responses = f.responses[1:]
+	}
+	if next.err != nil {
+		return nil, next.err
+	}
+	resp := &http.Response{
+		StatusCode: next.status,
+		Header:     http.Header{},
+		Body:       io.NopCloser(strings.NewReader("")),
+		Request:    req,
+	}
+	if next.retryAfter != "" {
+		resp.Header.Set("Retry-After", next.retryAfter)
+	}
+	return resp, nil
+}
+
+func TestRetryTransport(t *testing.T) {
+	errNetwork := errors.New("connection reset")
+	var tests = []struct {
+		name         string
+		method       string
+		body         io.Reader
+		responses    []fakeResponse
+		wantStatus   int
+		wantErr      error
+		wantAttempts int
+	}{
+		{
+			name:         "success on first attempt",
+			method:       http.MethodGet,
+			responses:    []fakeResponse{{status: 200}},
+			wantStatus:   200,
+			wantAttempts: 1,
+		},
+		{
+			name:         "retries GET on 503",
+			method:       http.MethodGet,
+			responses:    []fakeResponse{{status: 503}, {status: 503}, {status: 200}},
+			wantStatus:   200,
+			wantAttempts: 3,
+		},
+		{
+			name:         "gives up after max attempts",
+			method:       http.MethodGet,
+			responses:    []fakeResponse{{status: 502}},
+			wantStatus:   502,
+			wantAttempts: 4,
+		},
+		{
+			name:         "retries GET on transport error",
+			method:       http.MethodGet,
+			responses:    []fakeResponse{{err: errNetwork}, {status: 200}},
+			wantStatus:   200,
+			wantAttempts: 2,
+		},
+		{
+			name:         "does not retry POST on transport error",
+			method:       http.MethodPost,
+			body:         strings.NewReader("a=b"),
+			responses:    []fakeResponse{{err: errNetwork}, {status: 200}},
+			wantErr:      errNetwork,
+			wantAttempts: 1,
+		},
+		{
+			name:         "does not retry POST on 503",
+			method:       http.MethodPost,
+			body:         strings.NewReader("a=b"),
+			responses:    []fakeResponse{{status: 503}, {status: 200}},
+			wantStatus:   503,
+			wantAttempts: 1,
+		},
+		{
+			name:         "retries POST on 429",
+			method:       http.MethodPost,
+			body:         strings.NewReader("a=b"),
+			responses:    []fakeResponse{{status: 429, retryAfter: "0"}, {status: 200}},
+			wantStatus:   200,
+			wantAttempts: 2,
+		},
+		{
+			name:         "retries PUT on 429 with replayable body",
+			method:       http.MethodPut,
+			body:         strings.NewReader(`{"periods":[]}`),
+			responses:    []fakeResponse{{status: 429}, {status: 429}, {status: 200}},
+			wantStatus:   200,
+			wantAttempts: 3,
+		},
+		{
+			name:         "does not retry non-replayable body",
+			method:       http.MethodPut,
+			body:         io.MultiReader(strings.NewReader(`{"periods":[]}`)),
+			responses:    []fakeResponse{{status: 429}, {status: 200}},
+			wantStatus:   429,
+			wantAttempts: 1,
+		},
+		{
+			name:         "does not retry when Retry-After is too long",
+			method:       http.MethodGet,
+			responses:    []fakeResponse{{status: 429, retryAfter: "3600"}, {status: 200}},
+			wantStatus:   429,
+			wantAttempts: 1,
+		},
+		{
+			name:         "does not retry 404",
+			method:       http.MethodGet,
+			responses:    []fakeResponse{{status: 404}, {status: 200}},
+			wantStatus:   404,
+			wantAttempts: 1,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fake := &fakeRoundTripper{responses: tc.responses}
+			transport := &retryTransport{
+				next: fake,
+				policy: RetryPolicy{
+					MaxAttempts: 4,
+					MinBackoff:  time.Millisecond,
+					MaxBackoff:  10 * time.Millisecond,
+				},
+			}
+			req, err := http.NewRequest(tc.method, "https://example.personio.de/", tc.body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := transport.RoundTrip(req)
+			if tc.wantErr != nil {
+				if !errors.Is(err, tc.wantErr) {
+					t.Errorf("want error %v, got %v", tc.wantErr, err)
+				}
+			} else if err != nil {
+				t.Fatalf("want status %d, got error: %s", tc.wantStatus, err)
+			} else if resp.StatusCode != tc.wantStatus {
+				t.Errorf("want status %d, got %d", tc.wantStatus, resp.StatusCode)
+			}
+			if len(fake.bodies) != tc.wantAttempts {
+				t.Errorf("want %d attempts, got %d", tc.wantAttempts, len(fake.bodies))
+			}
+			for i, body := range fake.bodies[1:] {
+				if body != fake.bodies[0] {
+					t.Errorf("attempt %d: want replayed body %q, got %q", i+2, fake.bodies[0], body)
+				}
+			}
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2023, 1, 18, 12, 0, 0, 0, time.UTC)
+	var tests = []struct {
+		name   string
+		value  string
+		want   time.Duration
+		wantOK bool
+	}{
+		{
+			name:   "empty",
+			value:  "",
+			wantOK: false,
+		},
+		{
+			name:   "seconds",
+			value:  "120",
+			want:   2 * time.Minute,
+			wantOK: true,
+		},
+		{
+			name:   "HTTP date",
+			value:  "Wed, 18 Jan 2023 12:00:30 GMT",
+			want:   30 * time.Second,
+			wantOK: true,
+		},
+		{
+			name:   "HTTP date in the past",
+			value:  "Wed, 18 Jan 2023 11:00:00 GMT",
+			want:   0,
+			wantOK: true,
+		},
+		{
+			name:   "negative",
+			value:  "-5",
+			wantOK: false,
+		},
+		{
+			name:   "junk",
+			value:  "soon",
+			wantOK: false,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := parseRetryAfter(tc.value, now)
+			if ok != tc.wantOK {
+				t.Fatalf("want ok=%t, got ok=%t", tc.wantOK, ok)
+			}
+			if got != tc.want {
+				t.Errorf("want %s, got %s", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	now := time.Date(2023, 1, 18, 12, 0, 0, 0, time.UTC)
+	l := newRateLimiter(2, 3)
+	l.now = func() time.Time { return now }
+
+	// Burst is available right away
+	for i := 0; i < 3; i++ {
+		if wait := l.reserve(); wait != 0 {
+			t.Fatalf("request %d: want no wait, got %s", i+1, wait)
+		}
+	}
+	if wait := l.reserve(); wait != 500*time.Millisecond {
+		t.Errorf("want 500ms wait when bucket is empty, got %s", wait)
+	}
+	if wait := l.reserve(); wait != time.Second {
+		t.Errorf("want 1s wait for queued request, got %s", wait)
+	}
+
+	// Refills over time, but never above the burst
+	now = now.Add(time.Hour)
+	for i := 0; i < 3; i++ {
+		if wait := l.reserve(); wait != 0 {
+			t.Fatalf("after refill, request %d: want no wait, got %s", i+1, wait)
+		}
+	}
+	if wait := l.reserve(); wait == 0 {
+		t.Error("want wait after using refilled burst, got none")
+	}
+
+	if newRateLimiter(0, 10) != nil {
+		t.Error("want nil limiter when rate is 0")
+	}
+}
@@ -0,0 +1,175 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package personio
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+var (
+	ErrSessionBaseURLMismatch = errors.New("session base URL mismatch")
+)
+
+// Session is a snapshot of an authenticated client, meant to be persisted
+// between invocations so the program does not have to log in every time.
+type Session struct {
+	BaseURL         string          `json:"baseUrl"`
+	EmployeeID      int             `json:"employeeId"`
+	XSRFToken       string          `json:"xsrfToken,omitempty"`
+	AthenaXSRFToken string          `json:"athenaXsrfToken,omitempty"`
+	Cookies         []SessionCookie `json:"cookies"`
+	// UnlockCSRFToken is set when the login is waiting for the email token,
+	// as the login then needs to be finished via [Client.UnlockWithToken].
+	UnlockCSRFToken string    `json:"unlockCsrfToken,omitempty"`
+	SavedAt         time.Time `json:"savedAt"`
+}
+
+// SessionCookie is a cookie stored in a [Session], together with the URL
+// it was obtained from.
+type SessionCookie struct {
+	URL   string `json:"url"`
+	Name  string `json:"name"`
+	Value string `json:"value"`
+	// Domain is empty for host-only cookies, which are only sent to the
+	// host of the URL.
+	Domain string `json:"domain,omitempty"`
+	Path   string `json:"path,omitempty"`
+	Secure bool   `json:"secure,omitempty"`
+	// Expires is zero for cookies that only last for the browser session.
+	Expires time.Time `json:"expires"`
+}
+
+// Session returns a snapshot of the client's cookies and tokens.
+func (c *Client) Session() *Session {
+	s := &Session{
+		BaseURL:         c.BaseURL,
+		EmployeeID:      c.EmployeeID,
+		UnlockCSRFToken: c.unlockCSRFToken,
+		SavedAt:         time.Now(),
+		Cookies:         c.jar.snapshot(),
+	}
+	if baseURL, err := url.Parse(c.BaseURL); err == nil {
+		s.XSRFToken, _ = c.findCookie(baseURL, "XSRF-TOKEN")
+		s.AthenaXSRFToken, _ = c.findCookie(baseURL, "ATHENA-XSRF-TOKEN")
+	}
+	return s
+}
+
+// RestoreSession loads the cookies and tokens from a previously saved
+// [Session] into the client.
+//
+// The session is not validated. Use [Client.CheckSession] to verify that
+// it is still usable.
+func (c *Client) RestoreSession(s *Session) error {
+	if s.BaseURL != c.BaseURL {
+		return fmt.Errorf("%w: want %q, got %q", ErrSessionBaseURLMismatch, c.BaseURL, s.BaseURL)
+	}
+	now := time.Now()
+	for _, cookie := range s.Cookies {
+		if !cookie.Expires.IsZero() && !cookie.Expires.After(now) {
+			continue
+		}
+		u, err := url.Parse(cookie.URL)
+		if err != nil {
+			return fmt.Errorf("parse session cookie URL: %w", err)
+		}
+		path := cookie.Path
+		if path == "" {
+			// Sessions saved by older versions only stored the name and value
+			path = "/"
+		}
+		c.http.Jar.SetCookies(u, []*http.Cookie{{
+			Name:    cookie.Name,
+			Value:   cookie.Value,
+			Domain:  cookie.Domain,
+			Path:    path,
+			Secure:  cookie.Secure,
+			Expires: cookie.Expires,
+		}})
+	}
+	baseURL, err := url.Parse(c.BaseURL)
+	if err != nil {
+		return fmt.Errorf("parse base URL: %w", err)
+	}
+	if _, ok := c.findCookie(baseURL, "XSRF-TOKEN"); !ok && s.XSRFToken != "" {
+		c.http.Jar.SetCookies(baseURL, []*http.Cookie{{Name: "XSRF-TOKEN", Value: s.XSRFToken, Path: "/"}})
+	}
+	if _, ok := c.findCookie(baseURL, "ATHENA-XSRF-TOKEN"); !ok && s.AthenaXSRFToken != "" {
+		c.http.Jar.SetCookies(baseURL, []*http.Cookie{{Name: "ATHENA-XSRF-TOKEN", Value: s.AthenaXSRFToken, Path: "/"}})
+	}
+	c.EmployeeID = s.EmployeeID
+	c.unlockCSRFToken = s.UnlockCSRFToken
+	return nil
+}
+
+// CheckSession calls [Client.CheckSessionContext] with [context.Background].
+func (c *Client) CheckSession() error {
+	return c.CheckSessionContext(context.Background())
+}
+
+// CheckSessionContext verifies that the client is still logged in by querying
+// the currently logged in user, and updates the client's employee ID.
+//
+// Returns [ErrNotLoggedIn] if the session has expired.
+func (c *Client) CheckSessionContext(ctx context.Context) error {
+	userActivity, err := c.getUserActivity(ctx)
+	if err != nil {
+		c.EmployeeID = 0
+		return fmt.Errorf("%w: %w", ErrNotLoggedIn, err)
+	}
+	c.EmployeeID = userActivity.User.ID
+	return nil
+}
+
+// LoadSessionFile reads a [Session] from a JSON file.
+func LoadSessionFile(path string) (*Session, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var s Session
+	if err := json.Unmarshal(b, &s); err != nil {
+		return nil, fmt.Errorf("parse session file: %w", err)
+	}
+	return &s, nil
+}
+
+// SaveFile writes the session as JSON to a file that is only readable by
+// the current user, creating any parent directories as needed.
+func (s *Session) SaveFile(path string) error {
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, b, 0600); err != nil {
+		return err
+	}
+	// WriteFile only applies the permissions on newly created files
+	return os.Chmod(path, 0600)
+}
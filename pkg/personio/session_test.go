@@ -0,0 +1,141 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package personio
+
+import (
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSessionRoundTrip(t *testing.T) {
+	client, err := New("https://example.personio.de")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expires := time.Now().Add(time.Hour).Truncate(time.Second)
+	baseURL := &url.URL{Scheme: "https", Host: "example.personio.de", Path: "/"}
+	client.http.Jar.SetCookies(baseURL, []*http.Cookie{
+		{Name: "host", Value: "1", Path: "/"},
+		{Name: "domain", Value: "2", Domain: ".personio.de", Path: "/", Expires: expires},
+		{Name: "scoped", Value: "3", Path: "/api", Secure: true},
+		{Name: "max-age", Value: "4", MaxAge: 3600},
+		{Name: "expired", Value: "5", Expires: time.Now().Add(-time.Hour)},
+	})
+	loginURL := &url.URL{Scheme: "https", Host: "login.personio.com", Path: "/u/login"}
+	client.http.Jar.SetCookies(loginURL, []*http.Cookie{
+		{Name: "login", Value: "6"},
+	})
+
+	path := filepath.Join(t.TempDir(), "session.json")
+	if err := client.Session().SaveFile(path); err != nil {
+		t.Fatalf("save session: %s", err)
+	}
+	session, err := LoadSessionFile(path)
+	if err != nil {
+		t.Fatalf("load session: %s", err)
+	}
+	restored, err := New("https://example.personio.de")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := restored.RestoreSession(session); err != nil {
+		t.Fatalf("restore session: %s", err)
+	}
+
+	var tests = []struct {
+		url  string
+		want map[string]string
+	}{
+		{
+			url:  "https://example.personio.de/",
+			want: map[string]string{"host": "1", "domain": "2", "max-age": "4"},
+		},
+		{
+			url:  "https://example.personio.de/api/v1",
+			want: map[string]string{"host": "1", "domain": "2", "scoped": "3", "max-age": "4"},
+		},
+		{
+			url:  "http://example.personio.de/api/v1",
+			want: map[string]string{"host": "1", "domain": "2", "max-age": "4"},
+		},
+		{
+			url:  "https://other.personio.de/",
+			want: map[string]string{"domain": "2"},
+		},
+		{
+			url:  "https://login.personio.com/u/mfa",
+			want: map[string]string{"login": "6"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.url, func(t *testing.T) {
+			u, err := url.Parse(tc.url)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := map[string]string{}
+			for _, cookie := range restored.http.Jar.Cookies(u) {
+				got[cookie.Name] = cookie.Value
+			}
+			if len(got) != len(tc.want) {
+				t.Errorf("want cookies %v, got %v", tc.want, got)
+			}
+			for name, want := range tc.want {
+				if got[name] != want {
+					t.Errorf("cookie %q: want %q, got %q", name, want, got[name])
+				}
+			}
+		})
+	}
+
+	for _, cookie := range session.Cookies {
+		if cookie.Name == "domain" && !cookie.Expires.Equal(expires) {
+			t.Errorf("want expiry %s, got %s", expires, cookie.Expires)
+		}
+		if cookie.Name == "host" && !cookie.Expires.IsZero() {
+			t.Errorf("want no expiry for session cookie, got %s", cookie.Expires)
+		}
+	}
+}
+
+func TestRestoreSessionSkipsExpired(t *testing.T) {
+	client, err := New("https://example.personio.de")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = client.RestoreSession(&Session{
+		BaseURL: client.BaseURL,
+		Cookies: []SessionCookie{
+			// Saved by an older version, without any attributes
+			{URL: "https://example.personio.de/", Name: "old", Value: "1"},
+			{URL: "https://example.personio.de/", Name: "expired", Value: "2",
+				Expires: time.Now().Add(-time.Minute)},
+		},
+	})
+	if err != nil {
+		t.Fatalf("restore session: %s", err)
+	}
+	u := &url.URL{Scheme: "https", Host: "example.personio.de", Path: "/api"}
+	cookies := client.http.Jar.Cookies(u)
+	if len(cookies) != 1 || cookies[0].Name != "old" {
+		t.Errorf("want only cookie %q, got %v", "old", cookies)
+	}
+}
@@ -0,0 +1,154 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package personio
+
+import (
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// sessionJar is a [http.CookieJar] that also remembers the attributes of
+// the cookies, as [cookiejar.Jar] only returns their names and values,
+// which is not enough to store them in a [Session].
+type sessionJar struct {
+	jar *cookiejar.Jar
+
+	mu      sync.Mutex
+	cookies map[sessionCookieKey]SessionCookie
+}
+
+type sessionCookieKey struct {
+	domain string
+	path   string
+	name   string
+}
+
+func newSessionJar() (*sessionJar, error) {
+	jar, err := cookiejar.New(&cookiejar.Options{})
+	if err != nil {
+		return nil, err
+	}
+	return &sessionJar{
+		jar:     jar,
+		cookies: map[sessionCookieKey]SessionCookie{},
+	}, nil
+}
+
+// Cookies implements [http.CookieJar].
+func (j *sessionJar) Cookies(u *url.URL) []*http.Cookie {
+	return j.jar.Cookies(u)
+}
+
+// SetCookies implements [http.CookieJar].
+func (j *sessionJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	j.jar.SetCookies(u, cookies)
+
+	now := time.Now()
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for _, cookie := range cookies {
+		c := SessionCookie{
+			URL:    (&url.URL{Scheme: u.Scheme, Host: u.Host}).String() + "/",
+			Name:   cookie.Name,
+			Value:  cookie.Value,
+			Domain: strings.ToLower(strings.TrimPrefix(cookie.Domain, ".")),
+			Path:   cookie.Path,
+			Secure: cookie.Secure,
+		}
+		if c.Path == "" || !strings.HasPrefix(c.Path, "/") {
+			c.Path = defaultCookiePath(u.Path)
+		}
+		key := sessionCookieKey{domain: c.Domain, path: c.Path, name: c.Name}
+		if key.domain == "" {
+			key.domain = strings.ToLower(u.Hostname())
+		}
+		switch {
+		case cookie.MaxAge < 0:
+			delete(j.cookies, key)
+			continue
+		case cookie.MaxAge > 0:
+			c.Expires = now.Add(time.Duration(cookie.MaxAge) * time.Second)
+		case !cookie.Expires.IsZero():
+			c.Expires = cookie.Expires
+		}
+		if !c.Expires.IsZero() && !c.Expires.After(now) {
+			delete(j.cookies, key)
+			continue
+		}
+		j.cookies[key] = c
+	}
+}
+
+// snapshot returns the cookies that are still stored in the jar, sorted by
+// domain, path and name.
+func (j *sessionJar) snapshot() []SessionCookie {
+	now := time.Now()
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	keys := make([]sessionCookieKey, 0, len(j.cookies))
+	for key, c := range j.cookies {
+		if !c.Expires.IsZero() && !c.Expires.After(now) {
+			continue
+		}
+		// Skip the cookies that the jar rejected, e.g for other domains
+		if !j.hasCookie(c) {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	slices.SortFunc(keys, func(a, b sessionCookieKey) int {
+		return strings.Compare(a.domain+"\x00"+a.path+"\x00"+a.name, b.domain+"\x00"+b.path+"\x00"+b.name)
+	})
+	cookies := make([]SessionCookie, 0, len(keys))
+	for _, key := range keys {
+		cookies = append(cookies, j.cookies[key])
+	}
+	return cookies
+}
+
+func (j *sessionJar) hasCookie(c SessionCookie) bool {
+	u, err := url.Parse(c.URL)
+	if err != nil {
+		return false
+	}
+	u.Path = c.Path
+	if c.Secure {
+		u.Scheme = "https"
+	}
+	for _, cookie := range j.jar.Cookies(u) {
+		if cookie.Name == c.Name && cookie.Value == c.Value {
+			return true
+		}
+	}
+	return false
+}
+
+// defaultCookiePath returns the path used for cookies without a Path
+// attribute, as defined by RFC 6265 section 5.1.4.
+func defaultCookiePath(path string) string {
+	i := strings.LastIndex(path, "/")
+	if i <= 0 {
+		return "/"
+	}
+	return path[:i]
+}
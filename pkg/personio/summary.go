@@ -0,0 +1,154 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package personio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// DaySummary is a day's attendance periods with their project names
+// resolved, together with the totals of the day.
+// See [Client.SummarizeDays].
+type DaySummary struct {
+	Date     string `json:"date"`
+	IsOffDay bool   `json:"is_off_day"`
+	// ApprovalStatus is the status of the day's attendance approval,
+	// such as [ApprovalStatusPending], or empty if there is none.
+	ApprovalStatus string           `json:"approval_status"`
+	Periods        []PeriodSummary  `json:"periods"`
+	Projects       []ProjectSummary `json:"projects"`
+	// TrackedMinutes is the work time, excluding any breaks.
+	TrackedMinutes int `json:"tracked_minutes"`
+	BreakMinutes   int `json:"break_minutes"`
+	TargetMinutes  int `json:"target_minutes"`
+	TimeOffMinutes int `json:"time_off_minutes"`
+	// DifferenceMinutes is the tracked minus the target work time,
+	// which is negative if less than the target was tracked.
+	DifferenceMinutes int `json:"difference_minutes"`
+}
+
+// PeriodSummary is an attendance period in a [DaySummary].
+type PeriodSummary struct {
+	ID        uuid.UUID    `json:"id"`
+	Start     PersonioTime `json:"start"`
+	End       PersonioTime `json:"end"`
+	Type      PeriodType   `json:"type"`
+	ProjectID *int         `json:"project_id"`
+	// Project is the name of the project, or empty if there is none.
+	// Projects that Personio no longer lists, such as archived projects,
+	// are named by their ID instead, e.g "#42".
+	Project         string `json:"project"`
+	Comment         string `json:"comment"`
+	DurationMinutes int    `json:"duration_minutes"`
+}
+
+// ProjectSummary is the tracked work time of a project in a [DaySummary].
+type ProjectSummary struct {
+	ProjectID *int `json:"project_id"`
+	// Project is the name of the project, or empty for the work periods
+	// without a project.
+	Project        string `json:"project"`
+	TrackedMinutes int    `json:"tracked_minutes"`
+}
+
+// SummarizeDays calls [Client.SummarizeDaysContext] with [context.Background].
+func (c *Client) SummarizeDays(cards []Timecard) ([]DaySummary, error) {
+	return c.SummarizeDaysContext(context.Background(), cards)
+}
+
+// SummarizeDaysContext returns a summary of each timecard, where the project
+// names are looked up via [Client.GetProjectNameContext].
+func (c *Client) SummarizeDaysContext(ctx context.Context, cards []Timecard) ([]DaySummary, error) {
+	summaries := make([]DaySummary, 0, len(cards))
+	missingProjects := map[int]bool{}
+	for _, card := range cards {
+		summary := DaySummary{
+			Date:              card.Date,
+			IsOffDay:          card.IsOffDay,
+			ApprovalStatus:    card.ApprovalStatus(),
+			Periods:           []PeriodSummary{},
+			Projects:          []ProjectSummary{},
+			TrackedMinutes:    minutes(card.TrackedDuration()),
+			BreakMinutes:      minutes(card.BreakDuration()),
+			TargetMinutes:     minutes(card.TargetDuration()),
+			TimeOffMinutes:    minutes(card.TimeOffDuration()),
+			DifferenceMinutes: minutes(card.OvertimeDuration()),
+		}
+		for _, period := range card.Periods {
+			project, err := c.projectNameOrEmpty(ctx, period.ProjectID, missingProjects)
+			if err != nil {
+				return nil, err
+			}
+			summary.Periods = append(summary.Periods, PeriodSummary{
+				ID:              period.ID,
+				Start:           period.Start,
+				End:             period.End,
+				Type:            period.Type,
+				ProjectID:       period.ProjectID,
+				Project:         project,
+				Comment:         period.GetComment(),
+				DurationMinutes: minutes(period.End.Sub(period.Start.Time)),
+			})
+		}
+		for _, d := range card.ProjectDurations() {
+			project, err := c.projectNameOrEmpty(ctx, d.ProjectID, missingProjects)
+			if err != nil {
+				return nil, err
+			}
+			summary.Projects = append(summary.Projects, ProjectSummary{
+				ProjectID:      d.ProjectID,
+				Project:        project,
+				TrackedMinutes: minutes(d.Duration),
+			})
+		}
+		summaries = append(summaries, summary)
+	}
+	return summaries, nil
+}
+
+// projectNameOrEmpty returns the name of the project, or a placeholder
+// with its ID if Personio does not list it, where the missing projects
+// are only warned about once.
+func (c *Client) projectNameOrEmpty(ctx context.Context, id *int, missing map[int]bool) (string, error) {
+	if id == nil {
+		return "", nil
+	}
+	name, err := c.GetProjectNameContext(ctx, *id)
+	if errors.Is(err, ErrProjectNotFound) {
+		if !missing[*id] {
+			missing[*id] = true
+			log.Warn().Int("projectId", *id).
+				Msg("Project not found, such as when archived. Showing its ID instead.")
+		}
+		return fmt.Sprintf("#%d", *id), nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("get project name: %w", err)
+	}
+	return name, nil
+}
+
+func minutes(d time.Duration) int {
+	return int(d / time.Minute)
+}
@@ -0,0 +1,207 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package personio
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrPeriodNotFound = errors.New("period not found")
+)
+
+// Known values of [Approval.Status].
+const (
+	ApprovalStatusPending  = "pending"
+	ApprovalStatusApproved = "approved"
+	ApprovalStatusRejected = "rejected"
+)
+
+// TrackedDuration returns the tracked work time of the day, which is the
+// sum of the work periods, minus any breaks that overlap with them.
+//
+// Breaks that are tracked in between the work periods, e.g a work period
+// that ends when the break starts, are not subtracted, as they were never
+// part of the work periods.
+func (t Timecard) TrackedDuration() time.Duration {
+	var total time.Duration
+	for _, work := range t.Periods {
+		if work.Type == PeriodTypeWork {
+			total += t.workDuration(work)
+		}
+	}
+	return total
+}
+
+// BreakDuration returns the sum of the break periods of the day.
+func (t Timecard) BreakDuration() time.Duration {
+	var total time.Duration
+	for _, brk := range t.Periods {
+		if brk.Type == PeriodTypeBreak {
+			total += max(brk.End.Sub(brk.Start.Time), 0)
+		}
+	}
+	return total
+}
+
+// ProjectDuration is the tracked work time of a project on a day.
+type ProjectDuration struct {
+	// ProjectID is nil for the work periods without a project.
+	ProjectID *int
+	Duration  time.Duration
+}
+
+// ProjectDurations returns the tracked work time of the day per project,
+// in the order that the projects first appear in the periods. The sum of
+// the durations is the same as [Timecard.TrackedDuration].
+func (t Timecard) ProjectDurations() []ProjectDuration {
+	var durations []ProjectDuration
+	for _, work := range t.Periods {
+		if work.Type != PeriodTypeWork {
+			continue
+		}
+		i := slices.IndexFunc(durations, func(d ProjectDuration) bool {
+			return work.GetProjectID() == d.projectID()
+		})
+		if i == -1 {
+			i = len(durations)
+			durations = append(durations, ProjectDuration{ProjectID: work.ProjectID})
+		}
+		durations[i].Duration += t.workDuration(work)
+	}
+	return durations
+}
+
+func (d ProjectDuration) projectID() int {
+	if d.ProjectID == nil {
+		return 0
+	}
+	return *d.ProjectID
+}
+
+// workDuration returns the duration of a work period,
+// minus any breaks that overlap with it.
+func (t Timecard) workDuration(work Period) time.Duration {
+	total := work.End.Sub(work.Start.Time)
+	for _, brk := range t.Periods {
+		if brk.Type == PeriodTypeBreak {
+			total -= overlap(work, brk)
+		}
+	}
+	return max(total, 0)
+}
+
+// TargetDuration returns the work time that is expected for the day,
+// which already excludes any time off and holidays.
+func (t Timecard) TargetDuration() time.Duration {
+	return time.Duration(t.TargetHours.EffectiveWorkDurationMinutes) * time.Minute
+}
+
+// TimeOffDuration returns the duration of any absences on the day,
+// such as vacation or sick leave.
+func (t Timecard) TimeOffDuration() time.Duration {
+	if t.TimeOff == nil {
+		return 0
+	}
+	return time.Duration(t.TimeOff.AggregatedDurationMinutes) * time.Minute
+}
+
+// OvertimeDuration returns the difference between the tracked and target
+// work time of the day, which is negative if less than the target was
+// tracked.
+func (t Timecard) OvertimeDuration() time.Duration {
+	return t.TrackedDuration() - t.TargetDuration()
+}
+
+// OvertimeBalance returns the total overtime balance as calculated by
+// Personio up until and including the day. Returns false if Personio
+// did not include a balance for the day.
+func (t Timecard) OvertimeBalance() (time.Duration, bool) {
+	if t.Overtime == nil {
+		return 0, false
+	}
+	if t.Overtime.TotalOvertimeMinutes != nil {
+		return time.Duration(*t.Overtime.TotalOvertimeMinutes) * time.Minute, true
+	}
+	if t.Overtime.OvertimeMinutes == nil {
+		return 0, false
+	}
+	minutes := *t.Overtime.OvertimeMinutes
+	if t.Overtime.PendingMinutes != nil {
+		minutes += *t.Overtime.PendingMinutes
+	}
+	return time.Duration(minutes) * time.Minute, true
+}
+
+// HasTimeOff returns true if there are any absences on the day.
+func (t Timecard) HasTimeOff() bool {
+	return t.TimeOff != nil && (t.TimeOff.AggregatedDurationMinutes > 0 || len(t.TimeOff.Items) > 0)
+}
+
+// ApprovalStatus returns the status of the attendance approval,
+// such as [ApprovalStatusPending], or an empty string if there is none.
+func (t Timecard) ApprovalStatus() string {
+	if t.Approval == nil {
+		return ""
+	}
+	return t.Approval.Status
+}
+
+// FindPeriod returns the index in [Timecard.Periods] of the period
+// referenced either by its ID, or by its 1-based position in the list,
+// as shown by the "attendance show" command.
+//
+// Returns [ErrPeriodNotFound] if there is no such period.
+func (t Timecard) FindPeriod(ref string) (int, error) {
+	if id, err := uuid.Parse(ref); err == nil {
+		i := slices.IndexFunc(t.Periods, func(p Period) bool {
+			return p.ID == id
+		})
+		if i == -1 {
+			return -1, fmt.Errorf("%w: no period with ID %s on %s", ErrPeriodNotFound, id, t.Date)
+		}
+		return i, nil
+	}
+	pos, err := strconv.Atoi(ref)
+	if err != nil {
+		return -1, fmt.Errorf("%w: want period ID or position, got %q", ErrPeriodNotFound, ref)
+	}
+	if pos < 1 || pos > len(t.Periods) {
+		return -1, fmt.Errorf("%w: position %d is out of range, %s has %d periods",
+			ErrPeriodNotFound, pos, t.Date, len(t.Periods))
+	}
+	return pos - 1, nil
+}
+
+func overlap(a, b Period) time.Duration {
+	start := a.Start.Time
+	if b.Start.After(start) {
+		start = b.Start.Time
+	}
+	end := a.End.Time
+	if b.End.Before(end) {
+		end = b.End.Time
+	}
+	return max(end.Sub(start), 0)
+}
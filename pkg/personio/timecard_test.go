@@ -0,0 +1,261 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package personio
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestTimecardTrackedDuration(t *testing.T) {
+	period := func(typ PeriodType, start, end string) Period {
+		parse := func(s string) PersonioTime {
+			tm, err := time.Parse(time.DateTime, "2023-01-18 "+s)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return PersonioTime{tm}
+		}
+		return Period{Type: typ, Start: parse(start), End: parse(end)}
+	}
+
+	var tests = []struct {
+		name    string
+		periods []Period
+		want    time.Duration
+	}{
+		{
+			name: "no periods",
+			want: 0,
+		},
+		{
+			name: "breaks in between",
+			periods: []Period{
+				period(PeriodTypeWork, "08:00:00", "12:00:00"),
+				period(PeriodTypeBreak, "12:00:00", "13:00:00"),
+				period(PeriodTypeWork, "13:00:00", "17:00:00"),
+			},
+			want: 8 * time.Hour,
+		},
+		{
+			name: "break inside work",
+			periods: []Period{
+				period(PeriodTypeWork, "08:00:00", "17:00:00"),
+				period(PeriodTypeBreak, "12:00:00", "12:30:00"),
+			},
+			want: 8*time.Hour + 30*time.Minute,
+		},
+		{
+			name: "break partially overlapping",
+			periods: []Period{
+				period(PeriodTypeWork, "08:00:00", "12:00:00"),
+				period(PeriodTypeBreak, "11:45:00", "13:00:00"),
+				period(PeriodTypeWork, "13:00:00", "14:00:00"),
+			},
+			want: 4*time.Hour + 45*time.Minute,
+		},
+		{
+			name: "only breaks",
+			periods: []Period{
+				period(PeriodTypeBreak, "12:00:00", "13:00:00"),
+			},
+			want: 0,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			card := Timecard{Periods: tc.periods}
+			if got := card.TrackedDuration(); got != tc.want {
+				t.Errorf("want %s, got %s", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestTimecardOvertimeDuration(t *testing.T) {
+	start := time.Date(2023, 1, 18, 8, 0, 0, 0, time.UTC)
+	card := Timecard{
+		Periods: []Period{{
+			Type:  PeriodTypeWork,
+			Start: PersonioTime{start},
+			End:   PersonioTime{start.Add(7 * time.Hour)},
+		}},
+	}
+	card.TargetHours.EffectiveWorkDurationMinutes = 8 * 60
+	card.TargetHours.ContractualWorkDurationMinutes = 8 * 60
+
+	if got, want := card.TargetDuration(), 8*time.Hour; got != want {
+		t.Errorf("target: want %s, got %s", want, got)
+	}
+	if got, want := card.OvertimeDuration(), -time.Hour; got != want {
+		t.Errorf("overtime: want %s, got %s", want, got)
+	}
+	if card.HasTimeOff() {
+		t.Error("want no time off")
+	}
+
+	card.TimeOff = &TimeOff{AggregatedDurationMinutes: 4 * 60}
+	card.TargetHours.EffectiveWorkDurationMinutes = 4 * 60
+	if !card.HasTimeOff() {
+		t.Error("want time off")
+	}
+	if got, want := card.TimeOffDuration(), 4*time.Hour; got != want {
+		t.Errorf("time off: want %s, got %s", want, got)
+	}
+	if got, want := card.OvertimeDuration(), 3*time.Hour; got != want {
+		t.Errorf("overtime with time off: want %s, got %s", want, got)
+	}
+}
+
+func TestTimecardOvertimeBalance(t *testing.T) {
+	minutes := func(m int) *int { return &m }
+	var tests = []struct {
+		name     string
+		overtime *Overtime
+		want     time.Duration
+		wantOK   bool
+	}{
+		{
+			name: "no overtime",
+		},
+		{
+			name:     "all null",
+			overtime: &Overtime{},
+		},
+		{
+			name:     "total",
+			overtime: &Overtime{TotalOvertimeMinutes: minutes(-90), OvertimeMinutes: minutes(30)},
+			want:     -90 * time.Minute,
+			wantOK:   true,
+		},
+		{
+			name:     "overtime and pending",
+			overtime: &Overtime{OvertimeMinutes: minutes(30), PendingMinutes: minutes(15)},
+			want:     45 * time.Minute,
+			wantOK:   true,
+		},
+		{
+			name:     "only pending",
+			overtime: &Overtime{PendingMinutes: minutes(15)},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := Timecard{Overtime: tc.overtime}.OvertimeBalance()
+			if ok != tc.wantOK {
+				t.Fatalf("want ok %t, got %t", tc.wantOK, ok)
+			}
+			if got != tc.want {
+				t.Errorf("want %s, got %s", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestTimecardProjectDurations(t *testing.T) {
+	start := time.Date(2023, 1, 18, 8, 0, 0, 0, time.UTC)
+	period := func(typ PeriodType, projectID *int, from, to time.Duration) Period {
+		return Period{
+			Type:      typ,
+			ProjectID: projectID,
+			Start:     PersonioTime{start.Add(from)},
+			End:       PersonioTime{start.Add(to)},
+		}
+	}
+	projectX, projectY := 1, 2
+	card := Timecard{
+		Periods: []Period{
+			period(PeriodTypeWork, &projectX, 0, 4*time.Hour),
+			period(PeriodTypeBreak, nil, 3*time.Hour+30*time.Minute, 4*time.Hour+30*time.Minute),
+			period(PeriodTypeWork, nil, 4*time.Hour+30*time.Minute, 5*time.Hour),
+			period(PeriodTypeWork, &projectY, 5*time.Hour, 7*time.Hour),
+			period(PeriodTypeWork, &projectX, 7*time.Hour, 8*time.Hour),
+		},
+	}
+
+	want := []struct {
+		projectID int
+		duration  time.Duration
+	}{
+		{projectID: projectX, duration: 4*time.Hour + 30*time.Minute},
+		{projectID: 0, duration: 30 * time.Minute},
+		{projectID: projectY, duration: 2 * time.Hour},
+	}
+	got := card.ProjectDurations()
+	if len(got) != len(want) {
+		t.Fatalf("want %d projects, got %+v", len(want), got)
+	}
+	for i, w := range want {
+		if id := got[i].projectID(); id != w.projectID {
+			t.Errorf("project %d: want ID %d, got %d", i, w.projectID, id)
+		}
+		if got[i].Duration != w.duration {
+			t.Errorf("project %d: want %s, got %s", i, w.duration, got[i].Duration)
+		}
+	}
+	if got, want := card.BreakDuration(), time.Hour; got != want {
+		t.Errorf("breaks: want %s, got %s", want, got)
+	}
+	if got, want := card.TrackedDuration(), 7*time.Hour; got != want {
+		t.Errorf("tracked: want %s, got %s", want, got)
+	}
+}
+
+func TestTimecardFindPeriod(t *testing.T) {
+	first, second := uuid.New(), uuid.New()
+	card := Timecard{
+		Date:    "2023-01-18",
+		Periods: []Period{{ID: first}, {ID: second}},
+	}
+
+	var tests = []struct {
+		name    string
+		ref     string
+		want    int
+		wantErr bool
+	}{
+		{name: "ID", ref: second.String(), want: 1},
+		{name: "position", ref: "1", want: 0},
+		{name: "last position", ref: "2", want: 1},
+		{name: "unknown ID", ref: uuid.NewString(), wantErr: true},
+		{name: "position zero", ref: "0", wantErr: true},
+		{name: "position out of range", ref: "3", wantErr: true},
+		{name: "invalid", ref: "first", wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := card.FindPeriod(tc.ref)
+			if tc.wantErr {
+				if !errors.Is(err, ErrPeriodNotFound) {
+					t.Errorf("want ErrPeriodNotFound, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want {
+				t.Errorf("want index %d, got %d", tc.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package personio
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	totpPeriod = 30 * time.Second
+	totpDigits = 6
+)
+
+var (
+	ErrInvalidTOTPSecret = errors.New("invalid TOTP secret")
+)
+
+// GenerateTOTP generates a time-based one-time password (RFC 6238) for the
+// given time, using the same parameters as most authenticator apps:
+// HMAC-SHA1, 30 second periods, and 6 digits.
+//
+// The secret is the base32 encoded key, as shown when setting up 2FA, or an
+// "otpauth://" URI as encoded in the QR code.
+func GenerateTOTP(secret string, t time.Time) (string, error) {
+	key, err := decodeTOTPSecret(secret)
+	if err != nil {
+		return "", err
+	}
+	return generateTOTP(sha1.New, key, t, totpPeriod, totpDigits), nil
+}
+
+func decodeTOTPSecret(secret string) ([]byte, error) {
+	secret = strings.TrimSpace(secret)
+	if strings.HasPrefix(secret, "otpauth://") {
+		u, err := url.Parse(secret)
+		if err != nil {
+			return nil, fmt.Errorf("%w: parse URI: %s", ErrInvalidTOTPSecret, err)
+		}
+		secret = u.Query().Get("secret")
+	}
+	secret = strings.ToUpper(strings.ReplaceAll(secret, " ", ""))
+	secret = strings.TrimRight(secret, "=")
+	if secret == "" {
+		return nil, fmt.Errorf("%w: secret is empty", ErrInvalidTOTPSecret)
+	}
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidTOTPSecret, err)
+	}
+	return key, nil
+}
+
+func generateTOTP(h func() hash.Hash, key []byte, t time.Time, period time.Duration, digits int) string {
+	counter := uint64(t.Unix() / int64(period/time.Second))
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], counter)
+
+	mac := hmac.New(h, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	// Dynamic truncation, as defined in RFC 4226 section 5.3
+	offset := sum[len(sum)-1] & 0x0f
+	code := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < digits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", digits, code%mod)
+}
@@ -0,0 +1,118 @@
+// SPDX-FileCopyrightText: 2023 Kalle Fagerberg
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU General Public License as published by the
+// Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU General Public License along
+// with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package personio
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base32"
+	"errors"
+	"hash"
+	"testing"
+	"time"
+)
+
+// Test vectors from RFC 6238, Appendix B.
+func TestGenerateTOTPRFCVectors(t *testing.T) {
+	var (
+		seed20 = []byte("12345678901234567890")
+		seed32 = []byte("12345678901234567890123456789012")
+		seed64 = []byte("1234567890123456789012345678901234567890123456789012345678901234")
+	)
+	var tests = []struct {
+		unix   int64
+		sha1   string
+		sha256 string
+		sha512 string
+	}{
+		{unix: 59, sha1: "94287082", sha256: "46119246", sha512: "90693936"},
+		{unix: 1111111109, sha1: "07081804", sha256: "68084774", sha512: "25091201"},
+		{unix: 1111111111, sha1: "14050471", sha256: "67062674", sha512: "99943326"},
+		{unix: 1234567890, sha1: "89005924", sha256: "91819424", sha512: "93441116"},
+		{unix: 2000000000, sha1: "69279037", sha256: "90698825", sha512: "38618901"},
+		{unix: 20000000000, sha1: "65353130", sha256: "77737706", sha512: "47863826"},
+	}
+
+	for _, tc := range tests {
+		ts := time.Unix(tc.unix, 0)
+		for _, alg := range []struct {
+			name string
+			h    func() hash.Hash
+			key  []byte
+			want string
+		}{
+			{"SHA1", sha1.New, seed20, tc.sha1},
+			{"SHA256", sha256.New, seed32, tc.sha256},
+			{"SHA512", sha512.New, seed64, tc.sha512},
+		} {
+			t.Run(ts.UTC().Format(time.RFC3339)+"/"+alg.name, func(t *testing.T) {
+				got := generateTOTP(alg.h, alg.key, ts, 30*time.Second, 8)
+				if got != alg.want {
+					t.Errorf("want %q, got %q", alg.want, got)
+				}
+			})
+		}
+	}
+}
+
+func TestGenerateTOTP(t *testing.T) {
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+	var tests = []struct {
+		name   string
+		secret string
+		want   string
+	}{
+		{
+			name:   "base32",
+			secret: secret,
+			want:   "287082",
+		},
+		{
+			name:   "lowercase without padding and with spaces",
+			secret: "gezd gnbv gy3t qojq gezd gnbv gy3t qojq",
+			want:   "287082",
+		},
+		{
+			name:   "otpauth URI",
+			secret: "otpauth://totp/Personio:me@example.com?secret=" + secret + "&issuer=Personio",
+			want:   "287082",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := GenerateTOTP(tc.secret, time.Unix(59, 0))
+			if err != nil {
+				t.Fatalf("want %q, got error: %s", tc.want, err)
+			}
+			if got != tc.want {
+				t.Errorf("want %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestGenerateTOTPInvalidSecret(t *testing.T) {
+	for _, secret := range []string{"", "not base32!", "otpauth://totp/Personio"} {
+		_, err := GenerateTOTP(secret, time.Unix(59, 0))
+		if !errors.Is(err, ErrInvalidTOTPSecret) {
+			t.Errorf("secret %q: want ErrInvalidTOTPSecret, got %v", secret, err)
+		}
+	}
+}
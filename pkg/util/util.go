@@ -19,20 +19,18 @@
 package util
 
 import (
-	"bytes"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
 	"time"
 	"unicode"
-
-	"github.com/mattn/go-isatty"
 )
 
 var camelCaseReplacer = strings.NewReplacer(
 	"ID", "Id",
+	"TOTP", "Totp",
+	"CSRF", "Csrf",
 	"URL", "Url",
 	"HTTP", "Http",
 	"JSON", "Json",
@@ -80,36 +78,6 @@ func PrettyPath(s string) string {
 	return path
 }
 
-func ColorizeJSON(data []byte) ([]byte, error) {
-	args := []string{"."}
-	if isatty.IsTerminal(os.Stdout.Fd()) {
-		args = append(args, "--color-output")
-	}
-
-	jq := exec.Command("jq", args...)
-	jq.Stdin = bytes.NewReader(data)
-	colorized, err := jq.Output()
-	if err != nil {
-		return nil, err
-	}
-	return colorized, nil
-}
-
-func ColorizeYAML(data []byte) ([]byte, error) {
-	args := []string{".", "-"}
-	if isatty.IsTerminal(os.Stdout.Fd()) {
-		args = append(args, "--colors")
-	}
-
-	yq := exec.Command("yq", args...)
-	yq.Stdin = bytes.NewReader(data)
-	colorized, err := yq.Output()
-	if err != nil {
-		return nil, err
-	}
-	return colorized, nil
-}
-
 func TimeFullMonth(date time.Time) (time.Time, time.Time) {
 	year, month, _ := date.Date()
 	return time.Date(year, month, 1, 0, 0, 0, 0, time.UTC),